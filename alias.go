@@ -0,0 +1,80 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// errAliasExists reports the alias exists
+var errAliasExists = errors.New("alias exists")
+
+// Alias makes alias resolve to key in all operations, so multiple names resolve to one instance,
+// alias can point to another alias, if alias is an existing key or alias then return `ErrAlreadyExists` error
+// (use `errors.Is` to assert), and if alias chain forms a cycle then return `ErrCycle` error
+func (m *Map[K, V]) Alias(ctx context.Context, alias, key K) error {
+	if m.normalizer != nil {
+		alias, key = m.normalizer(alias), m.normalizer(key)
+	}
+	if err := m.validateKey(ctx, "Alias", alias); err != nil {
+		return err
+	}
+	m.lock.RLock()
+	exists := m.exists(alias)
+	m.lock.RUnlock()
+	if exists {
+		return m.newError("Alias", alias, ErrAlreadyExists, nil)
+	}
+	m.aliasesLock.Lock()
+	defer m.aliasesLock.Unlock()
+	if _, ok := m.aliases[alias]; ok {
+		return m.newError("Alias", alias, ErrAlreadyExists, errAliasExists)
+	}
+	for k, ok := key, true; ok; k, ok = m.aliases[k] {
+		if k == alias {
+			return m.newError("Alias", alias, ErrCycle, fmt.Errorf("alias to %v", key))
+		}
+	}
+	if m.aliases == nil {
+		m.aliases = make(map[K]K)
+	}
+	m.aliases[alias] = key
+	m.hasAliases.Store(true)
+	return nil
+}
+
+// Unalias removes alias
+func (m *Map[K, V]) Unalias(ctx context.Context, alias K) error {
+	if m.normalizer != nil {
+		alias = m.normalizer(alias)
+	}
+	m.aliasesLock.Lock()
+	defer m.aliasesLock.Unlock()
+	delete(m.aliases, alias)
+	return nil
+}
+
+// Aliases returns all aliases which resolve to key directly or indirectly
+func (m *Map[K, V]) Aliases(ctx context.Context, key K) []K {
+	key = m.key(key)
+	m.aliasesLock.RLock()
+	defer m.aliasesLock.RUnlock()
+	var aliases []K
+	for alias := range m.aliases {
+		if m.resolveAlias(alias) == key {
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+// resolveAlias follows the alias chain of key, must be called with aliases lock held
+func (m *Map[K, V]) resolveAlias(key K) K {
+	for {
+		target, ok := m.aliases[key]
+		if !ok {
+			return key
+		}
+		key = target
+	}
+}
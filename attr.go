@@ -0,0 +1,265 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// TypedAttr is a typed attribute, libraries register setters to consume it and the app sets it once,
+// which fans out the value to all registered setters, it shares the setters with `RegisterAttrSetter`
+// and `ExecuteAttrSetters` of the same attr name
+type TypedAttr[T any] struct {
+	name          Attr
+	env           string
+	flagName      string
+	flagUsage     string
+	flagValue     atomic.Pointer[T]
+	defaultLoader DefaultLoader[T]
+}
+
+// AttrOption used to configure a typed attribute
+type AttrOption[T any] func(a *TypedAttr[T])
+
+// Required declares the attribute must be set by the app, see `ValidateAttrs`
+func Required[T any]() AttrOption[T] {
+	return func(a *TypedAttr[T]) {
+		requiredAttrs.Set(context.Background(), a.name, struct{}{})
+	}
+}
+
+// WithDefault declares the default value of the attribute used if the app never sets it, see `LoadAttrs`
+func WithDefault[T any](value T) AttrOption[T] {
+	return WithDefaultLoader[T](literalDefault[T]{value: value})
+}
+
+// WithDefaultLoader declares the loader of the default value of the attribute used if the app never sets it,
+// which is invoked with the attr name as key on every resolving, see `LoadAttrs`.
+// If neither `WithDefault` nor `WithDefaultLoader` is used, T's `DefaultLoader` or `Default` implementation is used if any
+func WithDefaultLoader[T any](loader DefaultLoader[T]) AttrOption[T] {
+	return func(a *TypedAttr[T]) {
+		a.defaultLoader = loader
+	}
+}
+
+// NewAttr creates a typed attribute named name, usually declared as a package level variable
+func NewAttr[T any](name Attr, opts ...AttrOption[T]) *TypedAttr[T] {
+	attrConstructors.Register(context.Background(), name, NewConstructor[T]())
+	a := &TypedAttr[T]{name: name}
+	for _, opt := range opts {
+		opt(a)
+	}
+	typedAttrs.Set(context.Background(), name, a)
+	return a
+}
+
+// Name returns the name of the attribute
+func (a *TypedAttr[T]) Name() Attr {
+	return a.name
+}
+
+// Register registers a setter of the attribute, which is named by the caller location, used in library init
+func (a *TypedAttr[T]) Register(setter AttrSetter[T], opts ...SetterOption) error {
+	return RegisterAttrSetter(a.name, callerOutside().String(), setter, opts...)
+}
+
+// Set executes all setters of the attribute with value, used in app code
+func (a *TypedAttr[T]) Set(ctx context.Context, value T) error {
+	return ExecuteAttrSetters(ctx, a.name, value)
+}
+
+// Value returns the value of the attribute with precedence:
+// explicit set > flag(see `WithFlagBinding`) > env(see `WithEnvBinding`) > declared default,
+// returns `ErrAttrNotSet` error if neither
+func (a *TypedAttr[T]) Value(ctx context.Context) (T, error) {
+	if value, err := attrValues.Get(ctx, a.name); err == nil {
+		if typed, ok := value.(T); ok {
+			return typed, nil
+		}
+	}
+	value, ok, err := a.fallback(ctx)
+	if err != nil {
+		return value, err
+	}
+	if !ok {
+		return value, fmt.Errorf("inithook: attr %s: %w", a.name, ErrAttrNotSet)
+	}
+	return value, nil
+}
+
+// fallback resolves the value of the attribute used if the app never sets it, returns false if no fallback declared
+func (a *TypedAttr[T]) fallback(ctx context.Context) (T, bool, error) {
+	if value := a.flagValue.Load(); value != nil {
+		return *value, true, nil
+	}
+	if value, ok, err := a.lookupEnv(); ok || err != nil {
+		return value, ok, err
+	}
+	loader := a.defaultLoader
+	if loader == nil {
+		zero := Zero[T]()
+		_, isLoader := any(zero).(DefaultLoader[T])
+		_, isDefault := any(zero).(Default[T])
+		if !isLoader && !isDefault {
+			return *new(T), false, nil
+		}
+		loader = typeDefault[T]{}
+	}
+	value, err := loader.LoadDefault(ctx, a.name)
+	if err != nil {
+		return value, false, fmt.Errorf("inithook: attr %s load default failed: %w", a.name, err)
+	}
+	return value, true, nil
+}
+
+// load sets the attribute by its fallback if it has never been set
+func (a *TypedAttr[T]) load(ctx context.Context) error {
+	if settersUsed.Has(ctx, a.name) {
+		return nil
+	}
+	value, ok, err := a.fallback(ctx)
+	if err != nil || !ok {
+		return err
+	}
+	return a.Set(ctx, value)
+}
+
+// LoadAttrs sets each typed attribute which has never been set by its parsed flag(see `WithFlagBinding`),
+// bound env(see `WithEnvBinding`) or declared default(see `WithDefault`), so that the setters of libraries agree on the fallback,
+// it's a `HookFunc` registered as the first init hook of `DefaultHooks` named `AttrsHook`, so the other hooks see the attributes,
+// and can be registered to other `Hooks`, e.g. `h.OnInit("attrs", inithook.LoadAttrs)`
+func LoadAttrs(ctx context.Context) error {
+	names := typedAttrs.Keys(ctx)
+	sort.Strings(names)
+	for _, name := range names {
+		a, err := typedAttrs.Get(ctx, name)
+		if err != nil {
+			continue
+		}
+		if err := a.load(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// literalDefault is the `DefaultLoader` of a literal value
+type literalDefault[T any] struct {
+	value T
+}
+
+// LoadDefault implements `DefaultLoader`
+func (d literalDefault[T]) LoadDefault(ctx context.Context, key any) (T, error) {
+	return d.value, nil
+}
+
+// typeDefault is the `DefaultLoader` of T's `DefaultLoader` or `Default` implementation
+type typeDefault[T any] struct{}
+
+// LoadDefault implements `DefaultLoader`
+func (typeDefault[T]) LoadDefault(ctx context.Context, key any) (T, error) {
+	return defaultValue[any, T](ctx, key)
+}
+
+// AttrsHook is the name of the builtin init hook of `DefaultHooks` which invokes `LoadAttrs`
+const AttrsHook = "inithook.attrs"
+
+func init() {
+	DefaultHooks.OnInit(AttrsHook, LoadAttrs, WithPriority(math.MinInt))
+}
+
+// attrLoader is implemented by `*TypedAttr`
+type attrLoader interface {
+	load(ctx context.Context) error
+	flag() (AttrFlag, bool)
+}
+
+var typedAttrs = NewMap[Attr, attrLoader]()
+
+// ErrAttrNotSet defines the error returned by `ValidateAttrs` if some required attrs have not been set
+var ErrAttrNotSet = errors.New("attr not set")
+
+// ValidateAttrs returns `ErrAttrNotSet` error listing the required attrs(see `Required`) which have never been set,
+// it's a `HookFunc` and can be registered as a hook to fail the run, e.g. `inithook.OnStart("attrs", inithook.ValidateAttrs)`
+func ValidateAttrs(ctx context.Context) error {
+	var missing []string
+	for _, attr := range requiredAttrs.Keys(ctx) {
+		if !settersUsed.Has(ctx, attr) {
+			missing = append(missing, attr)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("inithook: required attrs %s: %w", strings.Join(missing, ", "), ErrAttrNotSet)
+}
+
+var requiredAttrs = NewMap[Attr, struct{}]()
+
+// ExecuteStructAttrSetters executes the attr setters of each field of struct cfg tagged by `inithook:"attr"`,
+// so that one config load fans out to all libraries, used in app code, e.g.
+//
+//	type Config struct {
+//		AppName string `inithook:"app_name"`
+//		Version string `inithook:"version,omitempty"`
+//	}
+//
+// the field is skipped if tagged `inithook:"-"` or with `omitempty` and zero value,
+// the untagged embedded structs are walked recursively
+func ExecuteStructAttrSetters(ctx context.Context, cfg any) error {
+	v := reflect.ValueOf(cfg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return fmt.Errorf("inithook: nil config %T", cfg)
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("inithook: config should be a struct but got %T", cfg)
+	}
+	return executeStructAttrSetters(ctx, v)
+}
+
+func executeStructAttrSetters(ctx context.Context, v reflect.Value) error {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, ok := field.Tag.Lookup("inithook")
+		if !ok {
+			fv := v.Field(i)
+			if field.Anonymous && fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if field.Anonymous && fv.Kind() == reflect.Struct {
+				if err := executeStructAttrSetters(ctx, fv); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		attr, opts, _ := strings.Cut(tag, ",")
+		if attr == "-" || attr == "" || !field.IsExported() {
+			continue
+		}
+		if opts == "omitempty" && v.Field(i).IsZero() {
+			continue
+		}
+		err := ExecuteAttrSetters(ctx, attr, v.Field(i).Interface())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// builtin typed attrs
+var (
+	AppNameAttr = NewAttr[string](AppName)
+	VersionAttr = NewAttr[string](Version)
+)
@@ -0,0 +1,286 @@
+package inithook_test
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedAttr(t *testing.T) {
+	ctx := context.Background()
+	port := inithook.NewAttr[int]("test_port")
+	assert.Equalf(t, "test_port", port.Name(), "name")
+	var a, b int
+	assert.Nilf(t, port.Register(func(ctx context.Context, v int) error {
+		a = v
+		return nil
+	}), "register")
+	assert.Nilf(t, port.Register(func(ctx context.Context, v int) error {
+		b = v * 2
+		return nil
+	}), "register")
+	assert.Nilf(t, port.Set(ctx, 8080), "set")
+	assert.Equalf(t, 8080, a, "fan out")
+	assert.Equalf(t, 16160, b, "fan out")
+	assert.NotNilf(t, inithook.GetAttrConstructor("test_port"), "constructor registered")
+	assert.Equalf(t, 0, inithook.GetAttrConstructor("test_port")(), "constructor registered")
+
+	errBoom := errors.New("boom")
+	flaky := inithook.NewAttr[string]("test_flaky")
+	flaky.Register(func(ctx context.Context, v string) error {
+		if v == "bad" {
+			return errBoom
+		}
+		return nil
+	})
+	assert.NotNilf(t, flaky.Set(ctx, "bad"), "setter error")
+	assert.Nilf(t, flaky.Set(ctx, "good"), "set")
+}
+
+func TestExecuteStructAttrSetters(t *testing.T) {
+	ctx := context.Background()
+	host := inithook.NewAttr[string]("test_struct_host")
+	timeout := inithook.NewAttr[int]("test_struct_timeout")
+	var gotHost string
+	var gotTimeout int
+	host.Register(func(ctx context.Context, v string) error {
+		gotHost = v
+		return nil
+	})
+	timeout.Register(func(ctx context.Context, v int) error {
+		gotTimeout = v
+		return nil
+	})
+	type Base struct {
+		Host string `inithook:"test_struct_host"`
+	}
+	type Config struct {
+		Base
+		Timeout int    `inithook:"test_struct_timeout,omitempty"`
+		Ignored string `inithook:"-"`
+		Plain   string
+	}
+	assert.Nilf(t, inithook.ExecuteStructAttrSetters(ctx, &Config{Base: Base{Host: "localhost"}}), "execute")
+	assert.Equalf(t, "localhost", gotHost, "embedded field")
+	assert.Equalf(t, 0, gotTimeout, "omitempty")
+	assert.Nilf(t, inithook.ExecuteStructAttrSetters(ctx, Config{Base: Base{Host: "example.com"}, Timeout: 3}), "execute")
+	assert.Equalf(t, "localhost", gotHost, "executed once")
+	assert.Equalf(t, 3, gotTimeout, "field")
+
+	assert.NotNilf(t, inithook.ExecuteStructAttrSetters(ctx, 1), "not a struct")
+	assert.NotNilf(t, inithook.ExecuteStructAttrSetters(ctx, (*Config)(nil)), "nil config")
+	name := inithook.NewAttr[string]("test_struct_name")
+	name.Register(func(ctx context.Context, v string) error { return nil })
+	type Mismatch struct {
+		Name int `inithook:"test_struct_name"`
+	}
+	assert.NotNilf(t, inithook.ExecuteStructAttrSetters(ctx, Mismatch{Name: 1}), "type mismatch")
+	assert.Nilf(t, name.Set(ctx, "x"), "set")
+}
+
+func TestDynamicAttrSetter(t *testing.T) {
+	ctx := context.Background()
+	level := inithook.NewAttr[string]("test_log_level")
+	var static, dynamic string
+	level.Register(func(ctx context.Context, v string) error {
+		static = v
+		return nil
+	})
+	level.Register(func(ctx context.Context, v string) error {
+		dynamic = v
+		return nil
+	}, inithook.WithDynamic())
+	assert.Nilf(t, level.Set(ctx, "info"), "set")
+	assert.Equalf(t, "info", static, "first set")
+	assert.Equalf(t, "info", dynamic, "first set")
+	assert.Nilf(t, level.Set(ctx, "debug"), "re-set")
+	assert.Equalf(t, "info", static, "static setter executed once")
+	assert.Equalf(t, "debug", dynamic, "dynamic setter re-executed")
+}
+
+func TestValidateAttrs(t *testing.T) {
+	ctx := context.Background()
+	name := inithook.NewAttr("test_required_name", inithook.Required[string]())
+	region := inithook.NewAttr("test_required_region", inithook.Required[string]())
+	inithook.NewAttr[string]("test_optional")
+	err := inithook.ValidateAttrs(ctx)
+	assert.Truef(t, errors.Is(err, inithook.ErrAttrNotSet), "required attrs not set")
+	assert.Equalf(t, "inithook: required attrs test_required_name, test_required_region: attr not set", err.Error(), "error message")
+
+	h := inithook.NewHooks()
+	h.OnStart("attrs", inithook.ValidateAttrs)
+	assert.Nilf(t, name.Set(ctx, "x"), "set")
+	err = h.Run(ctx)
+	assert.Truef(t, errors.Is(err, inithook.ErrAttrNotSet), "validated as hook")
+	assert.Containsf(t, err.Error(), "test_required_region", "missing attr")
+	assert.NotContainsf(t, err.Error(), "test_required_name", "set attr")
+
+	assert.Nilf(t, region.Set(ctx, "y"), "set")
+	assert.Nilf(t, inithook.ValidateAttrs(ctx), "all required attrs set")
+}
+
+func TestAttrDefault(t *testing.T) {
+	ctx := context.Background()
+	region := inithook.NewAttr("test_default_region", inithook.WithDefault("us-east-1"))
+	loader := &countingLoader{value: "a"}
+	zone := inithook.NewAttr("test_default_zone", inithook.WithDefaultLoader[string](loader))
+	none := inithook.NewAttr[string]("test_default_none")
+	var gotRegion, gotZone string
+	region.Register(func(ctx context.Context, v string) error {
+		gotRegion = v
+		return nil
+	})
+	zone.Register(func(ctx context.Context, v string) error {
+		gotZone = v
+		return nil
+	})
+
+	value, err := region.Value(ctx)
+	assert.Nilf(t, err, "value")
+	assert.Equalf(t, "us-east-1", value, "declared default")
+	_, err = none.Value(ctx)
+	assert.Truef(t, errors.Is(err, inithook.ErrAttrNotSet), "no default")
+
+	assert.Nilf(t, zone.Set(ctx, "b"), "set")
+	assert.Nilf(t, inithook.LoadAttrs(ctx), "load")
+	assert.Equalf(t, "us-east-1", gotRegion, "default loaded")
+	assert.Equalf(t, "b", gotZone, "explicit set takes precedence")
+	assert.Equalf(t, 0, loader.loads, "loader not invoked")
+	value, err = zone.Value(ctx)
+	assert.Nilf(t, err, "value")
+	assert.Equalf(t, "b", value, "explicit set")
+
+	errBoom := errors.New("boom")
+	failing := inithook.NewAttr("test_default_failing", inithook.WithDefaultLoader[string](&countingLoader{err: errBoom}))
+	_, err = failing.Value(ctx)
+	assert.Truef(t, errors.Is(err, errBoom), "loader error")
+	assert.Truef(t, errors.Is(inithook.LoadAttrs(ctx), errBoom), "loader error")
+	assert.Nilf(t, failing.Set(ctx, "x"), "set")
+	assert.Nilf(t, inithook.LoadAttrs(ctx), "load")
+
+	level := inithook.NewAttr[logLevel]("test_default_level")
+	value2, err := level.Value(ctx)
+	assert.Nilf(t, err, "value")
+	assert.Equalf(t, logLevel("info"), value2, "type default")
+}
+
+type countingLoader struct {
+	value string
+	err   error
+	loads int
+}
+
+func (l *countingLoader) LoadDefault(ctx context.Context, key any) (string, error) {
+	l.loads++
+	return l.value, l.err
+}
+
+type logLevel string
+
+func (logLevel) Default() logLevel {
+	return "info"
+}
+
+func TestAttrEnvBinding(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TEST_ENV_PORT", "8080")
+	t.Setenv("TEST_ENV_DEBUG", "true")
+	t.Setenv("TEST_ENV_TIMEOUT", "3s")
+	t.Setenv("TEST_ENV_HOSTS", "a, b,c")
+	t.Setenv("TEST_ENV_IP", "127.0.0.1")
+	t.Setenv("TEST_ENV_BAD", "x")
+	port := inithook.NewAttr("test_env_port", inithook.WithEnvBinding[int]("TEST_ENV_PORT"), inithook.WithDefault(80))
+	debug := inithook.NewAttr("test_env_debug", inithook.WithEnvBinding[bool]("TEST_ENV_DEBUG"))
+	timeout := inithook.NewAttr("test_env_timeout", inithook.WithEnvBinding[time.Duration]("TEST_ENV_TIMEOUT"))
+	hosts := inithook.NewAttr("test_env_hosts", inithook.WithEnvBinding[[]string]("TEST_ENV_HOSTS"))
+	ip := inithook.NewAttr("test_env_ip", inithook.WithEnvBinding[net.IP]("TEST_ENV_IP"))
+	unset := inithook.NewAttr("test_env_unset", inithook.WithEnvBinding[int]("TEST_ENV_UNSET"), inithook.WithDefault(1))
+	bad := inithook.NewAttr("test_env_bad", inithook.WithEnvBinding[int]("TEST_ENV_BAD"))
+
+	p, err := port.Value(ctx)
+	assert.Nilf(t, err, "value")
+	assert.Equalf(t, 8080, p, "env overrides default")
+	d, _ := debug.Value(ctx)
+	assert.Truef(t, d, "bool")
+	to, _ := timeout.Value(ctx)
+	assert.Equalf(t, 3*time.Second, to, "duration")
+	hs, _ := hosts.Value(ctx)
+	assert.Equalf(t, []string{"a", "b", "c"}, hs, "slice")
+	addr, _ := ip.Value(ctx)
+	assert.Equalf(t, "127.0.0.1", addr.String(), "text unmarshaler")
+	u, _ := unset.Value(ctx)
+	assert.Equalf(t, 1, u, "default if env not present")
+	_, err = bad.Value(ctx)
+	assert.NotNilf(t, err, "parse error")
+
+	var got int
+	port.Register(func(ctx context.Context, v int) error {
+		got = v
+		return nil
+	})
+	assert.Nilf(t, bad.Set(ctx, 0), "set")
+	h := inithook.NewHooks()
+	h.OnInit("attrs", inithook.LoadAttrs)
+	assert.Nilf(t, h.Run(ctx), "run")
+	assert.Equalf(t, 8080, got, "populated at run time")
+}
+
+func TestAttrsHook(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TEST_HOOK_REGION", "eu")
+	region := inithook.NewAttr("test_hook_region", inithook.WithEnvBinding[string]("TEST_HOOK_REGION"))
+	var set string
+	region.Register(func(ctx context.Context, v string) error {
+		set = v
+		return nil
+	})
+	var seen string
+	inithook.OnInit("test_hook_region_reader", func(ctx context.Context) error {
+		seen = set
+		return nil
+	})
+	defer inithook.DefaultHooks.Reset()
+	assert.Nilf(t, inithook.DefaultHooks.RunPhase(ctx, inithook.PhaseInit), "run init")
+	assert.Equalf(t, "eu", seen, "attrs are loaded before the other init hooks")
+}
+
+func TestAttrFlagBinding(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("TEST_FLAG_NAME", "env")
+	name := inithook.NewAttr("test_flag_name",
+		inithook.WithFlagBinding[string]("test-flag-name", "app name"),
+		inithook.WithEnvBinding[string]("TEST_FLAG_NAME"),
+		inithook.WithDefault("default"))
+	verbose := inithook.NewAttr("test_flag_verbose", inithook.WithFlagBinding[bool]("test-flag-verbose", "verbose"))
+	tags := inithook.NewAttr("test_flag_tags", inithook.WithFlagBinding[[]string]("test-flag-tags", "tags"))
+
+	var names []string
+	for _, f := range inithook.Flags() {
+		names = append(names, f.Name)
+	}
+	assert.Subsetf(t, names, []string{"test-flag-name", "test-flag-verbose", "test-flag-tags"}, "flags")
+
+	value, _ := name.Value(ctx)
+	assert.Equalf(t, "env", value, "env before parsed")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	inithook.BindFlags(fs)
+	assert.Nilf(t, fs.Parse([]string{"-test-flag-name", "flag", "-test-flag-verbose", "-test-flag-tags=a,b"}), "parse")
+	value, _ = name.Value(ctx)
+	assert.Equalf(t, "flag", value, "flag overrides env")
+	v, _ := verbose.Value(ctx)
+	assert.Truef(t, v, "bool flag")
+	ts, _ := tags.Value(ctx)
+	assert.Equalf(t, []string{"a", "b"}, ts, "slice flag")
+	assert.Equalf(t, "[]string", fs.Lookup("test-flag-tags").Value.(inithook.FlagValue).Type(), "pflag type")
+	assert.NotNilf(t, fs.Set("test-flag-verbose", "x"), "parse error")
+
+	assert.Nilf(t, name.Set(ctx, "explicit"), "set")
+	value, _ = name.Value(ctx)
+	assert.Equalf(t, "explicit", value, "explicit set overrides flag")
+}
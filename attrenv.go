@@ -0,0 +1,104 @@
+package inithook
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// WithEnvBinding binds the attribute to the env variable name, which overrides the declared default(see `WithDefault`)
+// if the app never sets the attribute, the env value is converted to T which is one of string, bool, ints, uints, floats,
+// `time.Duration`, `encoding.TextUnmarshaler` or a slice of them separated by comma, see `LoadAttrs`
+func WithEnvBinding[T any](name string) AttrOption[T] {
+	return func(a *TypedAttr[T]) {
+		a.env = name
+	}
+}
+
+// lookupEnv returns the value of the bound env variable, returns false if not bound or not present
+func (a *TypedAttr[T]) lookupEnv() (T, bool, error) {
+	var value T
+	if a.env == "" {
+		return value, false, nil
+	}
+	s, ok := os.LookupEnv(a.env)
+	if !ok {
+		return value, false, nil
+	}
+	if err := parseText(reflect.ValueOf(&value).Elem(), s); err != nil {
+		return value, false, fmt.Errorf("inithook: attr %s env %s=%q parse failed: %w", a.name, a.env, s, err)
+	}
+	return value, true, nil
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// parseText parses s into v, it is shared by the env binding(`WithEnvBinding`) and the flag binding(`WithFlagBinding`)
+func parseText(v reflect.Value, s string) error {
+	if v.CanAddr() {
+		if tu, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return tu.UnmarshalText([]byte(s))
+		}
+	}
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := parseText(slice.Index(i), strings.TrimSpace(part)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Ptr:
+		elem := reflect.New(v.Type().Elem())
+		if err := parseText(elem.Elem(), s); err != nil {
+			return err
+		}
+		v.Set(elem)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package inithook
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// WithFlagBinding binds the attribute to the command-line flag name, which overrides the bound env(see `WithEnvBinding`)
+// and the declared default(see `WithDefault`) if the app never sets the attribute,
+// the flag is registered by `BindFlags` or `Flags` and parsed like `WithEnvBinding`, see `LoadAttrs`
+func WithFlagBinding[T any](name, usage string) AttrOption[T] {
+	return func(a *TypedAttr[T]) {
+		a.flagName = name
+		a.flagUsage = usage
+	}
+}
+
+// FlagValue is the value of an attribute flag, which implements both `flag.Value` and `pflag.Value`
+type FlagValue interface {
+	flag.Value
+	Type() string
+}
+
+// AttrFlag describes the command-line flag of an attribute
+type AttrFlag struct {
+	Attr  Attr
+	Name  string
+	Usage string
+	Value FlagValue
+}
+
+// Flags returns the flags of all attributes bound by `WithFlagBinding` sorted by name,
+// used to register the flags to a flag library other than the standard flag package, e.g. pflag:
+//
+//	for _, f := range inithook.Flags() {
+//		pflag.CommandLine.Var(f.Value, f.Name, f.Usage)
+//	}
+func Flags() []AttrFlag {
+	var flags []AttrFlag
+	typedAttrs.Range(context.Background(), func(_, value any) bool {
+		if f, ok := value.(attrLoader).flag(); ok {
+			flags = append(flags, f)
+		}
+		return true
+	})
+	sort.Slice(flags, func(i, j int) bool {
+		return flags[i].Name < flags[j].Name
+	})
+	return flags
+}
+
+// BindFlags registers the flags of all attributes bound by `WithFlagBinding` to fs, default to `flag.CommandLine`,
+// should be called before `fs.Parse`
+func BindFlags(fs *flag.FlagSet) {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	for _, f := range Flags() {
+		fs.Var(f.Value, f.Name, f.Usage)
+	}
+}
+
+// flag implements `attrLoader`
+func (a *TypedAttr[T]) flag() (AttrFlag, bool) {
+	if a.flagName == "" {
+		return AttrFlag{}, false
+	}
+	return AttrFlag{Attr: a.name, Name: a.flagName, Usage: a.flagUsage, Value: attrFlagValue[T]{attr: a}}, true
+}
+
+// attrFlagValue implements `FlagValue` of an attribute
+type attrFlagValue[T any] struct {
+	attr *TypedAttr[T]
+}
+
+// String implements `flag.Value`
+func (v attrFlagValue[T]) String() string {
+	if v.attr == nil { // zero value used by flag.PrintDefaults
+		return ""
+	}
+	if value := v.attr.flagValue.Load(); value != nil {
+		return fmt.Sprint(*value)
+	}
+	return ""
+}
+
+// Set implements `flag.Value`
+func (v attrFlagValue[T]) Set(s string) error {
+	var value T
+	if err := parseText(reflect.ValueOf(&value).Elem(), s); err != nil {
+		return err
+	}
+	v.attr.flagValue.Store(&value)
+	return nil
+}
+
+// Type implements `pflag.Value`
+func (v attrFlagValue[T]) Type() string {
+	return reflect.TypeOf(new(T)).Elem().String()
+}
+
+// IsBoolFlag tells the standard flag package the flag can be given without value, e.g. `-debug`
+func (v attrFlagValue[T]) IsBoolFlag() bool {
+	return reflect.TypeOf(new(T)).Elem().Kind() == reflect.Bool
+}
@@ -0,0 +1,91 @@
+package inithook
+
+import "context"
+
+// RegisterMany register a batch of V's instances under a single lock acquisition,
+// if any key exists then return `ErrAlreadyExists` error(use `errors.Is` to assert) and nothing registered,
+// so as any value is invalid
+func (m *Map[K, V]) RegisterMany(ctx context.Context, values map[K]V) error {
+	values = m.keys(values)
+	if err := m.validateMany(ctx, "RegisterMany", values); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("RegisterMany")
+	}
+	for key := range values {
+		if m.exists(key) {
+			m.lock.Unlock()
+			return m.errAlreadyExists("RegisterMany", key)
+		}
+	}
+	events := make([]Event[K, V], 0, len(values))
+	for key, value := range values {
+		m.put(key, value)
+		events = append(events, Event[K, V]{Type: EventRegister, Key: key, NewValue: value})
+	}
+	m.lock.Unlock()
+	m.notify(events...)
+	return nil
+}
+
+// SetMany set a batch of V's instances under a single lock acquisition, if exists then override,
+// if any value is invalid nothing set
+func (m *Map[K, V]) SetMany(ctx context.Context, values map[K]V) error {
+	values = m.keys(values)
+	if err := m.validateMany(ctx, "SetMany", values); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("SetMany")
+	}
+	events := make([]Event[K, V], 0, len(values))
+	for key, value := range values {
+		old, loaded := m.load(key)
+		m.put(key, value)
+		events = append(events, Event[K, V]{Type: EventSet, Key: key, OldValue: old, NewValue: value, Loaded: loaded})
+	}
+	m.lock.Unlock()
+	m.notify(events...)
+	return m.release(ctx, events...)
+}
+
+// GetMany get a batch of V's instances under a single lock acquisition, keys not found are omitted from the result,
+// NOTE: the result is keyed by normalized keys if `WithKeyNormalizer` is used
+func (m *Map[K, V]) GetMany(ctx context.Context, keys []K) map[K]V {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	values := make(map[K]V, len(keys))
+	for _, key := range keys {
+		key = m.key(key)
+		if v, ok := m.load(key); ok {
+			values[key] = m.copy(v)
+		}
+	}
+	return values
+}
+
+// DeleteMany delete a batch of V's instances under a single lock acquisition
+func (m *Map[K, V]) DeleteMany(ctx context.Context, keys []K) error {
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("DeleteMany", keys...)
+	}
+	events := make([]Event[K, V], 0, len(keys))
+	for _, key := range keys {
+		key = m.key(key)
+		if old, loaded := m.load(key); loaded {
+			events = append(events, Event[K, V]{Type: EventDelete, Key: key, OldValue: old, Loaded: true})
+		}
+		m.remove(key)
+		delete(m.providers, key)
+	}
+	m.lock.Unlock()
+	m.notify(events...)
+	return m.release(ctx, events...)
+}
@@ -0,0 +1,250 @@
+package inithook
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+)
+
+// EvictionPolicy decides which instance to evict when a bounded map is full
+type EvictionPolicy int
+
+// eviction policies
+const (
+	// EvictLRU evicts the least recently used instance
+	EvictLRU EvictionPolicy = iota
+	// EvictLFU evicts the least frequently used instance, ties are broken by recency
+	EvictLFU
+)
+
+// NewBoundedMap creates a new map which holds at most capacity instances, when full the instance chosen by policy is evicted,
+// evicted instances are reported to the `WithOnEvict` callback and watchers as `EventDelete`
+func NewBoundedMap[K comparable, V any](capacity int, policy EvictionPolicy, opts ...Option[K, V]) *Map[K, V] {
+	var m *Map[K, V]
+	onEvict := func(key K, value V) {
+		// NOTE: called by Store under the write lock of m
+		m.cleanup(key)
+		m.pendingLock.Lock()
+		m.pending = append(m.pending, Event[K, V]{Type: EventDelete, Key: key, OldValue: value, Loaded: true})
+		m.pendingLock.Unlock()
+	}
+	var store Store[K, V]
+	switch policy {
+	case EvictLFU:
+		store = &lfuStore[K, V]{capacity: capacity, items: map[K]*lfuItem[K, V]{}, onEvict: onEvict}
+	default:
+		store = &lruStore[K, V]{capacity: capacity, items: map[K]*list.Element{}, order: list.New(), onEvict: onEvict}
+	}
+	m = NewMapWithStore(store, opts...)
+	return m
+}
+
+// flushEvicted reports the instances evicted by a bounded store, must be called without holding the map lock
+func (m *Map[K, V]) flushEvicted() {
+	m.pendingLock.Lock()
+	events := m.pending
+	m.pending = nil
+	m.pendingLock.Unlock()
+	if len(events) > 0 {
+		m.evicted(events...)
+	}
+}
+
+// lruStore is a bounded Store with LRU eviction, Load updates recency, so it needs its own lock
+// since Map only holds the read lock when loading
+type lruStore[K comparable, V any] struct {
+	lock     sync.Mutex
+	capacity int
+	items    map[K]*list.Element
+	order    *list.List // front is the most recently used
+	onEvict  func(key K, value V)
+}
+
+type lruItem[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func (s *lruStore[K, V]) Load(key K) (V, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if e, ok := s.items[key]; ok {
+		s.order.MoveToFront(e)
+		return e.Value.(*lruItem[K, V]).value, true
+	}
+	return *new(V), false
+}
+
+func (s *lruStore[K, V]) Store(key K, value V) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if e, ok := s.items[key]; ok {
+		e.Value.(*lruItem[K, V]).value = value
+		s.order.MoveToFront(e)
+		return
+	}
+	s.items[key] = s.order.PushFront(&lruItem[K, V]{key: key, value: value})
+	for s.capacity > 0 && len(s.items) > s.capacity {
+		item := s.order.Remove(s.order.Back()).(*lruItem[K, V])
+		delete(s.items, item.key)
+		s.onEvict(item.key, item.value)
+	}
+}
+
+func (s *lruStore[K, V]) Delete(key K) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if e, ok := s.items[key]; ok {
+		s.order.Remove(e)
+		delete(s.items, key)
+	}
+}
+
+func (s *lruStore[K, V]) Clear() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.items = map[K]*list.Element{}
+	s.order.Init()
+}
+
+func (s *lruStore[K, V]) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.items)
+}
+
+func (s *lruStore[K, V]) Range(fn func(key K, value V) bool) {
+	s.lock.Lock()
+	items := make([]lruItem[K, V], 0, len(s.items))
+	for e := s.order.Front(); e != nil; e = e.Next() {
+		items = append(items, *e.Value.(*lruItem[K, V]))
+	}
+	s.lock.Unlock()
+	for _, item := range items {
+		if !fn(item.key, item.value) {
+			return
+		}
+	}
+}
+
+// lfuStore is a bounded Store with LFU eviction, implemented as a min-heap of (frequency, last access tick)
+type lfuStore[K comparable, V any] struct {
+	lock     sync.Mutex
+	capacity int
+	items    map[K]*lfuItem[K, V]
+	heap     lfuHeap[K, V]
+	tick     uint64
+	onEvict  func(key K, value V)
+}
+
+type lfuItem[K comparable, V any] struct {
+	key   K
+	value V
+	freq  uint64
+	tick  uint64
+	index int
+}
+
+func (s *lfuStore[K, V]) touch(item *lfuItem[K, V]) {
+	s.tick++
+	item.freq++
+	item.tick = s.tick
+	heap.Fix(&s.heap, item.index)
+}
+
+func (s *lfuStore[K, V]) Load(key K) (V, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if item, ok := s.items[key]; ok {
+		s.touch(item)
+		return item.value, true
+	}
+	return *new(V), false
+}
+
+func (s *lfuStore[K, V]) Store(key K, value V) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if item, ok := s.items[key]; ok {
+		item.value = value
+		s.touch(item)
+		return
+	}
+	if s.capacity > 0 && len(s.items) >= s.capacity {
+		item := heap.Pop(&s.heap).(*lfuItem[K, V])
+		delete(s.items, item.key)
+		s.onEvict(item.key, item.value)
+	}
+	s.tick++
+	item := &lfuItem[K, V]{key: key, value: value, freq: 1, tick: s.tick}
+	s.items[key] = item
+	heap.Push(&s.heap, item)
+}
+
+func (s *lfuStore[K, V]) Delete(key K) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if item, ok := s.items[key]; ok {
+		heap.Remove(&s.heap, item.index)
+		delete(s.items, key)
+	}
+}
+
+func (s *lfuStore[K, V]) Clear() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.items = map[K]*lfuItem[K, V]{}
+	s.heap = nil
+}
+
+func (s *lfuStore[K, V]) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return len(s.items)
+}
+
+func (s *lfuStore[K, V]) Range(fn func(key K, value V) bool) {
+	s.lock.Lock()
+	items := make([]lfuItem[K, V], 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, *item)
+	}
+	s.lock.Unlock()
+	for _, item := range items {
+		if !fn(item.key, item.value) {
+			return
+		}
+	}
+}
+
+type lfuHeap[K comparable, V any] []*lfuItem[K, V]
+
+func (h lfuHeap[K, V]) Len() int { return len(h) }
+
+func (h lfuHeap[K, V]) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *lfuHeap[K, V]) Push(x any) {
+	item := x.(*lfuItem[K, V])
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *lfuHeap[K, V]) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,66 @@
+package inithook_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundedMapLRU(t *testing.T) {
+	var evicted []string
+	m := inithook.NewBoundedMap(2, inithook.EvictLRU, inithook.WithOnEvict(func(key string, value int) {
+		evicted = append(evicted, key)
+	}))
+	ctx := context.Background()
+	m.MustSet(ctx, "one", 1)
+	m.MustSet(ctx, "two", 2)
+	_, err := m.Get(ctx, "one")
+	assert.Nilf(t, err, "get one")
+	m.MustSet(ctx, "three", 3)
+	assert.Equalf(t, []string{"two"}, evicted, "two is least recently used")
+	assert.ElementsMatchf(t, []string{"one", "three"}, m.Keys(ctx), "keys")
+	m.MustSet(ctx, "four", 4)
+	assert.Equalf(t, []string{"two", "one"}, evicted, "one is least recently used")
+}
+
+func TestBoundedMapLFU(t *testing.T) {
+	var events []inithook.Event[string, int]
+	m := inithook.NewBoundedMap[string, int](2, inithook.EvictLFU)
+	ctx := context.Background()
+	m.Watch(ctx, func(ev inithook.Event[string, int]) {
+		if ev.Type == inithook.EventDelete {
+			events = append(events, ev)
+		}
+	})
+	m.MustSet(ctx, "one", 1)
+	m.MustSet(ctx, "two", 2)
+	for i := 0; i < 3; i++ {
+		m.Get(ctx, "two")
+	}
+	m.Get(ctx, "one")
+	m.MustSet(ctx, "three", 3)
+	assert.Equalf(t, []inithook.Event[string, int]{
+		{Type: inithook.EventDelete, Key: "one", OldValue: 1, Loaded: true},
+	}, events, "one is least frequently used")
+	m.MustSet(ctx, "four", 4)
+	assert.Equalf(t, "three", events[1].Key, "three is least frequently used")
+	assert.ElementsMatchf(t, []string{"two", "four"}, m.Keys(ctx), "keys")
+	m.MustDelete(ctx, "two")
+	m.MustSet(ctx, "five", 5)
+	assert.Lenf(t, events, 3, "one, three evicted and two deleted")
+}
+
+func TestBoundedMapEvictCleanup(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewBoundedMap(1, inithook.EvictLRU, inithook.WithHistory[string, int](2))
+	m.MustSet(ctx, "one", 1)
+	m.MustSet(ctx, "one", 11)
+	assert.Equalf(t, []int{1}, m.History(ctx, "one"), "history")
+	m.MustSet(ctx, "two", 2)
+	assert.Falsef(t, m.Has(ctx, "one"), "evicted")
+	assert.Emptyf(t, m.History(ctx, "one"), "history dropped with the evicted")
+	m.MustSet(ctx, "one", 111)
+	assert.Emptyf(t, m.History(ctx, "one"), "stale history is not applied when stored again")
+}
@@ -0,0 +1,51 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+)
+
+// NewChain creates a fallback chain over maps, which are consulted in order,
+// e.g. request-scoped -> app-scoped -> defaults
+func NewChain[K comparable, V any](maps ...*Map[K, V]) *Chain[K, V] {
+	return &Chain[K, V]{
+		maps: maps,
+	}
+}
+
+// Chain layers multiple Maps, the former map overrides the latter
+type Chain[K comparable, V any] struct {
+	maps []*Map[K, V]
+}
+
+// Get returns the instance of key from the first map which has key,
+// if no map has key then return `ErrNotFound` error(use `errors.Is` to assert)
+func (c *Chain[K, V]) Get(ctx context.Context, key K) (V, error) {
+	for _, m := range c.maps {
+		if !m.Has(ctx, key) {
+			continue
+		}
+		value, err := m.Get(ctx, key)
+		if errors.Is(err, ErrNotFound) { // deleted concurrently, fall through
+			continue
+		}
+		return value, err
+	}
+	var zero V
+	return zero, newRegistryError[V]("Get", key, ErrNotFound, nil)
+}
+
+// Has reports whether any map of the chain has key
+func (c *Chain[K, V]) Has(ctx context.Context, key K) bool {
+	for _, m := range c.maps {
+		if m.Has(ctx, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Maps returns the maps of the chain in lookup order
+func (c *Chain[K, V]) Maps() []*Map[K, V] {
+	return append([]*Map[K, V](nil), c.maps...)
+}
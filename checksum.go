@@ -0,0 +1,129 @@
+package inithook
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// checksum records the checksum of the V's instance of key if `WithImmutabilityCheck` is used, must be called with write lock held
+func (m *Map[K, V]) checksum(key K, value V) {
+	if m.hasher == nil {
+		return
+	}
+	if m.checksums == nil {
+		m.checksums = make(map[K]uint64)
+	}
+	m.checksums[key] = m.hasher(value)
+}
+
+// mutated tells if the V's instance of key is mutated in place since stored, must be called with lock held
+func (m *Map[K, V]) mutated(key K, value V) bool {
+	if m.hasher == nil {
+		return false
+	}
+	sum, ok := m.checksums[key]
+	return ok && sum != m.hasher(value)
+}
+
+// panicMutated panics for the V's instance of key mutated in place, must be called without lock held
+func (m *Map[K, V]) panicMutated(key K) {
+	panic(fmt.Sprintf("inithook: type %s instance %v is mutated in place after stored", typeName[V](), key))
+}
+
+// DeepHash returns the reflect-based deep hash of v, which follows pointers, and hashes maps regardless of the order,
+// funcs and channels are hashed by their pointers, used by `WithImmutabilityCheck` if no hasher given
+func DeepHash[V any](v V) uint64 {
+	h := fnv.New64a()
+	deepHash(h, reflect.ValueOf(&v).Elem(), make(map[uintptr]bool))
+	return h.Sum64()
+}
+
+// deepHash writes value into h, visited holds the pointers being hashed on the current path to guard the cycles
+func deepHash(h hash.Hash64, value reflect.Value, visited map[uintptr]bool) {
+	var buf [8]byte
+	writeUint := func(n uint64) {
+		binary.LittleEndian.PutUint64(buf[:], n)
+		h.Write(buf[:])
+	}
+	if !value.IsValid() {
+		writeUint(0)
+		return
+	}
+	switch value.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(value.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeUint(math.Float64bits(value.Float()))
+	case reflect.Complex64, reflect.Complex128:
+		writeUint(math.Float64bits(real(value.Complex())))
+		writeUint(math.Float64bits(imag(value.Complex())))
+	case reflect.String:
+		writeUint(uint64(value.Len()))
+		h.Write([]byte(value.String()))
+	case reflect.Pointer:
+		if value.IsNil() {
+			writeUint(0)
+			return
+		}
+		if visited[value.Pointer()] {
+			writeUint(uint64(value.Pointer()))
+			return
+		}
+		visited[value.Pointer()] = true
+		deepHash(h, value.Elem(), visited)
+		delete(visited, value.Pointer()) // only the pointers of the current path, the shared ones are hashed each time
+	case reflect.Interface:
+		if value.IsNil() {
+			writeUint(0)
+			return
+		}
+		h.Write([]byte(value.Elem().Type().String()))
+		deepHash(h, value.Elem(), visited)
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			writeUint(math.MaxUint64)
+			return
+		}
+		writeUint(uint64(value.Len()))
+		for i := 0; i < value.Len(); i++ {
+			deepHash(h, value.Index(i), visited)
+		}
+	case reflect.Map:
+		if value.IsNil() {
+			writeUint(math.MaxUint64)
+			return
+		}
+		sums := make([]uint64, 0, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			entry := fnv.New64a()
+			deepHash(entry, iter.Key(), visited)
+			deepHash(entry, iter.Value(), visited)
+			sums = append(sums, entry.Sum64())
+		}
+		sort.Slice(sums, func(i, j int) bool { return sums[i] < sums[j] })
+		writeUint(uint64(len(sums)))
+		for _, sum := range sums {
+			writeUint(sum)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			deepHash(h, value.Field(i), visited)
+		}
+	default: // func, chan, unsafe pointer
+		writeUint(uint64(value.Pointer()))
+	}
+}
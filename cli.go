@@ -0,0 +1,123 @@
+package inithook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// CommandName is the name of the subcommand handled by `Main`
+const CommandName = "inithook"
+
+// Main handles the `inithook` subcommand of the binary if it's the first argument, prints what the binary registers
+// and exits without starting the service, otherwise returns immediately, should be called at the start of main, e.g.
+//
+//	mybin inithook list
+//	mybin inithook graph [json]
+//	mybin inithook describe <key>
+func Main() {
+	if len(os.Args) < 2 || os.Args[1] != CommandName {
+		return
+	}
+	if err := Command(os.Stdout, os.Args[2:]...); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	os.Exit(0)
+}
+
+// Command executes the `inithook` subcommand args against `DefaultHooks` and all named registries and attrs,
+// and writes the output to w, see `Main`
+func Command(w io.Writer, args ...string) error {
+	if len(args) == 0 {
+		return errors.New(commandUsage)
+	}
+	ctx := context.Background()
+	switch args[0] {
+	case "list":
+		return commandList(ctx, w)
+	case "graph":
+		g := Graph()
+		if len(args) > 1 && args[1] == "json" {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(g)
+		}
+		_, err := io.WriteString(w, g.DOT())
+		return err
+	case "describe":
+		if len(args) < 2 {
+			return errors.New(commandUsage)
+		}
+		return commandDescribe(ctx, w, args[1])
+	default:
+		return fmt.Errorf("inithook: unknown command %q\n%s", args[0], commandUsage)
+	}
+}
+
+const commandUsage = `usage: inithook list|graph [json]|describe <key>`
+
+// commandList prints the registries, hooks and attrs
+func commandList(ctx context.Context, w io.Writer) error {
+	d := DefaultHooks.Debug(ctx)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "REGISTRY\tTYPE\tKEYS")
+	for _, r := range d.Registries {
+		keys := make([]string, 0, len(r.Keys))
+		for _, k := range r.Keys {
+			keys = append(keys, k.Key)
+		}
+		fmt.Fprintf(tw, "%s\tMap[%s, %s]\t%s\n", r.Name, r.KeyType, r.ValueType, strings.Join(keys, ","))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "PHASE\tHOOK\tPRIORITY\tREQUIRES")
+	for _, hk := range d.Hooks {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", hk.Phase, hk.Name, hk.Priority, strings.Join(hk.Requires, ","))
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "ATTR\tSETTERS")
+	attrs := attrConstructors.Keys(ctx)
+	sort.Strings(attrs)
+	settersLock.Lock()
+	for _, attr := range attrs {
+		fmt.Fprintf(tw, "%s\t%d\n", attr, len(setters[attr]))
+	}
+	settersLock.Unlock()
+	return tw.Flush()
+}
+
+// commandDescribe prints the metadata of key in all registries, if not found then return `ErrNotFound` error
+func commandDescribe(ctx context.Context, w io.Writer, key string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	var found bool
+	for _, r := range DefaultHooks.Debug(ctx).Registries {
+		for _, k := range r.Keys {
+			if k.Key != key {
+				continue
+			}
+			if found {
+				fmt.Fprintln(tw)
+			}
+			found = true
+			fmt.Fprintf(tw, "registry:\t%s\n", r.Name)
+			fmt.Fprintf(tw, "type:\tMap[%s, %s]\n", r.KeyType, r.ValueType)
+			fmt.Fprintf(tw, "key:\t%s\n", k.Key)
+			fmt.Fprintf(tw, "description:\t%s\n", k.Metadata.Description)
+			fmt.Fprintf(tw, "tags:\t%s\n", strings.Join(k.Metadata.Tags, ","))
+			if k.Info != nil {
+				fmt.Fprintf(tw, "registered at:\t%s\n", k.Info.RegisteredAt.Format("2006-01-02T15:04:05.000Z07:00"))
+				fmt.Fprintf(tw, "registered by:\t%s %s\n", k.Info.Caller.Function, k.Info.Caller)
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("inithook: key %s: %w", key, ErrNotFound)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,41 @@
+package inithook_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommand(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.MustRegisterMap[string, int]("test_cli")
+	m.MustRegister(ctx, "test_cli_key", 1, inithook.WithDescription("cli key"), inithook.WithTags("a", "b"))
+	inithook.OnShutdown("test_cli_hook", func(ctx context.Context) error { return nil })
+
+	var out bytes.Buffer
+	assert.Nilf(t, inithook.Command(&out, "list"), "list")
+	assert.Containsf(t, out.String(), "test_cli", "registry listed")
+	assert.Containsf(t, out.String(), "test_cli_key", "key listed")
+	assert.Containsf(t, out.String(), "test_cli_hook", "hook listed")
+	assert.Containsf(t, out.String(), inithook.AppName, "attr listed")
+
+	out.Reset()
+	assert.Nilf(t, inithook.Command(&out, "graph"), "graph")
+	assert.Containsf(t, out.String(), `"shutdown/test_cli_hook"`, "dot")
+	out.Reset()
+	assert.Nilf(t, inithook.Command(&out, "graph", "json"), "graph json")
+	assert.Containsf(t, out.String(), `"id": "shutdown/test_cli_hook"`, "json")
+
+	out.Reset()
+	assert.Nilf(t, inithook.Command(&out, "describe", "test_cli_key"), "describe")
+	assert.Containsf(t, out.String(), "cli key", "description")
+	assert.Containsf(t, out.String(), "a,b", "tags")
+	assert.Truef(t, errors.Is(inithook.Command(&out, "describe", "test_cli_missing"), inithook.ErrNotFound), "not found")
+	assert.NotNilf(t, inithook.Command(&out, "describe"), "usage")
+	assert.NotNilf(t, inithook.Command(&out, "unknown"), "usage")
+	assert.NotNilf(t, inithook.Command(&out), "usage")
+}
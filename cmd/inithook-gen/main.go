@@ -0,0 +1,215 @@
+// Command inithook-gen generates strongly-typed registry packages backed by inithook.Map, e.g.
+//
+//	//go:generate go run github.com/ccmonky/inithook/cmd/inithook-gen -out ./registry handlers=net/http.Handler ports=int
+//
+// generates the package ./registry/handlers of http.Handler and ./registry/ports of int, each has
+// `Register(name, value)`, `Get(name)` etc. and a test, and the Map is registered by `inithook.RegisterMap`
+// named as the package, so it can be discovered by `inithook.MapOf` and introspected by `inithook.DebugHandler`.
+// A type is `[*][]import/path.Type` or a predeclared type.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+func main() {
+	out := flag.String("out", ".", "output directory of the generated packages")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: inithook-gen [-out dir] package=type...\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	for _, arg := range flag.Args() {
+		spec, err := parseSpec(arg)
+		if err == nil {
+			err = generate(*out, spec)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "inithook-gen: %v\n", err)
+			os.Exit(1)
+		}
+	}
+}
+
+// spec is the spec of a generated package
+type spec struct {
+	Package string
+	Import  string // import path of the value type, empty if predeclared
+	Type    string // value type qualified by the package name of Import
+}
+
+// parseSpec parses `package=type`
+func parseSpec(arg string) (spec, error) {
+	pkg, typ, ok := strings.Cut(arg, "=")
+	if !ok || pkg == "" || typ == "" {
+		return spec{}, fmt.Errorf("invalid spec %q, should be package=type", arg)
+	}
+	if !token.IsIdentifier(pkg) {
+		return spec{}, fmt.Errorf("invalid package name %q", pkg)
+	}
+	s := spec{Package: pkg}
+	var prefix string
+	for strings.HasPrefix(typ, "*") || strings.HasPrefix(typ, "[]") {
+		if typ[0] == '*' {
+			prefix, typ = prefix+"*", typ[1:]
+		} else {
+			prefix, typ = prefix+"[]", typ[2:]
+		}
+	}
+	if i := strings.LastIndex(typ, "."); i > 0 {
+		s.Import = typ[:i]
+		name := typ[i+1:]
+		if !token.IsIdentifier(name) {
+			return spec{}, fmt.Errorf("invalid type name %q", name)
+		}
+		typ = path.Base(s.Import) + "." + name
+	} else if !token.IsIdentifier(typ) && typ != "any" {
+		return spec{}, fmt.Errorf("invalid type %q", typ)
+	}
+	s.Type = prefix + typ
+	return s, nil
+}
+
+// generate generates the package and its test of s in out dir
+func generate(out string, s spec) error {
+	dir := filepath.Join(out, s.Package)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	for name, tmpl := range map[string]*template.Template{
+		s.Package + ".go":      packageTemplate,
+		s.Package + "_test.go": testTemplate,
+	} {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, s); err != nil {
+			return err
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return fmt.Errorf("format %s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), src, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var packageTemplate = template.Must(template.New("package").Parse(`// Code generated by inithook-gen. DO NOT EDIT.
+
+// Package {{.Package}} is the registry of {{.Type}} backed by inithook.Map
+package {{.Package}}
+
+import (
+	"context"
+	"sort"
+{{if .Import}}
+	"{{.Import}}"
+{{end}}
+	"github.com/ccmonky/inithook"
+)
+
+// Registry is the Map of {{.Type}}, registered named "{{.Package}}" to be discovered by inithook.MapOf
+var Registry = inithook.MustRegisterMap[string, {{.Type}}]("{{.Package}}")
+
+// Register registers value named name, if exists then return inithook.ErrAlreadyExists error(use errors.Is to assert)
+func Register(name string, value {{.Type}}, opts ...inithook.RegisterOption) error {
+	return Registry.Register(context.Background(), name, value, opts...)
+}
+
+// MustRegister registers value named name, if failed(e.g. already exists) then panic, usually used in init
+func MustRegister(name string, value {{.Type}}, opts ...inithook.RegisterOption) {
+	Registry.MustRegister(context.Background(), name, value, opts...)
+}
+
+// Set sets value named name, overrides the existing one
+func Set(name string, value {{.Type}}) error {
+	return Registry.Set(context.Background(), name, value)
+}
+
+// Get returns the value named name, if not found then return inithook.ErrNotFound error(use errors.Is to assert)
+func Get(name string) ({{.Type}}, error) {
+	return Registry.Get(context.Background(), name)
+}
+
+// Has tells if name is registered
+func Has(name string) bool {
+	return Registry.Has(context.Background(), name)
+}
+
+// Delete deletes the value named name
+func Delete(name string) error {
+	return Registry.Delete(context.Background(), name)
+}
+
+// Names returns the sorted names of registered values
+func Names() []string {
+	names := Registry.Keys(context.Background())
+	sort.Strings(names)
+	return names
+}
+`))
+
+var testTemplate = template.Must(template.New("test").Parse(`// Code generated by inithook-gen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"errors"
+	"testing"
+{{if .Import}}
+	"{{.Import}}"
+{{end}}
+	"github.com/ccmonky/inithook"
+)
+
+func TestRegistry(t *testing.T) {
+	const name = "inithook-gen-test"
+	var value {{.Type}}
+	if err := Register(name, value); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	defer Delete(name)
+	if err := Register(name, value); !errors.Is(err, inithook.ErrAlreadyExists) {
+		t.Errorf("register twice should return ErrAlreadyExists, got %v", err)
+	}
+	if _, err := Get(name); err != nil {
+		t.Errorf("get: %v", err)
+	}
+	if !Has(name) {
+		t.Errorf("should has %s", name)
+	}
+	if err := Set(name, value); err != nil {
+		t.Errorf("set: %v", err)
+	}
+	var found bool
+	for _, n := range Names() {
+		found = found || n == name
+	}
+	if !found {
+		t.Errorf("names should contain %s, got %v", name, Names())
+	}
+	if m, err := inithook.MapOf[string, {{.Type}}]("{{.Package}}"); err != nil || m != Registry {
+		t.Errorf("map of: %v", err)
+	}
+	if err := Delete(name); err != nil {
+		t.Errorf("delete: %v", err)
+	}
+	if _, err := Get(name); !errors.Is(err, inithook.ErrNotFound) {
+		t.Errorf("get after delete should return ErrNotFound, got %v", err)
+	}
+}
+`))
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSpec(t *testing.T) {
+	cases := []struct {
+		arg  string
+		spec spec
+		err  bool
+	}{
+		{arg: "ports=int", spec: spec{Package: "ports", Type: "int"}},
+		{arg: "anys=any", spec: spec{Package: "anys", Type: "any"}},
+		{arg: "handlers=net/http.Handler", spec: spec{Package: "handlers", Import: "net/http", Type: "http.Handler"}},
+		{arg: "raws=*[]encoding/json.RawMessage", spec: spec{Package: "raws", Import: "encoding/json", Type: "*[]json.RawMessage"}},
+		{arg: "handlers", err: true},
+		{arg: "my-handlers=int", err: true},
+		{arg: "handlers=net/http.", err: true},
+		{arg: "handlers=map[string]int", err: true},
+	}
+	for _, c := range cases {
+		s, err := parseSpec(c.arg)
+		if c.err {
+			assert.NotNilf(t, err, "arg %s", c.arg)
+			continue
+		}
+		assert.Nilf(t, err, "arg %s", c.arg)
+		assert.Equalf(t, c.spec, s, "arg %s", c.arg)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	out := t.TempDir()
+	s, err := parseSpec("handlers=net/http.Handler")
+	assert.Nilf(t, err, "parse spec")
+	err = generate(out, s)
+	assert.Nilf(t, err, "generate")
+	for _, name := range []string{"handlers.go", "handlers_test.go"} {
+		path := filepath.Join(out, "handlers", name)
+		src, err := os.ReadFile(path)
+		assert.Nilf(t, err, "read %s", name)
+		assert.Truef(t, strings.HasPrefix(string(src), "// Code generated by inithook-gen. DO NOT EDIT."), "header of %s", name)
+		f, err := parser.ParseFile(token.NewFileSet(), path, src, parser.ImportsOnly)
+		assert.Nilf(t, err, "parse %s", name)
+		assert.Equalf(t, "handlers", f.Name.Name, "package of %s", name)
+	}
+	src, _ := os.ReadFile(filepath.Join(out, "handlers", "handlers.go"))
+	for _, decl := range []string{
+		`var Registry = inithook.MustRegisterMap[string, http.Handler]("handlers")`,
+		"func Register(name string, value http.Handler, opts ...inithook.RegisterOption) error",
+		"func Get(name string) (http.Handler, error)",
+		"func Names() []string",
+	} {
+		assert.Containsf(t, string(src), decl, "decl %s", decl)
+	}
+}
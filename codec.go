@@ -0,0 +1,48 @@
+package inithook
+
+import (
+	"context"
+	"encoding/gob"
+	"io"
+)
+
+// Codec used to serialize Map snapshots by `Encode` and `Decode`, see `WithCodec`
+type Codec interface {
+	Encode(w io.Writer, v any) error
+	Decode(r io.Reader, v any) error
+}
+
+// GobCodec is a Codec using `encoding/gob`, which is the default codec,
+// NOTE: interface values should be registered by `gob.Register`
+type GobCodec struct{}
+
+// Encode implements Codec
+func (GobCodec) Encode(w io.Writer, v any) error {
+	return gob.NewEncoder(w).Encode(v)
+}
+
+// Decode implements Codec
+func (GobCodec) Decode(r io.Reader, v any) error {
+	return gob.NewDecoder(r).Decode(v)
+}
+
+// Encode writes a snapshot(see `Snapshot`) of all instances to w, e.g. to persist the registry across restarts
+func (m *Map[K, V]) Encode(w io.Writer) error {
+	return m.codec().Encode(w, m.Snapshot(context.Background()))
+}
+
+// Decode reads a snapshot written by `Encode` from r and restores it(see `Restore`), e.g. for warm start
+func (m *Map[K, V]) Decode(r io.Reader) error {
+	var snapshot map[K]V
+	if err := m.codec().Decode(r, &snapshot); err != nil {
+		return err
+	}
+	return m.Restore(context.Background(), snapshot)
+}
+
+func (m *Map[K, V]) codec() Codec {
+	if m.valueCodec != nil {
+		return m.valueCodec
+	}
+	return GobCodec{}
+}
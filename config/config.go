@@ -0,0 +1,288 @@
+// Package config loads JSON, YAML and TOML config files into inithook attrs and named registries,
+// each top-level section of a file is mapped to the attr(see `inithook.RegisterAttrSetter` and `inithook.NewAttr`)
+// or the registry(see `inithook.RegisterMap`) of the same name, and the attr setters are executed to fan out the values
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ccmonky/inithook"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is the format of a config file
+type Format string
+
+// supported formats
+const (
+	JSON Format = "json"
+	YAML Format = "yaml"
+	TOML Format = "toml"
+)
+
+// ErrUnknownKey defines the error of config sections or fields matching no attr, registry or struct field
+var ErrUnknownKey = errors.New("unknown key")
+
+// Option used to configure the loading
+type Option func(o *options)
+
+type options struct {
+	format        Format
+	optional      bool
+	ignoreUnknown bool
+	errorHandler  func(err error)
+}
+
+// WithFormat sets the format of the file, default to the one detected by the file extension
+func WithFormat(format Format) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// Optional makes a missing file not an error, nothing is loaded
+func Optional() Option {
+	return func(o *options) {
+		o.optional = true
+	}
+}
+
+// IgnoreUnknown ignores the sections matching no attr or registry and the unknown fields of struct attrs,
+// instead of returning `ErrUnknownKey` error
+func IgnoreUnknown() Option {
+	return func(o *options) {
+		o.ignoreUnknown = true
+	}
+}
+
+// Load loads the config file of path, see `Decode`,
+// if the file not exists then return an error wrapping `fs.ErrNotExist`(use `errors.Is` to assert) unless `Optional`
+func Load(ctx context.Context, path string, opts ...Option) error {
+	o := newOptions(opts)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && o.optional {
+			return nil
+		}
+		return fmt.Errorf("config: load %s failed: %w", path, err)
+	}
+	if o.format == "" {
+		o.format, err = detect(path)
+		if err != nil {
+			return err
+		}
+	}
+	if err := decode(ctx, data, o); err != nil {
+		return fmt.Errorf("config: load %s failed: %w", path, err)
+	}
+	return nil
+}
+
+// Decode decodes data of format, sets the registries and executes the attr setters of the top-level sections,
+// attrs take precedence over registries of the same name, all sections are checked before anything is set,
+// if some sections match no attr or registry then return `ErrUnknownKey` error listing them unless `IgnoreUnknown`
+func Decode(ctx context.Context, data []byte, format Format, opts ...Option) error {
+	o := newOptions(opts)
+	o.format = format
+	return decode(ctx, data, o)
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// detect detects the format by the extension of path
+func detect(path string) (Format, error) {
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		return JSON, nil
+	case ".yaml", ".yml":
+		return YAML, nil
+	case ".toml":
+		return TOML, nil
+	default:
+		return "", fmt.Errorf("config: unknown format of %s, use `WithFormat`", path)
+	}
+}
+
+// Sections decodes a document of format into the top-level sections encoded as json, used by the `Source` implementations
+func Sections(data []byte, format Format) (map[string]json.RawMessage, error) {
+	var values map[string]any
+	switch format {
+	case JSON:
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	case YAML:
+		if err := yaml.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+	case TOML:
+		if _, err := toml.Decode(string(data), &values); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("config: unsupported format %q", format)
+	}
+	raw := make(map[string]json.RawMessage, len(values))
+	for key, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("section %s: %w", key, err)
+		}
+		raw[key] = data
+	}
+	return raw, nil
+}
+
+// Section converts a section value of format into json, used by the `Source` implementations,
+// a TOML value should be a table
+func Section(data []byte, format Format) (json.RawMessage, error) {
+	var value any
+	switch format {
+	case JSON:
+		if !json.Valid(data) {
+			return nil, fmt.Errorf("config: invalid json %q", data)
+		}
+		return json.RawMessage(data), nil
+	case YAML:
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+	case TOML:
+		var table map[string]any
+		if _, err := toml.Decode(string(data), &table); err != nil {
+			return nil, err
+		}
+		value = table
+	default:
+		return nil, fmt.Errorf("config: unsupported format %q", format)
+	}
+	return json.Marshal(value)
+}
+
+// target is a resolved section
+type target struct {
+	key      string
+	data     json.RawMessage
+	attr     bool
+	value    any      // attr value
+	registry registry // registry
+}
+
+// registry is implemented by `*inithook.Map`
+type registry interface {
+	json.Unmarshaler
+	StageJSON(ctx context.Context, old, new []byte) (func(ctx context.Context) error, error)
+}
+
+func decode(ctx context.Context, data []byte, o *options) error {
+	raw, err := Sections(data, o.format)
+	if err != nil {
+		return err
+	}
+	return apply(ctx, raw, o)
+}
+
+// apply sets the registries and executes the attr setters of the sections
+func apply(ctx context.Context, raw map[string]json.RawMessage, o *options) error {
+	targets, err := resolve(raw, o)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		if t.attr {
+			err = inithook.ExecuteAttrSetters(ctx, t.key, t.value)
+		} else if err = t.registry.UnmarshalJSON(t.data); err != nil {
+			err = fmt.Errorf("registry %s: %w", t.key, err)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolve resolves the attrs and registries of the sections sorted by key, and decodes the attr values
+func resolve(raw map[string]json.RawMessage, o *options) ([]target, error) {
+	registries := make(map[string][]inithook.RegistryInfo)
+	for _, info := range inithook.Registries() {
+		registries[info.Name] = append(registries[info.Name], info)
+	}
+	keys := make([]string, 0, len(raw))
+	for key := range raw {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var targets []target
+	var unknown []string
+	for _, key := range keys {
+		if fn := inithook.GetAttrConstructor(key); fn != nil {
+			value, err := decodeAttr(raw[key], fn(), !o.ignoreUnknown)
+			if err != nil {
+				return nil, fmt.Errorf("attr %s: %w", key, err)
+			}
+			targets = append(targets, target{key: key, data: raw[key], attr: true, value: value})
+			continue
+		}
+		switch infos := registries[key]; len(infos) {
+		case 0:
+			unknown = append(unknown, key)
+		case 1:
+			reg, err := inithook.Registry(infos[0])
+			if err != nil {
+				return nil, err
+			}
+			r, ok := reg.(registry)
+			if !ok {
+				return nil, fmt.Errorf("registry %s: %T should be an *inithook.Map", key, reg)
+			}
+			targets = append(targets, target{key: key, data: raw[key], registry: r})
+		default:
+			return nil, fmt.Errorf("registry %s: ambiguous, %d registries of different types", key, len(infos))
+		}
+	}
+	if len(unknown) > 0 && !o.ignoreUnknown {
+		return nil, fmt.Errorf("sections %s: %w", strings.Join(unknown, ", "), ErrUnknownKey)
+	}
+	return targets, nil
+}
+
+// decodeAttr decodes data into a value of the same type as zero, disallows unknown struct fields if strict
+func decodeAttr(data json.RawMessage, zero any, strict bool) (any, error) {
+	var ptr reflect.Value
+	if zero == nil { // interface attr
+		ptr = reflect.New(reflect.TypeOf(&zero).Elem())
+	} else {
+		ptr = reflect.New(reflect.TypeOf(zero))
+		ptr.Elem().Set(reflect.ValueOf(zero))
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(ptr.Interface()); err != nil {
+		if strict && strings.HasPrefix(err.Error(), "json: unknown field") {
+			return nil, fmt.Errorf("%s: %w", strings.TrimPrefix(err.Error(), "json: "), ErrUnknownKey)
+		}
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
@@ -0,0 +1,92 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/ccmonky/inithook/config"
+	"github.com/stretchr/testify/assert"
+)
+
+type Server struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+var (
+	appName   string
+	server    Server
+	endpoints = inithook.MustRegisterMap[string, string]("endpoints")
+)
+
+func init() {
+	inithook.AppNameAttr.Register(func(ctx context.Context, v string) error {
+		appName = v
+		return nil
+	}, inithook.WithDynamic())
+	inithook.NewAttr[Server]("server").Register(func(ctx context.Context, v Server) error {
+		server = v
+		return nil
+	}, inithook.WithDynamic())
+}
+
+func write(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	files := map[string]string{
+		"app.json": `{"app_name": "json", "server": {"host": "a", "port": 1}, "endpoints": {"users": "/json/users"}}`,
+		"app.yaml": "app_name: yaml\nserver:\n  host: b\n  port: 2\nendpoints:\n  users: /yaml/users\n",
+		"app.toml": "app_name = \"toml\"\n[server]\nhost = \"c\"\nport = 3\n[endpoints]\nusers = \"/toml/users\"\n",
+	}
+	expected := map[string]Server{
+		"app.json": {Host: "a", Port: 1},
+		"app.yaml": {Host: "b", Port: 2},
+		"app.toml": {Host: "c", Port: 3},
+	}
+	for name, content := range files {
+		err := config.Load(ctx, write(t, name, content))
+		assert.Nilf(t, err, "load %s", name)
+		assert.Equalf(t, name[4:], appName, "attr of %s", name)
+		assert.Equalf(t, expected[name], server, "struct attr of %s", name)
+		users, err := endpoints.Get(ctx, "users")
+		assert.Nilf(t, err, "registry of %s", name)
+		assert.Equalf(t, "/"+name[4:]+"/users", users, "registry of %s", name)
+	}
+}
+
+func TestLoadDiagnostics(t *testing.T) {
+	ctx := context.Background()
+	err := config.Load(ctx, filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Truef(t, errors.Is(err, fs.ErrNotExist), "file not found")
+	assert.Nilf(t, config.Load(ctx, filepath.Join(t.TempDir(), "missing.yaml"), config.Optional()), "optional")
+
+	appName = ""
+	path := write(t, "app.yaml", "app_name: x\nunknown_a: 1\nunknown_b: 2\n")
+	err = config.Load(ctx, path)
+	assert.Truef(t, errors.Is(err, config.ErrUnknownKey), "unknown keys")
+	assert.Containsf(t, err.Error(), "unknown_a, unknown_b", "unknown keys listed")
+	assert.Equalf(t, "", appName, "nothing set")
+	assert.Nilf(t, config.Load(ctx, path, config.IgnoreUnknown()), "ignore unknown")
+	assert.Equalf(t, "x", appName, "attr")
+
+	err = config.Decode(ctx, []byte(`{"server": {"host": "a", "hots": "b"}}`), config.JSON)
+	assert.Truef(t, errors.Is(err, config.ErrUnknownKey), "unknown field")
+	assert.Containsf(t, err.Error(), `"hots"`, "unknown field")
+
+	err = config.Load(ctx, write(t, "app.ini", "app_name=x"))
+	assert.NotNilf(t, err, "unknown format")
+	assert.Nilf(t, config.Load(ctx, write(t, "app.conf", "app_name: conf"), config.WithFormat(config.YAML)), "with format")
+	assert.Equalf(t, "conf", appName, "with format")
+}
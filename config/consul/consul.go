@@ -0,0 +1,97 @@
+// Package consul implements the `config.Source` of the Consul KV store
+package consul
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ccmonky/inithook/config"
+	"github.com/hashicorp/consul/api"
+)
+
+// Source is a `config.Source` of the keys under a prefix of the Consul KV store, each key with the prefix trimmed
+// is a section, the changes are watched by blocking queries
+type Source struct {
+	kv     *api.KV
+	prefix string
+	format config.Format
+
+	lock     sync.Mutex
+	index    uint64
+	modified map[string]uint64 // modify index of keys
+}
+
+// NewSource creates a `Source` of the keys under prefix, whose values are encoded in format, default to `config.JSON`
+func NewSource(client *api.Client, prefix string, format config.Format) *Source {
+	if format == "" {
+		format = config.JSON
+	}
+	return &Source{kv: client.KV(), prefix: prefix, format: format}
+}
+
+// Load implements `config.Source`
+func (s *Source) Load(ctx context.Context) (map[string]json.RawMessage, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	sections, _, err := s.list(ctx, 0)
+	return sections, err
+}
+
+// Watch implements `config.Source`, watches the changes since the index of the last `Load`
+func (s *Source) Watch(ctx context.Context, fn func(changes map[string]json.RawMessage)) error {
+	for {
+		s.lock.Lock()
+		_, changes, err := s.list(ctx, s.index)
+		s.lock.Unlock()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if len(changes) > 0 {
+			fn(changes)
+		}
+	}
+}
+
+// list lists the keys blocking until the index of the prefix is greater than index,
+// returns all sections and the changed ones since the last list, must be called with lock held
+func (s *Source) list(ctx context.Context, index uint64) (map[string]json.RawMessage, map[string]json.RawMessage, error) {
+	pairs, meta, err := s.kv.List(s.prefix, (&api.QueryOptions{WaitIndex: index}).WithContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+	sections := make(map[string]json.RawMessage, len(pairs))
+	changes := make(map[string]json.RawMessage)
+	modified := make(map[string]uint64, len(pairs))
+	for _, pair := range pairs {
+		key := strings.TrimPrefix(pair.Key, s.prefix)
+		if key == "" || strings.HasSuffix(key, "/") { // folder
+			continue
+		}
+		section, err := config.Section(pair.Value, s.format)
+		if err != nil {
+			return nil, nil, fmt.Errorf("consul: key %s: %w", pair.Key, err)
+		}
+		sections[key] = section
+		modified[key] = pair.ModifyIndex
+		if s.modified[key] != pair.ModifyIndex {
+			changes[key] = section
+		}
+	}
+	for key := range s.modified {
+		if _, ok := modified[key]; !ok {
+			changes[key] = nil
+		}
+	}
+	s.modified = modified
+	s.index = meta.LastIndex
+	if s.index < index { // index reset, see https://developer.hashicorp.com/consul/api-docs/features/blocking
+		s.index = 0
+	}
+	return sections, changes, nil
+}
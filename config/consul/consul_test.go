@@ -0,0 +1,81 @@
+package consul_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ccmonky/inithook/config/consul"
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+)
+
+type pair struct {
+	value string
+	index uint64
+}
+
+func TestSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lock sync.Mutex
+	changed := make(chan struct{})
+	index := uint64(3)
+	pairs := map[string]pair{
+		"app/app_name":  {`"x"`, 2},
+		"app/endpoints": {`{"users": "/users"}`, 3},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wait, _ := strconv.ParseUint(r.URL.Query().Get("index"), 10, 64)
+		lock.Lock()
+		if wait >= index { // blocking query
+			ch := changed
+			lock.Unlock()
+			select {
+			case <-ch:
+			case <-r.Context().Done():
+				return
+			}
+			lock.Lock()
+		}
+		defer lock.Unlock()
+		var kvs []*api.KVPair
+		for key, p := range pairs {
+			kvs = append(kvs, &api.KVPair{Key: key, Value: []byte(p.value), ModifyIndex: p.index})
+		}
+		w.Header().Set("X-Consul-Index", fmt.Sprint(index))
+		json.NewEncoder(w).Encode(kvs)
+	}))
+	defer srv.Close()
+
+	client, err := api.NewClient(&api.Config{Address: srv.URL})
+	assert.Nilf(t, err, "client")
+	src := consul.NewSource(client, "app/", "")
+	sections, err := src.Load(ctx)
+	assert.Nilf(t, err, "load")
+	assert.Equalf(t, map[string]json.RawMessage{
+		"app_name":  json.RawMessage(`"x"`),
+		"endpoints": json.RawMessage(`{"users": "/users"}`),
+	}, sections, "load")
+
+	changes := make(chan map[string]json.RawMessage)
+	go src.Watch(ctx, func(c map[string]json.RawMessage) {
+		changes <- c
+	})
+	lock.Lock()
+	index = 5
+	pairs["app/app_name"] = pair{`"y"`, 5}
+	delete(pairs, "app/endpoints")
+	close(changed)
+	changed = make(chan struct{})
+	lock.Unlock()
+	assert.Equalf(t, map[string]json.RawMessage{
+		"app_name":  json.RawMessage(`"y"`),
+		"endpoints": nil,
+	}, <-changes, "watch")
+}
@@ -0,0 +1,92 @@
+// Package etcd implements the `config.Source` of etcd
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ccmonky/inithook/config"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// Client is the part of `*clientv3.Client` used by `Source`
+type Client interface {
+	clientv3.KV
+	clientv3.Watcher
+}
+
+// Source is a `config.Source` of the keys under a prefix of etcd, each key with the prefix trimmed is a section
+type Source struct {
+	client Client
+	prefix string
+	format config.Format
+
+	lock     sync.Mutex
+	revision int64
+}
+
+// NewSource creates a `Source` of the keys under prefix, whose values are encoded in format, default to `config.JSON`
+func NewSource(client Client, prefix string, format config.Format) *Source {
+	if format == "" {
+		format = config.JSON
+	}
+	return &Source{client: client, prefix: prefix, format: format}
+}
+
+// Load implements `config.Source`
+func (s *Source) Load(ctx context.Context) (map[string]json.RawMessage, error) {
+	resp, err := s.client.Get(ctx, s.prefix, clientv3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+	sections := make(map[string]json.RawMessage, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		key := strings.TrimPrefix(string(kv.Key), s.prefix)
+		sections[key], err = config.Section(kv.Value, s.format)
+		if err != nil {
+			return nil, fmt.Errorf("etcd: key %s: %w", kv.Key, err)
+		}
+	}
+	s.lock.Lock()
+	s.revision = resp.Header.GetRevision()
+	s.lock.Unlock()
+	return sections, nil
+}
+
+// Watch implements `config.Source`, watches the changes since the revision of the last `Load`
+func (s *Source) Watch(ctx context.Context, fn func(changes map[string]json.RawMessage)) error {
+	opts := []clientv3.OpOption{clientv3.WithPrefix()}
+	s.lock.Lock()
+	if s.revision > 0 {
+		opts = append(opts, clientv3.WithRev(s.revision+1))
+	}
+	s.lock.Unlock()
+	for resp := range s.client.Watch(clientv3.WithRequireLeader(ctx), s.prefix, opts...) {
+		if err := resp.Err(); err != nil {
+			return err
+		}
+		changes := make(map[string]json.RawMessage, len(resp.Events))
+		for _, ev := range resp.Events {
+			key := strings.TrimPrefix(string(ev.Kv.Key), s.prefix)
+			if ev.Type == clientv3.EventTypeDelete {
+				changes[key] = nil
+				continue
+			}
+			section, err := config.Section(ev.Kv.Value, s.format)
+			if err != nil {
+				return fmt.Errorf("etcd: key %s: %w", ev.Kv.Key, err)
+			}
+			changes[key] = section
+		}
+		s.lock.Lock()
+		s.revision = resp.Header.GetRevision()
+		s.lock.Unlock()
+		if len(changes) > 0 {
+			fn(changes)
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,80 @@
+package etcd_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ccmonky/inithook/config"
+	"github.com/ccmonky/inithook/config/etcd"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+type fakeClient struct {
+	clientv3.KV
+	clientv3.Watcher
+	kvs    []*mvccpb.KeyValue
+	events chan clientv3.WatchResponse
+	rev    int64
+}
+
+func (c *fakeClient) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return &clientv3.GetResponse{Header: &etcdserverpb.ResponseHeader{Revision: 7}, Kvs: c.kvs}, nil
+}
+
+func (c *fakeClient) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	op := clientv3.OpGet(key, opts...)
+	c.rev = op.Rev()
+	ch := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case resp := <-c.events:
+				ch <- resp
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func TestSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &fakeClient{
+		kvs: []*mvccpb.KeyValue{
+			{Key: []byte("/app/app_name"), Value: []byte("name: x")},
+			{Key: []byte("/app/endpoints"), Value: []byte("users: /users")},
+		},
+		events: make(chan clientv3.WatchResponse),
+	}
+	src := etcd.NewSource(client, "/app/", config.YAML)
+	sections, err := src.Load(ctx)
+	assert.Nilf(t, err, "load")
+	assert.Equalf(t, map[string]json.RawMessage{
+		"app_name":  json.RawMessage(`{"name":"x"}`),
+		"endpoints": json.RawMessage(`{"users":"/users"}`),
+	}, sections, "load")
+
+	changes := make(chan map[string]json.RawMessage)
+	go src.Watch(ctx, func(c map[string]json.RawMessage) {
+		changes <- c
+	})
+	client.events <- clientv3.WatchResponse{
+		Header: etcdserverpb.ResponseHeader{Revision: 8},
+		Events: []*clientv3.Event{
+			{Type: mvccpb.PUT, Kv: &mvccpb.KeyValue{Key: []byte("/app/app_name"), Value: []byte("name: y")}},
+			{Type: mvccpb.DELETE, Kv: &mvccpb.KeyValue{Key: []byte("/app/endpoints")}},
+		},
+	}
+	assert.Equalf(t, map[string]json.RawMessage{
+		"app_name":  json.RawMessage(`{"name":"y"}`),
+		"endpoints": nil,
+	}, <-changes, "watch")
+	assert.Equalf(t, int64(8), client.rev, "watch since the loaded revision")
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sync"
+	"time"
+)
+
+// HTTPSource is a `Source` of a config document served over HTTP, which is polled to watch the changes
+type HTTPSource struct {
+	// URL of the config document
+	URL string
+
+	// Format of the document, default to the one detected by the extension of the url path
+	Format Format
+
+	// Interval of polling, default to 30s
+	Interval time.Duration
+
+	// Client used to request, default to `http.DefaultClient`
+	Client *http.Client
+
+	lock     sync.Mutex
+	etag     string
+	sections map[string]json.RawMessage
+}
+
+// Load implements `Source`
+func (s *HTTPSource) Load(ctx context.Context) (map[string]json.RawMessage, error) {
+	sections, _, err := s.fetch(ctx)
+	return sections, err
+}
+
+// Watch implements `Source`
+func (s *HTTPSource) Watch(ctx context.Context, fn func(changes map[string]json.RawMessage)) error {
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		s.lock.Lock()
+		old := s.sections
+		s.lock.Unlock()
+		sections, modified, err := s.fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if !modified {
+			continue
+		}
+		if changes := diff(old, sections); len(changes) > 0 {
+			fn(changes)
+		}
+	}
+}
+
+// fetch requests the document, returns false if not modified since the last fetch
+func (s *HTTPSource) fetch(ctx context.Context) (map[string]json.RawMessage, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	s.lock.Lock()
+	if s.etag != "" {
+		req.Header.Set("If-None-Match", s.etag)
+	}
+	s.lock.Unlock()
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		return s.sections, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("config: get %s failed: %s", s.URL, resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	format := s.Format
+	if format == "" {
+		format, err = detect(path.Base(req.URL.Path))
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	sections, err := Sections(data, format)
+	if err != nil {
+		return nil, false, fmt.Errorf("config: get %s failed: %w", s.URL, err)
+	}
+	s.lock.Lock()
+	s.etag = resp.Header.Get("ETag")
+	s.sections = sections
+	s.lock.Unlock()
+	return sections, true, nil
+}
+
+// diff returns the sections changed from old to new, the deleted ones are nil
+func diff(old, new map[string]json.RawMessage) map[string]json.RawMessage {
+	changes := make(map[string]json.RawMessage)
+	for key, value := range new {
+		if !bytes.Equal(old[key], value) {
+			changes[key] = value
+		}
+	}
+	for key := range old {
+		if _, ok := new[key]; !ok {
+			changes[key] = nil
+		}
+	}
+	return changes
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/ccmonky/inithook"
+)
+
+// Reloader keeps the attrs and registries updated with a `Source` like `Sync`, but every reload is staged:
+// the changed sections are decoded and the affected registries are rebuilt in staging Maps first(see `inithook.Map.StageJSON`),
+// if anything fails then nothing changes, otherwise the registries are swapped atomically one by one,
+// the attr setters registered `inithook.WithDynamic` are executed and then the reload hooks(see `inithook.Hooks.OnReload`) fired.
+// The instances loaded from a section which are absent in the reloaded section are deleted from the registry,
+// the instances registered by code are untouched
+type Reloader struct {
+	src   Source
+	hooks *inithook.Hooks
+	o     *options
+
+	lock     sync.Mutex                 // serializes reloads
+	sections map[string]json.RawMessage // sections of registries applied
+}
+
+// NewReloader creates a `Reloader` of src which fires the reload hooks of hooks, default to `inithook.DefaultHooks`
+func NewReloader(src Source, hooks *inithook.Hooks, opts ...Option) *Reloader {
+	if hooks == nil {
+		hooks = inithook.DefaultHooks
+	}
+	return &Reloader{src: src, hooks: hooks, o: newOptions(opts)}
+}
+
+// Run loads and applies all sections of the source, then blocks reloading the changed sections until ctx is done,
+// the errors of reloads are reported to the handler of `WithErrorHandler` and the watch goes on
+func (r *Reloader) Run(ctx context.Context) error {
+	raw, err := r.src.Load(ctx)
+	if err != nil {
+		return fmt.Errorf("config: load source failed: %w", err)
+	}
+	if err := r.reload(ctx, raw, false); err != nil {
+		return err
+	}
+	err = r.src.Watch(ctx, func(changes map[string]json.RawMessage) {
+		if err := r.Reload(ctx, changes); err != nil {
+			r.o.handle(err)
+		}
+	})
+	if err != nil && ctx.Err() == nil {
+		return fmt.Errorf("config: watch source failed: %w", err)
+	}
+	return nil
+}
+
+// Reload reloads the changed sections, a nil section means deleted, which deletes the instances loaded from it
+// and leaves the attr untouched, the reload hooks can retrieve the reloaded sections by `ReloadedSections`
+func (r *Reloader) Reload(ctx context.Context, changes map[string]json.RawMessage) error {
+	return r.reload(ctx, changes, true)
+}
+
+func (r *Reloader) reload(ctx context.Context, changes map[string]json.RawMessage, fire bool) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	raw := make(map[string]json.RawMessage, len(changes))
+	for key, value := range changes {
+		if value != nil {
+			raw[key] = value
+		} else if _, ok := r.sections[key]; ok {
+			raw[key] = json.RawMessage(`{}`)
+		}
+	}
+	if len(raw) == 0 {
+		return nil
+	}
+	targets, err := resolve(raw, r.o)
+	if err != nil {
+		return fmt.Errorf("config: reload failed: %w", err)
+	}
+	swaps := make(map[string]func(ctx context.Context) error, len(targets))
+	for _, t := range targets {
+		if t.attr {
+			continue
+		}
+		swap, err := t.registry.StageJSON(ctx, r.sections[t.key], t.data)
+		if err != nil {
+			return fmt.Errorf("config: reload registry %s failed: %w", t.key, err)
+		}
+		swaps[t.key] = swap
+	}
+	if r.sections == nil {
+		r.sections = make(map[string]json.RawMessage)
+	}
+	for _, t := range targets {
+		if t.attr {
+			continue
+		}
+		if err := swaps[t.key](ctx); err != nil {
+			return fmt.Errorf("config: reload registry %s failed: %w", t.key, err)
+		}
+		r.sections[t.key] = t.data
+	}
+	sections := make([]string, 0, len(targets)) // sorted as targets
+	for _, t := range targets {
+		sections = append(sections, t.key)
+		if !t.attr {
+			continue
+		}
+		if err := inithook.ExecuteAttrSetters(ctx, t.key, t.value); err != nil {
+			return fmt.Errorf("config: reload failed: %w", err)
+		}
+	}
+	if !fire {
+		return nil
+	}
+	if err := r.hooks.Reload(context.WithValue(ctx, reloadedKey{}, sections)); err != nil {
+		return fmt.Errorf("config: reload hooks failed: %w", err)
+	}
+	return nil
+}
+
+type reloadedKey struct{}
+
+// ReloadedSections returns the sorted sections reloaded by `Reloader`, used in the reload hooks
+func ReloadedSections(ctx context.Context) []string {
+	sections, _ := ctx.Value(reloadedKey{}).([]string)
+	return sections
+}
@@ -0,0 +1,95 @@
+package config_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ccmonky/inithook"
+	"github.com/ccmonky/inithook/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// chanSource is a `config.Source` whose changes are sent by the test
+type chanSource struct {
+	sections map[string]json.RawMessage
+	changes  chan map[string]json.RawMessage
+}
+
+func (s *chanSource) Load(ctx context.Context) (map[string]json.RawMessage, error) {
+	return s.sections, nil
+}
+
+func (s *chanSource) Watch(ctx context.Context, fn func(changes map[string]json.RawMessage)) error {
+	for {
+		select {
+		case changes := <-s.changes:
+			fn(changes)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func TestReloader(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ports := inithook.MustRegisterMap("test_ports", inithook.WithValidator(func(ctx context.Context, key string, value int) error {
+		if value <= 0 {
+			return errors.New("invalid port")
+		}
+		return nil
+	}))
+	ports.MustRegister(ctx, "builtin", 1)
+	src := &chanSource{
+		sections: map[string]json.RawMessage{
+			"app_name":   json.RawMessage(`"v1"`),
+			"test_ports": json.RawMessage(`{"http": 80, "grpc": 90}`),
+		},
+		changes: make(chan map[string]json.RawMessage),
+	}
+	hooks := inithook.NewHooks()
+	reloaded := make(chan []string, 10)
+	hooks.OnReload("test", func(ctx context.Context) error {
+		reloaded <- config.ReloadedSections(ctx)
+		return nil
+	})
+	var errs []error
+	errCh := make(chan error, 10)
+	r := config.NewReloader(src, hooks, config.WithErrorHandler(func(err error) { errCh <- err }))
+	go r.Run(ctx)
+
+	assert.Eventuallyf(t, func() bool { return ports.Len(ctx) == 3 }, time.Second, time.Millisecond, "loaded")
+	value, _ := inithook.AppNameAttr.Value(ctx)
+	assert.Equalf(t, "v1", value, "loaded")
+
+	src.changes <- map[string]json.RawMessage{
+		"app_name":   json.RawMessage(`"v2"`),
+		"test_ports": json.RawMessage(`{"http": 8080}`),
+	}
+	assert.Equalf(t, []string{"app_name", "test_ports"}, <-reloaded, "reload hooks fired")
+	assert.Equalf(t, map[string]int{"builtin": 1, "http": 8080}, ports.Map(ctx), "stale instance deleted, builtin untouched")
+	value, _ = inithook.AppNameAttr.Value(ctx)
+	assert.Equalf(t, "v2", value, "attr reloaded")
+
+	src.changes <- map[string]json.RawMessage{
+		"app_name":   json.RawMessage(`"v3"`),
+		"test_ports": json.RawMessage(`{"http": 80, "grpc": -1}`),
+	}
+	select {
+	case err := <-errCh:
+		errs = append(errs, err)
+	case <-time.After(time.Second):
+		t.Fatal("reload error not reported")
+	}
+	assert.Containsf(t, errs[0].Error(), "invalid port", "validation failed")
+	assert.Equalf(t, map[string]int{"builtin": 1, "http": 8080}, ports.Map(ctx), "nothing changed")
+	value, _ = inithook.AppNameAttr.Value(ctx)
+	assert.Equalf(t, "v2", value, "nothing changed")
+
+	src.changes <- map[string]json.RawMessage{"test_ports": nil}
+	assert.Equalf(t, []string{"test_ports"}, <-reloaded, "reload hooks fired")
+	assert.Equalf(t, map[string]int{"builtin": 1}, ports.Map(ctx), "section deleted")
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/ccmonky/inithook"
+)
+
+// Source is a config source of sections, e.g. a remote config service, whose values are encoded as json,
+// see `Sections` and `Section` to convert the documents of other formats
+type Source interface {
+	// Load loads all sections
+	Load(ctx context.Context) (map[string]json.RawMessage, error)
+
+	// Watch blocks watching the changes of sections until ctx is done, fn is called with the changed sections,
+	// a nil value means the section is deleted
+	Watch(ctx context.Context, fn func(changes map[string]json.RawMessage)) error
+}
+
+// WithErrorHandler sets the handler of the errors occurred while watching a `Source`, default to `log.Printf`
+func WithErrorHandler(fn func(err error)) Option {
+	return func(o *options) {
+		o.errorHandler = fn
+	}
+}
+
+func (o *options) handle(err error) {
+	if o.errorHandler != nil {
+		o.errorHandler(err)
+		return
+	}
+	log.Printf("%v", err)
+}
+
+// Sync loads the sections of src and applies them like `Decode`, then keeps the attrs and registries updated
+// with the sections changed until ctx is done. The sections are held in the returned Map, which is updated by
+// the watch of src, and whose events(see `inithook.Map.Watch`) drive the updates, so the callers can watch it
+// to react to the changes too. Deleting a section leaves the attr or registry untouched.
+// Only the attr setters registered `inithook.WithDynamic` are executed again on changes.
+func Sync(ctx context.Context, src Source, opts ...Option) (*inithook.Map[string, json.RawMessage], error) {
+	o := newOptions(opts)
+	raw, err := src.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("config: load source failed: %w", err)
+	}
+	if err := apply(ctx, raw, o); err != nil {
+		return nil, fmt.Errorf("config: load source failed: %w", err)
+	}
+	m := inithook.NewMap[string, json.RawMessage]()
+	if err := m.SetMany(ctx, raw); err != nil {
+		return nil, err
+	}
+	m.Watch(ctx, func(ev inithook.Event[string, json.RawMessage]) {
+		if ev.Type != inithook.EventSet || (ev.Loaded && bytes.Equal(ev.OldValue, ev.NewValue)) {
+			return
+		}
+		if err := apply(ctx, map[string]json.RawMessage{ev.Key: ev.NewValue}, o); err != nil {
+			o.handle(fmt.Errorf("config: apply section %s failed: %w", ev.Key, err))
+		}
+	})
+	go func() {
+		err := src.Watch(ctx, func(changes map[string]json.RawMessage) {
+			for key, value := range changes {
+				if value == nil {
+					m.Delete(ctx, key)
+					continue
+				}
+				m.Set(ctx, key, value)
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			o.handle(fmt.Errorf("config: watch source failed: %w", err))
+		}
+	}()
+	return m, nil
+}
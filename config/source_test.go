@@ -0,0 +1,73 @@
+package config_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ccmonky/inithook"
+	"github.com/ccmonky/inithook/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncHTTPSource(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var lock sync.Mutex
+	document := `{"app_name": "v1", "endpoints": {"orders": "/v1/orders"}}`
+	var requests, notModified int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests++
+		etag := `"` + document + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(document))
+	}))
+	defer srv.Close()
+
+	src := &config.HTTPSource{URL: srv.URL + "/app.json", Interval: 5 * time.Millisecond}
+	m, err := config.Sync(ctx, src)
+	assert.Nilf(t, err, "sync")
+	assert.Equalf(t, "v1", appName, "loaded")
+	orders, _ := endpoints.Get(ctx, "orders")
+	assert.Equalf(t, "/v1/orders", orders, "loaded")
+	changed := make(chan string, 10)
+	m.Watch(ctx, func(ev inithook.Event[string, json.RawMessage]) {
+		changed <- ev.Type.String() + " " + ev.Key
+	})
+
+	time.Sleep(20 * time.Millisecond)
+	lock.Lock()
+	assert.Truef(t, notModified > 0, "not modified polls")
+	document = `{"app_name": "v2"}`
+	lock.Unlock()
+	var events []string
+	for len(events) < 2 {
+		select {
+		case ev := <-changed:
+			events = append(events, ev)
+		case <-time.After(time.Second):
+			t.Fatalf("changes not watched: %v", events)
+		}
+	}
+	assert.ElementsMatchf(t, []string{"set app_name", "delete endpoints"}, events, "changes")
+	assert.Eventuallyf(t, func() bool {
+		value, _ := inithook.AppNameAttr.Value(ctx)
+		return value == "v2"
+	}, time.Second, time.Millisecond, "updated")
+	orders, _ = endpoints.Get(ctx, "orders")
+	assert.Equalf(t, "/v1/orders", orders, "deleted section leaves registry untouched")
+
+	_, err = config.Sync(ctx, &config.HTTPSource{URL: srv.URL + "/app"})
+	assert.NotNilf(t, err, "unknown format")
+}
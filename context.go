@@ -0,0 +1,26 @@
+package inithook
+
+import "context"
+
+// mapContextKey is the context key of Map[K, V], each instantiation is a distinct key
+type mapContextKey[K comparable, V any] struct{}
+
+// WithMap returns a copy of ctx carrying m, so a request or test scoped Map can shadow the global one,
+// see `MapFromContext`
+func WithMap[K comparable, V any](ctx context.Context, m *Map[K, V]) context.Context {
+	return context.WithValue(ctx, mapContextKey[K, V]{}, m)
+}
+
+// MapFromContext returns the Map[K, V] carried by ctx(see `WithMap`)
+func MapFromContext[K comparable, V any](ctx context.Context) (*Map[K, V], bool) {
+	m, ok := ctx.Value(mapContextKey[K, V]{}).(*Map[K, V])
+	return m, ok && m != nil
+}
+
+// MapFromContextOr returns the Map[K, V] carried by ctx, or def if ctx carries none
+func MapFromContextOr[K comparable, V any](ctx context.Context, def *Map[K, V]) *Map[K, V] {
+	if m, ok := MapFromContext[K, V](ctx); ok {
+		return m
+	}
+	return def
+}
@@ -0,0 +1,227 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// COWMap is a copy-on-write instances map of specified Type for read-heavy registries,
+// which usually written during init and read many times afterwards:
+// reads load an immutable snapshot via `atomic.Pointer` without any locking,
+// writes copy the whole snapshot under a mutex, so writes are O(n).
+type COWMap[K comparable, V any] struct {
+	snapshot atomic.Pointer[map[K]V]
+	lock     sync.Mutex
+}
+
+// NewCOWMap creates a new copy-on-write map
+func NewCOWMap[K comparable, V any]() *COWMap[K, V] {
+	m := &COWMap[K, V]{}
+	instances := make(map[K]V)
+	m.snapshot.Store(&instances)
+	return m
+}
+
+// load returns the current immutable snapshot, must not be modified
+func (m *COWMap[K, V]) load() map[K]V {
+	return *m.snapshot.Load()
+}
+
+// write copies the current snapshot, applies fn to the copy, then publishes it, if fn returns error nothing published
+func (m *COWMap[K, V]) write(fn func(instances map[K]V) error) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	old := m.load()
+	instances := make(map[K]V, len(old)+1)
+	for k, v := range old {
+		instances[k] = v
+	}
+	if err := fn(instances); err != nil {
+		return err
+	}
+	m.snapshot.Store(&instances)
+	return nil
+}
+
+// MustRegister register a V's instance with key, if failed(e.g. already exists) then panic
+func (m *COWMap[K, V]) MustRegister(ctx context.Context, key K, value V) {
+	err := m.Register(ctx, key, value)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Register register a V's instance with key, if exists then return `ErrAlreadyExists` error(use `errors.Is` to assert)
+func (m *COWMap[K, V]) Register(ctx context.Context, key K, value V) error {
+	return m.write(func(instances map[K]V) error {
+		if _, ok := instances[key]; ok {
+			return newRegistryError[V]("Register", key, ErrAlreadyExists, nil)
+		}
+		instances[key] = value
+		return nil
+	})
+}
+
+// MustSet set a V's instance with key, if exists then override, if failed then panic
+func (m *COWMap[K, V]) MustSet(ctx context.Context, key K, value V) {
+	err := m.Set(ctx, key, value)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Set set a V's instance with key, if exists then override
+func (m *COWMap[K, V]) Set(ctx context.Context, key K, value V) error {
+	return m.write(func(instances map[K]V) error {
+		instances[key] = value
+		return nil
+	})
+}
+
+// GetOrSet returns the existing V's instance of key if present, otherwise set value with key and returns it
+func (m *COWMap[K, V]) GetOrSet(ctx context.Context, key K, value V) (V, error) {
+	if v, ok := m.load()[key]; ok {
+		return v, nil
+	}
+	actual := value
+	err := m.write(func(instances map[K]V) error {
+		if v, ok := instances[key]; ok {
+			actual = v
+			return errAbortWrite
+		}
+		instances[key] = value
+		return nil
+	})
+	if err != nil && err != errAbortWrite {
+		return actual, err
+	}
+	return actual, nil
+}
+
+// MustDelete delete a V's instance specified by key, if failed then panic
+func (m *COWMap[K, V]) MustDelete(ctx context.Context, key K) {
+	err := m.Delete(ctx, key)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Delete delete a V's instance specified by key
+func (m *COWMap[K, V]) Delete(ctx context.Context, key K) error {
+	if _, ok := m.load()[key]; !ok {
+		return nil
+	}
+	return m.write(func(instances map[K]V) error {
+		delete(instances, key)
+		return nil
+	})
+}
+
+// MustClear clear all V's instances, if failed then panic
+func (m *COWMap[K, V]) MustClear(ctx context.Context) {
+	err := m.Clear(ctx)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Clear clear all V's instances
+func (m *COWMap[K, V]) Clear(ctx context.Context) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	instances := make(map[K]V)
+	m.snapshot.Store(&instances)
+	return nil
+}
+
+// Get get a V's instance by key, if not found return `NotFound` error(use `errors.Is` to assert)
+func (m *COWMap[K, V]) Get(ctx context.Context, key K) (V, error) {
+	if v, ok := m.load()[key]; ok {
+		return v, nil
+	}
+	value := *new(V)
+	return value, newRegistryError[V]("Get", key, ErrNotFound, nil)
+}
+
+// GetDefault get a V's instance by key, if not found, then try to returns a default one,
+// defaulted tells if the instance is the default one rather than a registered one
+func (m *COWMap[K, V]) GetDefault(ctx context.Context, key K) (value V, defaulted bool, err error) {
+	if v, ok := m.load()[key]; ok {
+		return v, false, nil
+	}
+	value, err = m.Default(ctx, key)
+	return value, true, err
+}
+
+// Default returns V's default value if it implement the `DefaultLoaderK`, `DefaultLoader` or `Default`, otherwise return `Zero[V]()`
+func (m *COWMap[K, V]) Default(ctx context.Context, key K) (V, error) {
+	return defaultValue[K, V](ctx, key)
+}
+
+// Has tells if map has key
+func (m *COWMap[K, V]) Has(ctx context.Context, key K) bool {
+	_, ok := m.load()[key]
+	return ok
+}
+
+// Len returns the number of V's instances
+func (m *COWMap[K, V]) Len(ctx context.Context) int {
+	return len(m.load())
+}
+
+// IsEmpty tells if map has no V's instance
+func (m *COWMap[K, V]) IsEmpty(ctx context.Context) bool {
+	return len(m.load()) == 0
+}
+
+// Range calls f sequentially for each key and value present in the snapshot. If f returns false or ctx is done, range stops the iteration.
+// NOTE: it's safe to write the map in f, since f iterates an immutable snapshot.
+func (m *COWMap[K, V]) Range(ctx context.Context, fn func(key, value any) bool) {
+	for k, v := range m.load() {
+		if ctx.Err() != nil || !fn(k, v) {
+			return
+		}
+	}
+}
+
+// RangeTyped calls f sequentially for each key and value present in the snapshot with typed arguments. If f returns false or ctx is done, range stops the iteration.
+func (m *COWMap[K, V]) RangeTyped(ctx context.Context, fn func(key K, value V) bool) {
+	for k, v := range m.load() {
+		if ctx.Err() != nil || !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Keys return all keys
+func (m *COWMap[K, V]) Keys(ctx context.Context) []K {
+	var keys []K
+	for k := range m.load() {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Values return all values
+func (m *COWMap[K, V]) Values(ctx context.Context) []V {
+	var values []V
+	for _, v := range m.load() {
+		values = append(values, v)
+	}
+	return values
+}
+
+// Map return map with all items
+func (m *COWMap[K, V]) Map(ctx context.Context) map[K]V {
+	snapshot := m.load()
+	kvs := make(map[K]V, len(snapshot))
+	for k, v := range snapshot {
+		kvs[k] = v
+	}
+	return kvs
+}
+
+// errAbortWrite aborts a COWMap write without reporting an error
+var errAbortWrite = errors.New("abort write")
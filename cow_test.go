@@ -0,0 +1,51 @@
+package inithook_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCOWMap(t *testing.T) {
+	m := inithook.NewCOWMap[string, int]()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			m.MustRegister(ctx, strconv.Itoa(i), i)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			m.Has(ctx, strconv.Itoa(i))
+		}(i)
+	}
+	wg.Wait()
+	assert.Lenf(t, m.Keys(ctx), 50, "keys")
+	assert.Equalf(t, 50, m.Len(ctx), "len")
+	err := m.Register(ctx, "1", 1)
+	assert.ErrorIsf(t, err, inithook.ErrAlreadyExists, "register twice")
+	v, err := m.GetOrSet(ctx, "1", 100)
+	assert.Nilf(t, err, "get or set existing")
+	assert.Equalf(t, 1, v, "existing")
+	v, err = m.GetOrSet(ctx, "100", 100)
+	assert.Nilf(t, err, "get or set new")
+	assert.Equalf(t, 100, v, "new")
+
+	m.Range(ctx, func(key, value any) bool {
+		m.MustDelete(ctx, key.(string))
+		return true
+	})
+	assert.Emptyf(t, m.Keys(ctx), "deleted in range")
+	_, err = m.Get(ctx, "1")
+	assert.ErrorIsf(t, err, inithook.ErrNotFound, "not found")
+}
+
+func BenchmarkCOWMapReadHeavy(b *testing.B) {
+	benchmarkReadWrite(b, inithook.NewCOWMap[string, int](), 0)
+}
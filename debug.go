@@ -0,0 +1,155 @@
+package inithook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Debug is the introspection of all named registries(see `RegisterMap`) and hooks, rendered by `DebugHandler`
+type Debug struct {
+	Registries []RegistryDebug `json:"registries"`
+	Hooks      []HookDebug     `json:"hooks"`
+}
+
+// RegistryDebug describes a named registry
+type RegistryDebug struct {
+	Name      string     `json:"name"`
+	KeyType   string     `json:"key_type"`
+	ValueType string     `json:"value_type"`
+	Keys      []KeyDebug `json:"keys"`
+}
+
+// KeyDebug describes a key of a registry
+type KeyDebug struct {
+	Key      string   `json:"key"`
+	Metadata Metadata `json:"metadata"`
+	Info     *Info    `json:"info,omitempty"`
+}
+
+// HookDebug describes a hook in execution order of its phase, with the result of the last run if any(see `Hooks.Report`)
+type HookDebug struct {
+	Phase    string        `json:"phase"`
+	Name     string        `json:"name"`
+	Priority int           `json:"priority"`
+	Requires []string      `json:"requires,omitempty"`
+	Status   string        `json:"status"`
+	Start    time.Time     `json:"start"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// debugger is implemented by `*Map` to describe itself
+type debugger interface {
+	debugKeys(ctx context.Context) []KeyDebug
+}
+
+// debugKeys describes the keys sorted
+func (m *Map[K, V]) debugKeys(ctx context.Context) []KeyDebug {
+	keys := m.Keys(ctx)
+	debugs := make([]KeyDebug, 0, len(keys))
+	for _, key := range keys {
+		d := KeyDebug{Key: fmt.Sprint(key)}
+		d.Metadata, _ = m.Describe(ctx, key)
+		if info, ok := m.Info(ctx, key); ok {
+			d.Info = &info
+		}
+		debugs = append(debugs, d)
+	}
+	sort.Slice(debugs, func(i, j int) bool {
+		return debugs[i].Key < debugs[j].Key
+	})
+	return debugs
+}
+
+// Debug returns the introspection of all named registries and the hooks of h
+func (h *Hooks) Debug(ctx context.Context) Debug {
+	var d Debug
+	for _, info := range Registries() {
+		rd := RegistryDebug{Name: info.Name, KeyType: info.KeyType.String(), ValueType: info.ValueType.String()}
+		if m, err := Registry(info); err == nil {
+			if dm, ok := m.(debugger); ok {
+				rd.Keys = dm.debugKeys(ctx)
+			}
+		}
+		d.Registries = append(d.Registries, rd)
+	}
+	results := make(map[string]HookResult)
+	for _, result := range h.Report().Results {
+		results[result.Phase.String()+"/"+result.Name] = result
+	}
+	for _, phase := range []Phase{PhaseInit, PhaseStart, PhaseShutdown, PhaseReload} {
+		hooks, err := h.order(phase)
+		if err != nil { // e.g. cycle, fallback to registration order
+			h.lock.Lock()
+			hooks = append([]*hook(nil), h.hooks[phase]...)
+			h.lock.Unlock()
+		}
+		for _, hk := range hooks {
+			hd := HookDebug{Phase: phase.String(), Name: hk.name, Priority: hk.priority, Requires: hk.requires, Status: HookNotRun.String()}
+			if result, ok := results[hd.Phase+"/"+hd.Name]; ok {
+				hd.Status = result.Status.String()
+				hd.Start = result.Start
+				hd.Duration = result.Duration
+				if result.Err != nil {
+					hd.Error = result.Err.Error()
+				}
+			}
+			d.Hooks = append(d.Hooks, hd)
+		}
+	}
+	return d
+}
+
+// DebugHandler returns the handler rendering `Debug` of `DefaultHooks`, e.g. mounted on /debug/inithook,
+// see `Hooks.DebugHandler`
+func DebugHandler() http.Handler {
+	return DefaultHooks.DebugHandler()
+}
+
+// DebugHandler returns the handler rendering `Debug` of h as HTML,
+// or as JSON if requested with `?format=json` or `Accept: application/json`
+func (h *Hooks) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d := h.Debug(r.Context())
+		if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+			w.Header().Set("Content-Type", "application/json")
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			enc.Encode(d)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := debugTemplate.Execute(w, d); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+var debugTemplate = template.Must(template.New("debug").Parse(`<!DOCTYPE html>
+<html>
+<head><title>inithook</title>
+<style>table{border-collapse:collapse;margin-bottom:1em}th,td{border:1px solid #ccc;padding:2px 8px;text-align:left}</style>
+</head>
+<body>
+<h1>Registries</h1>
+{{range .Registries}}
+<h2>{{.Name}} <small>Map[{{.KeyType}}, {{.ValueType}}]</small></h2>
+<table>
+<tr><th>Key</th><th>Description</th><th>Tags</th><th>Registered</th></tr>
+{{range .Keys}}<tr><td>{{.Key}}</td><td>{{.Metadata.Description}}</td><td>{{range .Metadata.Tags}}{{.}} {{end}}</td><td>{{with .Info}}{{.Caller}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+<h1>Hooks</h1>
+<table>
+<tr><th>Phase</th><th>Name</th><th>Priority</th><th>Requires</th><th>Status</th><th>Duration</th><th>Error</th></tr>
+{{range .Hooks}}<tr><td>{{.Phase}}</td><td>{{.Name}}</td><td>{{.Priority}}</td><td>{{range .Requires}}{{.}} {{end}}</td><td>{{.Status}}</td><td>{{.Duration}}</td><td>{{.Error}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
@@ -0,0 +1,57 @@
+package inithook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebugHandler(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.MustRegisterMap("test_debug", inithook.WithRegistrationInfo[string, int]())
+	m.MustRegister(ctx, "b", 2, inithook.WithDescription("<b>"), inithook.WithTags("x"))
+	m.MustRegister(ctx, "a", 1)
+	h := inithook.NewHooks()
+	h.OnInit("db", func(ctx context.Context) error { return nil })
+	h.OnInit("cache", func(ctx context.Context) error { return errors.New("boom") }, inithook.WithRequires("db"))
+	h.OnStart("http", func(ctx context.Context) error { return nil })
+	h.Run(ctx)
+
+	rec := httptest.NewRecorder()
+	h.DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/inithook?format=json", nil))
+	assert.Equalf(t, "application/json", rec.Header().Get("Content-Type"), "json")
+	var d inithook.Debug
+	assert.Nilf(t, json.Unmarshal(rec.Body.Bytes(), &d), "json")
+	var registry *inithook.RegistryDebug
+	for i := range d.Registries {
+		if d.Registries[i].Name == "test_debug" {
+			registry = &d.Registries[i]
+		}
+	}
+	if assert.NotNilf(t, registry, "registry") {
+		assert.Equalf(t, "int", registry.ValueType, "value type")
+		assert.Lenf(t, registry.Keys, 2, "keys")
+		assert.Equalf(t, "a", registry.Keys[0].Key, "sorted keys")
+		assert.Equalf(t, "<b>", registry.Keys[1].Metadata.Description, "metadata")
+		assert.NotNilf(t, registry.Keys[1].Info, "registration info")
+	}
+	if assert.Lenf(t, d.Hooks, 3, "hooks") {
+		assert.Equalf(t, "db", d.Hooks[0].Name, "execution order")
+		assert.Equalf(t, "succeeded", d.Hooks[0].Status, "status")
+		assert.Equalf(t, "cache", d.Hooks[1].Name, "execution order")
+		assert.Equalf(t, "failed", d.Hooks[1].Status, "status")
+		assert.Containsf(t, d.Hooks[1].Error, "boom", "error")
+		assert.Equalf(t, "not run", d.Hooks[2].Status, "status")
+	}
+
+	rec = httptest.NewRecorder()
+	h.DebugHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/debug/inithook", nil))
+	assert.Containsf(t, rec.Header().Get("Content-Type"), "text/html", "html")
+	assert.Containsf(t, rec.Body.String(), "test_debug", "html")
+	assert.Containsf(t, rec.Body.String(), "&lt;b&gt;", "html escaped")
+}
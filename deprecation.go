@@ -0,0 +1,69 @@
+package inithook
+
+import (
+	"context"
+	"log"
+	"log/slog"
+)
+
+// Deprecate marks key(or alias) as deprecated, Get/GetDefault of key still returns the instance,
+// but reports message to the deprecation handler(see `WithDeprecationHandler`, default to log),
+// if key is neither an existing key nor an alias then return `ErrNotFound` error(use `errors.Is` to assert)
+func (m *Map[K, V]) Deprecate(ctx context.Context, key K, message string) error {
+	if m.normalizer != nil {
+		key = m.normalizer(key)
+	}
+	m.aliasesLock.RLock()
+	_, isAlias := m.aliases[key]
+	m.aliasesLock.RUnlock()
+	m.lock.RLock()
+	exists := m.exists(key)
+	m.lock.RUnlock()
+	if !isAlias && !exists {
+		return m.newError("Deprecate", key, ErrNotFound, nil)
+	}
+	m.deprecatedLock.Lock()
+	defer m.deprecatedLock.Unlock()
+	if m.deprecated == nil {
+		m.deprecated = make(map[K]string)
+	}
+	m.deprecated[key] = message
+	m.hasDeprecated.Store(true)
+	return nil
+}
+
+// Deprecated returns all deprecated keys and aliases with their deprecation messages
+func (m *Map[K, V]) Deprecated(ctx context.Context) map[K]string {
+	m.deprecatedLock.RLock()
+	defer m.deprecatedLock.RUnlock()
+	deprecated := make(map[K]string, len(m.deprecated))
+	for k, msg := range m.deprecated {
+		deprecated[k] = msg
+	}
+	return deprecated
+}
+
+// warnDeprecated reports key to the deprecation handler if it's deprecated, key should not be resolved yet
+func (m *Map[K, V]) warnDeprecated(ctx context.Context, key K) {
+	if !m.hasDeprecated.Load() {
+		return
+	}
+	if m.normalizer != nil {
+		key = m.normalizer(key)
+	}
+	m.deprecatedLock.RLock()
+	message, ok := m.deprecated[key]
+	m.deprecatedLock.RUnlock()
+	if !ok {
+		return
+	}
+	if m.deprecationHandler != nil {
+		m.deprecationHandler(ctx, key, message)
+		return
+	}
+	if m.logger != nil {
+		m.log(ctx, slog.LevelWarn, "inithook: deprecated", key, slog.String("message", message))
+		return
+	}
+	log.Printf("inithook: type %s instance %v is deprecated: %s", typeName[V](), key, message)
+}
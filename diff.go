@@ -0,0 +1,72 @@
+package inithook
+
+import (
+	"context"
+	"reflect"
+)
+
+// MapDiff is the difference from a map(or snapshot) to another one, see `Diff`
+type MapDiff[K comparable, V any] struct {
+	Added   map[K]V         // keys only in the new one
+	Removed map[K]V         // keys only in the old one, with the old values
+	Changed map[K]Change[V] // keys in both with different values
+}
+
+// Change is the old and new values of a changed key
+type Change[V any] struct {
+	Old V
+	New V
+}
+
+// IsEmpty tells if nothing changed
+func (d MapDiff[K, V]) IsEmpty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Events returns the events which turn the old one into the new one, i.e. the unloaded `EventSet` of added keys,
+// the `EventDelete` of removed keys and the loaded `EventSet` of changed keys, e.g. to fire targeted notifications
+func (d MapDiff[K, V]) Events() []Event[K, V] {
+	events := make([]Event[K, V], 0, len(d.Added)+len(d.Removed)+len(d.Changed))
+	for k, v := range d.Added {
+		events = append(events, Event[K, V]{Type: EventSet, Key: k, NewValue: v})
+	}
+	for k, v := range d.Removed {
+		events = append(events, Event[K, V]{Type: EventDelete, Key: k, OldValue: v, Loaded: true})
+	}
+	for k, c := range d.Changed {
+		events = append(events, Event[K, V]{Type: EventSet, Key: k, OldValue: c.Old, NewValue: c.New, Loaded: true})
+	}
+	return events
+}
+
+// Diff reports the keys added, removed and changed from a to b, values are compared by eq, if eq is nil then `reflect.DeepEqual` is used
+func Diff[K comparable, V any](ctx context.Context, a, b *Map[K, V], eq func(x, y V) bool) MapDiff[K, V] {
+	return DiffSnapshots(a.Map(ctx), b.Map(ctx), eq)
+}
+
+// DiffSnapshots reports the keys added, removed and changed from snapshot a to b(see `Map.Snapshot`), see `Diff`
+func DiffSnapshots[K comparable, V any](a, b map[K]V, eq func(x, y V) bool) MapDiff[K, V] {
+	if eq == nil {
+		eq = func(x, y V) bool { return reflect.DeepEqual(x, y) }
+	}
+	d := MapDiff[K, V]{
+		Added:   make(map[K]V),
+		Removed: make(map[K]V),
+		Changed: make(map[K]Change[V]),
+	}
+	for k, old := range a {
+		new, ok := b[k]
+		switch {
+		case !ok:
+			d.Removed[k] = old
+		case !eq(old, new):
+			d.Changed[k] = Change[V]{Old: old, New: new}
+		}
+	}
+	for k, v := range b {
+		if _, ok := a[k]; !ok {
+			d.Added[k] = v
+		}
+	}
+	return d
+}
@@ -0,0 +1,61 @@
+// Package encoding serializes and populates inithook Maps from YAML and TOML documents
+package encoding
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/BurntSushi/toml"
+	"github.com/ccmonky/inithook"
+	"gopkg.in/yaml.v3"
+)
+
+// DecodeHook used to transform or check every decoded value before it's set into the Map,
+// e.g. fill defaults, an error aborts the decoding and nothing is set
+type DecodeHook[K ~string, V any] func(ctx context.Context, key K, value V) (V, error)
+
+// MarshalYAML serializes the instances of m as a YAML mapping
+func MarshalYAML[K ~string, V any](ctx context.Context, m *inithook.Map[K, V]) ([]byte, error) {
+	return yaml.Marshal(m.Map(ctx))
+}
+
+// UnmarshalYAML decodes a YAML mapping and sets all instances into m(see `Map.SetMany`)
+func UnmarshalYAML[K ~string, V any](ctx context.Context, data []byte, m *inithook.Map[K, V], hooks ...DecodeHook[K, V]) error {
+	var values map[K]V
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	return set(ctx, m, values, hooks)
+}
+
+// MarshalTOML serializes the instances of m as a TOML document, each instance is a top-level key
+func MarshalTOML[K ~string, V any](ctx context.Context, m *inithook.Map[K, V]) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(m.Map(ctx)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalTOML decodes a TOML document and sets all instances into m(see `Map.SetMany`)
+func UnmarshalTOML[K ~string, V any](ctx context.Context, data []byte, m *inithook.Map[K, V], hooks ...DecodeHook[K, V]) error {
+	var values map[K]V
+	if _, err := toml.Decode(string(data), &values); err != nil {
+		return err
+	}
+	return set(ctx, m, values, hooks)
+}
+
+func set[K ~string, V any](ctx context.Context, m *inithook.Map[K, V], values map[K]V, hooks []DecodeHook[K, V]) error {
+	for key, value := range values {
+		for _, hook := range hooks {
+			var err error
+			value, err = hook(ctx, key, value)
+			if err != nil {
+				return err
+			}
+		}
+		values[key] = value
+	}
+	return m.SetMany(ctx, values)
+}
@@ -0,0 +1,68 @@
+package encoding_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/ccmonky/inithook/encoding"
+	"github.com/stretchr/testify/assert"
+)
+
+type server struct {
+	Addr    string `yaml:"addr" toml:"addr"`
+	Timeout int    `yaml:"timeout" toml:"timeout"`
+}
+
+func defaultTimeout(ctx context.Context, key string, value server) (server, error) {
+	if value.Timeout == 0 {
+		value.Timeout = 30
+	}
+	return value, nil
+}
+
+func TestYAML(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, server]()
+	data := []byte("api:\n  addr: \":8080\"\nadmin:\n  addr: \":9090\"\n  timeout: 5\n")
+	assert.Nilf(t, encoding.UnmarshalYAML(ctx, data, m, defaultTimeout), "unmarshal")
+	assert.Equalf(t, map[string]server{
+		"api":   {Addr: ":8080", Timeout: 30},
+		"admin": {Addr: ":9090", Timeout: 5},
+	}, m.Map(ctx), "unmarshal")
+
+	out, err := encoding.MarshalYAML(ctx, m)
+	assert.Nilf(t, err, "marshal")
+	loaded := inithook.NewMap[string, server]()
+	assert.Nilf(t, encoding.UnmarshalYAML(ctx, out, loaded), "round trip")
+	assert.Equalf(t, m.Map(ctx), loaded.Map(ctx), "round trip")
+}
+
+func TestTOML(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, server]()
+	data := []byte("[api]\naddr = \":8080\"\n\n[admin]\naddr = \":9090\"\ntimeout = 5\n")
+	assert.Nilf(t, encoding.UnmarshalTOML(ctx, data, m, defaultTimeout), "unmarshal")
+	assert.Equalf(t, map[string]server{
+		"api":   {Addr: ":8080", Timeout: 30},
+		"admin": {Addr: ":9090", Timeout: 5},
+	}, m.Map(ctx), "unmarshal")
+
+	out, err := encoding.MarshalTOML(ctx, m)
+	assert.Nilf(t, err, "marshal")
+	loaded := inithook.NewMap[string, server]()
+	assert.Nilf(t, encoding.UnmarshalTOML(ctx, out, loaded), "round trip")
+	assert.Equalf(t, m.Map(ctx), loaded.Map(ctx), "round trip")
+}
+
+func TestDecodeHookError(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, server]()
+	errBad := errors.New("bad")
+	err := encoding.UnmarshalYAML(ctx, []byte("api:\n  addr: x\n"), m, func(ctx context.Context, key string, value server) (server, error) {
+		return value, errBad
+	})
+	assert.Truef(t, errors.Is(err, errBad), "hook error")
+	assert.Truef(t, m.IsEmpty(ctx), "nothing set")
+}
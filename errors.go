@@ -0,0 +1,127 @@
+package inithook
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// captureStacks tells if the stacks of errors are captured, see `CaptureStacks`
+var captureStacks atomic.Bool
+
+// CaptureStacks enables or disables capturing the stack of every `*RegistryError` when created, disabled by default
+// since it's costly, the stack is printed by the `%+v` verb and retrieved by `RegistryError.StackTrace`
+func CaptureStacks(enabled bool) {
+	captureStacks.Store(enabled)
+}
+
+// RegistryError is the error of an operation on the instances of a Map, which wraps a sentinel(e.g. `ErrNotFound`),
+// the fields can be extracted by `errors.As` instead of parsing the message, and `errors.Is` works for both Err and Cause
+type RegistryError struct {
+	Op       string // the method failed, e.g. Register, Get
+	Key      any    // the key, nil if the operation is not on a key, e.g. Clear
+	TypeName string // the type name of V
+	Caller   string // the caller location of the operation, recorded if `WithRegistrationInfo` is used
+	Origin   string // the location of the original registration conflicted with, only for `ErrAlreadyExists`
+	Err      error  // the sentinel, e.g. `ErrNotFound`, `ErrInvalidValue`
+	Cause    error  // the underlying error if any, e.g. the one returned by a validator
+
+	stack []uintptr
+}
+
+// Error implements error
+func (e *RegistryError) Error() string {
+	var b strings.Builder
+	b.WriteString("type ")
+	b.WriteString(e.TypeName)
+	if e.Key != nil {
+		fmt.Fprintf(&b, " instance %v", e.Key)
+	}
+	if e.Origin != "" {
+		fmt.Fprintf(&b, " registered at %s, conflict with %s", e.Origin, e.Caller)
+	}
+	if e.Cause != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Cause.Error())
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+// Unwrap returns Err and Cause
+func (e *RegistryError) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Err}
+	}
+	return []error{e.Err, e.Cause}
+}
+
+// StackTrace returns the stack where the error created, nil unless `CaptureStacks` enabled
+func (e *RegistryError) StackTrace() []Caller {
+	if len(e.stack) == 0 {
+		return nil
+	}
+	callers := make([]Caller, 0, len(e.stack))
+	frames := runtime.CallersFrames(e.stack)
+	for {
+		frame, more := frames.Next()
+		callers = append(callers, Caller{Package: funcPackage(frame.Function), Function: frame.Function, File: frame.File, Line: frame.Line})
+		if !more {
+			return callers
+		}
+	}
+}
+
+// Format implements `fmt.Formatter`, the `%+v` verb prints the stack trace if captured
+func (e *RegistryError) Format(s fmt.State, verb rune) {
+	switch verb {
+	case 'v':
+		io.WriteString(s, e.Error())
+		if s.Flag('+') {
+			for _, caller := range e.StackTrace() {
+				fmt.Fprintf(s, "\n%s\n\t%s:%d", caller.Function, caller.File, caller.Line)
+			}
+		}
+	case 's':
+		io.WriteString(s, e.Error())
+	case 'q':
+		fmt.Fprintf(s, "%q", e.Error())
+	}
+}
+
+// Must returns v if err is nil, otherwise panic with err, e.g. `srv := inithook.Must(servers.Get(ctx, "api"))`,
+// usually used in init where panic-on-error is idiomatic
+func Must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+// newRegistryError creates a `*RegistryError` of V's instance of key, captures the stack if `CaptureStacks` enabled
+func newRegistryError[V any](op string, key any, err, cause error) *RegistryError {
+	e := &RegistryError{Op: op, Key: key, TypeName: typeName[V](), Err: err, Cause: cause}
+	if captureStacks.Load() {
+		pcs := make([]uintptr, 32)
+		e.stack = pcs[:runtime.Callers(2, pcs)]
+	}
+	return e
+}
+
+// typeName returns the name of V, the interface name rather than `<nil>` if V is an interface
+func typeName[V any]() string {
+	return reflect.TypeOf((*V)(nil)).Elem().String()
+}
+
+// newError creates a `*RegistryError` of key, and records the caller if `WithRegistrationInfo` is used
+func (m *Map[K, V]) newError(op string, key any, err, cause error) *RegistryError {
+	e := newRegistryError[V](op, key, err, cause)
+	if m.recordInfo {
+		e.Caller = callerOutside().String()
+	}
+	return e
+}
@@ -0,0 +1,108 @@
+package inithook
+
+import (
+	"context"
+	"sync"
+)
+
+// EventType defines the type of map mutation
+type EventType int
+
+// map mutation event types
+const (
+	EventRegister EventType = iota + 1
+	EventSet
+	EventDelete
+	EventClear
+	// EventReclaim reports an instance memoized by a provider is dropped(see `WithWeigher` and `Map.Reclaim`),
+	// while the key still exists and the instance is constructed again on the next Get
+	EventReclaim
+)
+
+// String returns the name of event type
+func (t EventType) String() string {
+	switch t {
+	case EventRegister:
+		return "register"
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "delete"
+	case EventClear:
+		return "clear"
+	case EventReclaim:
+		return "reclaim"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a mutation of one key of the map, `Loaded` tells if there was an old value,
+// and `Clear` fires one event per cleared key
+type Event[K comparable, V any] struct {
+	Type     EventType
+	Key      K
+	OldValue V
+	NewValue V
+	Loaded   bool
+}
+
+// Watch subscribes fn to all mutations of the map, fn is called synchronously after the mutation has been applied
+// and the map lock released, so it's safe to call back into the map in fn.
+// The subscription ends when ctx is done or the returned cancel func is called.
+func (m *Map[K, V]) Watch(ctx context.Context, fn func(ev Event[K, V])) (cancel func()) {
+	m.watchersLock.Lock()
+	if m.watchers == nil {
+		m.watchers = make(map[uint64]func(ev Event[K, V]))
+	}
+	m.watcherID++
+	id := m.watcherID
+	m.watchers[id] = fn
+	m.watchersLock.Unlock()
+
+	var once sync.Once
+	stop := make(chan struct{})
+	cancel = func() {
+		once.Do(func() {
+			close(stop) // ends the goroutine watching ctx
+			m.watchersLock.Lock()
+			delete(m.watchers, id)
+			m.watchersLock.Unlock()
+		})
+	}
+	if done := ctx.Done(); done != nil {
+		go func() {
+			select {
+			case <-done:
+				cancel()
+			case <-stop:
+			}
+		}()
+	}
+	return cancel
+}
+
+// notify fires events to all watchers, must be called without holding the map lock
+func (m *Map[K, V]) notify(events ...Event[K, V]) {
+	m.flushEvicted()
+	if len(events) == 0 {
+		return
+	}
+	m.record(events)
+	m.logEvents(events)
+	m.watchersLock.RLock()
+	if len(m.watchers) == 0 {
+		m.watchersLock.RUnlock()
+		return
+	}
+	watchers := make([]func(ev Event[K, V]), 0, len(m.watchers))
+	for _, fn := range m.watchers {
+		watchers = append(watchers, fn)
+	}
+	m.watchersLock.RUnlock()
+	for _, ev := range events {
+		for _, fn := range watchers {
+			fn(ev)
+		}
+	}
+}
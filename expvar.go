@@ -0,0 +1,25 @@
+package inithook
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+)
+
+// PublishExpvar publishes the keys, size and hit/miss counters of the map under name to expvar(i.e. /debug/vars),
+// if name is already published then return `ErrAlreadyExists` error(use `errors.Is` to assert)
+func (m *Map[K, V]) PublishExpvar(name string) error {
+	if expvar.Get(name) != nil {
+		return fmt.Errorf("expvar %s: %w", name, ErrAlreadyExists)
+	}
+	expvar.Publish(name, expvar.Func(func() any {
+		ctx := context.Background()
+		return map[string]any{
+			"keys":   m.Keys(ctx),
+			"len":    m.Len(ctx),
+			"hits":   m.counters.hits.Load(),
+			"misses": m.counters.misses.Load(),
+		}
+	}))
+	return nil
+}
@@ -0,0 +1,39 @@
+package inithook
+
+import "context"
+
+// Filter returns a new map with the same options holding the items which pred returns true,
+// if an item is rejected by the validators(see `WithValidator`) then return the error
+func (m *Map[K, V]) Filter(ctx context.Context, pred func(key K, value V) bool) (*Map[K, V], error) {
+	filtered := NewMap(m.opts...)
+	values := make(map[K]V)
+	for k, v := range m.Map(ctx) {
+		if pred(k, v) {
+			values[k] = v
+		}
+	}
+	if err := filtered.SetMany(ctx, values); err != nil {
+		return nil, err
+	}
+	return filtered, nil
+}
+
+// MapValues returns a new map holding the items of m with values transformed by fn
+func MapValues[K comparable, V, W any](ctx context.Context, m *Map[K, V], fn func(key K, value V) W) *Map[K, W] {
+	mapped := NewMap[K, W]()
+	values := make(map[K]W)
+	for k, v := range m.Map(ctx) {
+		values[k] = fn(k, v)
+	}
+	mapped.SetMany(ctx, values)
+	return mapped
+}
+
+// Reduce folds all items of m into an accumulator starting from init, NOTE: the iteration order is not specified
+func Reduce[K comparable, V, A any](ctx context.Context, m *Map[K, V], init A, fn func(acc A, key K, value V) A) A {
+	acc := init
+	for k, v := range m.Map(ctx) {
+		acc = fn(acc, k, v)
+	}
+	return acc
+}
@@ -0,0 +1,225 @@
+package inithook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DependencyGraph is the dependency graph of hooks and attrs, the hooks are linked to their requirements
+// (see `WithRequires`) and the attrs to their setters(see `RegisterAttrSetter`), see `Graph`
+type DependencyGraph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+	// Cycles are the hook ids of each cycle of requirements, which fails the run with `ErrHookCycle` error
+	Cycles [][]string `json:"cycles,omitempty"`
+}
+
+// GraphNode is a hook, an attr or an attr setter
+type GraphNode struct {
+	// ID is `phase/name` of hooks, `attr/name` of attrs and `setter/attr/name` of setters
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Label string `json:"label"`
+	Phase string `json:"phase,omitempty"`
+	// Missing tells the hook is required but not registered
+	Missing bool `json:"missing,omitempty"`
+}
+
+// GraphEdge links a hook to its requirement, or an attr to its setter
+type GraphEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Kind  string `json:"kind"`
+	Cycle bool   `json:"cycle,omitempty"`
+}
+
+// node kinds and edge kinds of `DependencyGraph`
+const (
+	GraphHook   = "hook"
+	GraphAttr   = "attr"
+	GraphSetter = "setter"
+
+	GraphRequires = "requires"
+	GraphSets     = "sets"
+)
+
+// Graph returns the dependency graph of the hooks of `DefaultHooks` and all attrs
+func Graph() DependencyGraph {
+	return DefaultHooks.Graph()
+}
+
+// Graph returns the dependency graph of the hooks of h and all attrs, nodes and edges are sorted by id
+func (h *Hooks) Graph() DependencyGraph {
+	var g DependencyGraph
+	nodes := make(map[string]*GraphNode)
+	h.lock.Lock()
+	for _, phase := range []Phase{PhaseInit, PhaseStart, PhaseShutdown, PhaseReload} {
+		for _, hk := range h.hooks[phase] {
+			id := hookID(phase, hk.name)
+			nodes[id] = &GraphNode{ID: id, Kind: GraphHook, Label: hk.name, Phase: phase.String()}
+			for _, name := range hk.requires {
+				g.Edges = append(g.Edges, GraphEdge{From: id, To: hookID(phase, name), Kind: GraphRequires})
+			}
+		}
+	}
+	h.lock.Unlock()
+	for _, edge := range g.Edges {
+		if _, ok := nodes[edge.To]; !ok {
+			phase, name, _ := strings.Cut(edge.To, "/")
+			nodes[edge.To] = &GraphNode{ID: edge.To, Kind: GraphHook, Label: name, Phase: phase, Missing: true}
+		}
+	}
+	for _, attr := range attrConstructors.Keys(context.Background()) {
+		id := GraphAttr + "/" + attr
+		nodes[id] = &GraphNode{ID: id, Kind: GraphAttr, Label: attr}
+	}
+	settersLock.Lock()
+	for attr, attrSetters := range setters {
+		id := GraphAttr + "/" + attr
+		nodes[id] = &GraphNode{ID: id, Kind: GraphAttr, Label: attr}
+		for name := range attrSetters {
+			setterID := GraphSetter + "/" + attr + "/" + name
+			nodes[setterID] = &GraphNode{ID: setterID, Kind: GraphSetter, Label: name}
+			g.Edges = append(g.Edges, GraphEdge{From: id, To: setterID, Kind: GraphSets})
+		}
+	}
+	settersLock.Unlock()
+	for _, node := range nodes {
+		g.Nodes = append(g.Nodes, *node)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool {
+		return g.Nodes[i].ID < g.Nodes[j].ID
+	})
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
+		}
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	g.markCycles()
+	return g
+}
+
+func hookID(phase Phase, name string) string {
+	return phase.String() + "/" + name
+}
+
+// markCycles finds the strongly connected components of the requirements by Tarjan's algorithm,
+// the ones with more than one hook or a self requirement are cycles
+func (g *DependencyGraph) markCycles() {
+	adjacent := make(map[string][]string)
+	for _, edge := range g.Edges {
+		if edge.Kind == GraphRequires {
+			adjacent[edge.From] = append(adjacent[edge.From], edge.To)
+		}
+	}
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	component := make(map[string]int) // node id -> cycle index
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, to := range adjacent[id] {
+			if _, ok := index[to]; !ok {
+				visit(to)
+				low[id] = min(low[id], low[to])
+			} else if onStack[to] {
+				low[id] = min(low[id], index[to])
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+		var scc []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			scc = append(scc, top)
+			if top == id {
+				break
+			}
+		}
+		if len(scc) == 1 && !contains(adjacent[id], id) {
+			return
+		}
+		sort.Strings(scc)
+		for _, node := range scc {
+			component[node] = len(g.Cycles)
+		}
+		g.Cycles = append(g.Cycles, scc)
+	}
+	for _, node := range g.Nodes {
+		if _, ok := index[node.ID]; !ok && node.Kind == GraphHook {
+			visit(node.ID)
+		}
+	}
+	for i, edge := range g.Edges {
+		from, ok1 := component[edge.From]
+		to, ok2 := component[edge.To]
+		g.Edges[i].Cycle = edge.Kind == GraphRequires && ok1 && ok2 && from == to
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// DOT formats the graph in the DOT language of Graphviz, hooks are clustered by phase,
+// missing hooks are dashed and cycles are red
+func (g DependencyGraph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph inithook {\n\trankdir=LR;\n")
+	clusters := make(map[string][]GraphNode)
+	var phases []string
+	for _, node := range g.Nodes {
+		if node.Kind != GraphHook {
+			continue
+		}
+		if _, ok := clusters[node.Phase]; !ok {
+			phases = append(phases, node.Phase)
+		}
+		clusters[node.Phase] = append(clusters[node.Phase], node)
+	}
+	for _, phase := range phases {
+		fmt.Fprintf(&b, "\tsubgraph %s {\n\t\tlabel=%s;\n", strconv.Quote("cluster_"+phase), strconv.Quote(phase))
+		for _, node := range clusters[phase] {
+			style := ""
+			if node.Missing {
+				style = ", style=dashed"
+			}
+			fmt.Fprintf(&b, "\t\t%s [label=%s%s];\n", strconv.Quote(node.ID), strconv.Quote(node.Label), style)
+		}
+		b.WriteString("\t}\n")
+	}
+	for _, node := range g.Nodes {
+		switch node.Kind {
+		case GraphAttr:
+			fmt.Fprintf(&b, "\t%s [label=%s, shape=box];\n", strconv.Quote(node.ID), strconv.Quote(node.Label))
+		case GraphSetter:
+			fmt.Fprintf(&b, "\t%s [label=%s, shape=note];\n", strconv.Quote(node.ID), strconv.Quote(node.Label))
+		}
+	}
+	for _, edge := range g.Edges {
+		attrs := "label=" + strconv.Quote(edge.Kind)
+		if edge.Cycle {
+			attrs += ", color=red"
+		}
+		fmt.Fprintf(&b, "\t%s -> %s [%s];\n", strconv.Quote(edge.From), strconv.Quote(edge.To), attrs)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package inithook_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraph(t *testing.T) {
+	noop := func(ctx context.Context) error { return nil }
+	h := inithook.NewHooks()
+	h.OnInit("db", noop)
+	h.OnInit("cache", noop, inithook.WithRequires("db"))
+	h.OnInit("a", noop, inithook.WithRequires("b"))
+	h.OnInit("b", noop, inithook.WithRequires("a", "missing"))
+	h.OnStart("http", noop)
+	g := h.Graph()
+
+	assert.Equalf(t, [][]string{{"init/a", "init/b"}}, g.Cycles, "cycles")
+	nodes := make(map[string]inithook.GraphNode)
+	for _, node := range g.Nodes {
+		nodes[node.ID] = node
+	}
+	assert.Truef(t, nodes["init/missing"].Missing, "missing requirement")
+	assert.Equalf(t, "start", nodes["start/http"].Phase, "phase")
+	assert.Equalf(t, inithook.GraphAttr, nodes["attr/"+inithook.AppName].Kind, "attr")
+	assert.Containsf(t, g.Edges, inithook.GraphEdge{From: "init/cache", To: "init/db", Kind: inithook.GraphRequires}, "requires")
+	assert.Containsf(t, g.Edges, inithook.GraphEdge{From: "init/a", To: "init/b", Kind: inithook.GraphRequires, Cycle: true}, "cycle edge")
+	assert.Containsf(t, g.Edges, inithook.GraphEdge{From: "init/b", To: "init/missing", Kind: inithook.GraphRequires}, "missing edge")
+
+	dot := g.DOT()
+	assert.Containsf(t, dot, `"init/cache" -> "init/db" [label="requires"];`, "dot")
+	assert.Containsf(t, dot, `"init/a" -> "init/b" [label="requires", color=red];`, "dot cycle")
+	assert.Containsf(t, dot, `"init/missing" [label="missing", style=dashed];`, "dot missing")
+	assert.Containsf(t, dot, `subgraph "cluster_start"`, "dot cluster")
+
+	data, err := json.Marshal(g)
+	assert.Nilf(t, err, "json")
+	assert.Containsf(t, string(data), `"cycles":[["init/a","init/b"]]`, "json")
+}
@@ -0,0 +1,108 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+)
+
+var errNoHistory = errors.New("no history")
+
+// keepHistory keeps the current V's instance of key in the history before it's replaced if `WithHistory` is used,
+// the oldest ones are dropped beyond the limit, must be called with write lock held
+func (m *Map[K, V]) keepHistory(key K) {
+	if m.historySize <= 0 {
+		return
+	}
+	old, ok := m.load(key)
+	if !ok {
+		return
+	}
+	if m.history == nil {
+		m.history = make(map[K][]V)
+	}
+	h := append(m.history[key], old)
+	if len(h) > m.historySize {
+		m.retire(key, h[:len(h)-m.historySize]...)
+		h = h[len(h)-m.historySize:]
+	}
+	m.history[key] = h
+}
+
+// retire records the V's instances of key dropped from the history, so that release closes them
+// if `WithAutoClose` is used, must be called with write lock held
+func (m *Map[K, V]) retire(key K, values ...V) {
+	if !m.autoClose || len(values) == 0 {
+		return
+	}
+	if m.retired == nil {
+		m.retired = make(map[K][]V)
+	}
+	m.retired[key] = append(m.retired[key], values...)
+}
+
+// takeRetired takes the retired V's instances which are neither the current one of their key nor in its history,
+// must be called without holding the map lock
+func (m *Map[K, V]) takeRetired() []V {
+	if m.historySize <= 0 {
+		return nil
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var values []V
+	for key, retired := range m.retired {
+		current, loaded := m.load(key)
+	next:
+		for _, v := range retired {
+			if loaded && same(current, v) {
+				continue
+			}
+			for _, kept := range m.history[key] {
+				if same(kept, v) {
+					continue next
+				}
+			}
+			values = append(values, v)
+		}
+	}
+	m.retired = nil
+	return values
+}
+
+// History returns the previous V's instances of key kept by `WithHistory`, from the oldest to the latest,
+// the current one is not included
+func (m *Map[K, V]) History(ctx context.Context, key K) []V {
+	key = m.key(key)
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	h := m.history[key]
+	values := make([]V, 0, len(h))
+	for _, v := range h {
+		values = append(values, m.copy(v))
+	}
+	return values
+}
+
+// Rollback replaces the V's instance of key with the latest one in its history(see `WithHistory`) and drops it from the history,
+// the replaced one is closed if `WithAutoClose` is used, if key has no history then return `ErrNotFound` error(use `errors.Is` to assert)
+func (m *Map[K, V]) Rollback(ctx context.Context, key K) error {
+	key = m.key(key)
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("Rollback", key)
+	}
+	h := m.history[key]
+	old, loaded := m.load(key)
+	if len(h) == 0 || !loaded {
+		m.lock.Unlock()
+		return m.newError("Rollback", key, ErrNotFound, errNoHistory)
+	}
+	value := h[len(h)-1]
+	m.put(key, value)
+	m.history[key] = h[:len(h)-1] // drops the replaced one recorded by put
+	m.retire(key, old)
+	m.lock.Unlock()
+	ev := Event[K, V]{Type: EventSet, Key: key, OldValue: old, NewValue: value, Loaded: true}
+	m.notify(ev)
+	return m.release(ctx, ev)
+}
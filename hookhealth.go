@@ -0,0 +1,87 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Healther is implemented by components which can check their health, e.g. a db connection pool
+type Healther interface {
+	Health(ctx context.Context) error
+}
+
+// HealthFunc is a func implementing Healther
+type HealthFunc func(ctx context.Context) error
+
+// Health implements Healther
+func (f HealthFunc) Health(ctx context.Context) error {
+	return f(ctx)
+}
+
+// WithHealther attaches the health check of the component initialized by a hook, see `Hooks.Health`
+func WithHealther(healther Healther) HookOption {
+	return func(h *hook) {
+		h.healther = healther
+	}
+}
+
+// ErrNotReady defines the error reported by `Hooks.Health` for hooks which have not succeeded yet
+var ErrNotReady = errors.New("not ready")
+
+// Health checks the healthers(see `WithHealther`) of the init and start hooks, and joins the failures,
+// hooks which have not succeeded are reported as `ErrNotReady` error(use `errors.Is` to assert),
+// and skipped hooks are ignored, suitable for a readiness probe
+func (h *Hooks) Health(ctx context.Context) error {
+	h.lock.Lock()
+	var hooks []*hook
+	for _, phase := range []Phase{PhaseInit, PhaseStart} {
+		for _, hk := range h.hooks[phase] {
+			if hk.healther != nil {
+				hooks = append(hooks, hk)
+			}
+		}
+	}
+	h.lock.Unlock()
+	var errs []error
+	for _, hk := range hooks {
+		switch hookState(hk.state.Load()) {
+		case hookSkipped:
+			continue
+		case hookSucceeded:
+			if err := hk.healther.Health(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("inithook: %s hook %s unhealthy: %w", hk.phase, hk.name, err))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("inithook: %s hook %s: %w", hk.phase, hk.name, ErrNotReady))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Health checks the healthers of the init and start hooks of `DefaultHooks`, e.g. used by /readyz
+func Health(ctx context.Context) error {
+	return DefaultHooks.Health(ctx)
+}
+
+// hookState is the state of the last execution of a hook
+type hookState int32
+
+const (
+	hookPending hookState = iota
+	hookSucceeded
+	hookFailed
+	hookSkipped
+)
+
+// done records the result of the execution of hook
+func (h *hook) done(err error) {
+	switch {
+	case err == nil:
+		h.state.Store(int32(hookSucceeded))
+	case err == errHookSkipped:
+		h.state.Store(int32(hookSkipped))
+	default:
+		h.state.Store(int32(hookFailed))
+	}
+}
@@ -0,0 +1,24 @@
+package inithook
+
+import "context"
+
+// OnReload registers a hook executed in the reload phase, which runs every time the config is reloaded(see `Reload`),
+// if name exists in the phase then return `ErrAlreadyExists` error(use `errors.Is` to assert)
+func (h *Hooks) OnReload(name string, fn HookFunc, opts ...HookOption) error {
+	return h.add(PhaseReload, name, fn, opts)
+}
+
+// Reload executes the reload hooks like `RunPhase`, which can be run any times, usually fired by a config reloader
+func (h *Hooks) Reload(ctx context.Context, opts ...RunOption) error {
+	return h.run(ctx, []Phase{PhaseReload}, newRunOptions(opts))
+}
+
+// OnReload registers a hook executed in the reload phase of `DefaultHooks`
+func OnReload(name string, fn HookFunc, opts ...HookOption) error {
+	return DefaultHooks.OnReload(name, fn, opts...)
+}
+
+// Reload executes the reload hooks of `DefaultHooks`
+func Reload(ctx context.Context, opts ...RunOption) error {
+	return DefaultHooks.Reload(ctx, opts...)
+}
@@ -0,0 +1,190 @@
+package inithook
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// HookStatus defines the execution status of a hook
+type HookStatus int
+
+// hook statuses
+const (
+	HookNotRun HookStatus = iota
+	HookSucceeded
+	HookFailed
+	HookTimedOut
+	HookSkipped
+)
+
+// String returns the name of status
+func (s HookStatus) String() string {
+	switch s {
+	case HookNotRun:
+		return "not run"
+	case HookSucceeded:
+		return "succeeded"
+	case HookFailed:
+		return "failed"
+	case HookTimedOut:
+		return "timed out"
+	case HookSkipped:
+		return "skipped"
+	default:
+		return "unknown"
+	}
+}
+
+// HookResult is the execution result of a hook
+type HookResult struct {
+	Phase Phase
+	Name  string
+	// Order is the start order of the hook in the run, starts from 1, 0 if not run or skipped
+	Order    int
+	Status   HookStatus
+	Start    time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// RunReport is the report of a run, see `Hooks.Report`
+type RunReport struct {
+	Start    time.Time
+	Duration time.Duration
+	// Results are sorted by start order, followed by the hooks skipped or not run
+	Results []HookResult
+}
+
+// String formats the report as a table
+func (r RunReport) String() string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ORDER\tPHASE\tNAME\tSTATUS\tDURATION\tERROR")
+	for _, result := range r.Results {
+		order, duration, errMsg := "-", "-", ""
+		if result.Order > 0 {
+			order = strconv.Itoa(result.Order)
+			duration = result.Duration.String()
+		}
+		if result.Err != nil {
+			errMsg = strings.ReplaceAll(result.Err.Error(), "\n", "; ")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", order, result.Phase, result.Name, result.Status, duration, errMsg)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// Report returns the report of the last `Run`, `RunPhase` or `Shutdown`
+func (h *Hooks) Report() RunReport {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if h.report == nil {
+		return RunReport{}
+	}
+	return h.report.build()
+}
+
+// Report returns the report of the last run of `DefaultHooks`
+func Report() RunReport {
+	return DefaultHooks.Report()
+}
+
+// report collects the results of a run
+type report struct {
+	start   time.Time
+	end     time.Time
+	results []HookResult
+	seq     int
+	lock    sync.Mutex
+}
+
+func newReport() *report {
+	return &report{start: time.Now()}
+}
+
+// started assigns the start order of hk, returns the index of its result
+func (r *report) started(hk *hook) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.seq++
+	r.results = append(r.results, HookResult{Phase: hk.phase, Name: hk.name, Order: r.seq})
+	return len(r.results) - 1
+}
+
+// skipped records hk is skipped by its conditions
+func (r *report) skipped(hk *hook) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.results = append(r.results, HookResult{Phase: hk.phase, Name: hk.name, Status: HookSkipped})
+}
+
+// finished records the result of the hook at index
+func (r *report) finished(index int, start time.Time, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	result := &r.results[index]
+	result.Start = start
+	result.Duration = time.Since(start)
+	result.Err = err
+	switch {
+	case err == nil:
+		result.Status = HookSucceeded
+	case errors.Is(err, ErrHookTimeout):
+		result.Status = HookTimedOut
+	default:
+		result.Status = HookFailed
+	}
+}
+
+// notRun records the hooks which are not started
+func (r *report) notRun(hooks []*hook) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for _, hk := range hooks {
+		var found bool
+		for _, result := range r.results {
+			if result.Phase == hk.phase && result.Name == hk.name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			r.results = append(r.results, HookResult{Phase: hk.phase, Name: hk.name, Status: HookNotRun})
+		}
+	}
+}
+
+// done records the end of the run
+func (r *report) done() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.end = time.Now()
+}
+
+func (r *report) build() RunReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	var run, notRun []HookResult
+	for _, result := range r.results {
+		if result.Order > 0 {
+			run = append(run, result)
+		} else {
+			notRun = append(notRun, result)
+		}
+	}
+	end := r.end
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return RunReport{
+		Start:    r.start,
+		Duration: end.Sub(r.start),
+		Results:  append(run, notRun...),
+	}
+}
@@ -0,0 +1,360 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"runtime/debug"
+	"time"
+)
+
+// RunOption used to configure a run of hooks
+type RunOption func(o *runOptions)
+
+type runOptions struct {
+	parallelism     int
+	timeout         time.Duration
+	continueOnError bool
+	features        map[string]bool
+	groups          map[string]bool
+
+	report *report
+}
+
+func newRunOptions(opts []RunOption) *runOptions {
+	o := &runOptions{parallelism: 1}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithParallelism executes at most n independent hooks concurrently, default to 1 i.e. sequentially,
+// a hook is started after its requirements(see `WithRequires`) and the preceding hooks with smaller priority are done
+func WithParallelism(n int) RunOption {
+	return func(o *runOptions) {
+		o.parallelism = n
+	}
+}
+
+// WithRunTimeout sets the overall deadline of the run, when exceeded the running hooks are canceled
+// and reported as `ErrHookTimeout` error, and no more hooks started
+func WithRunTimeout(timeout time.Duration) RunOption {
+	return func(o *runOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithContinueOnError keeps running the remaining hooks after a failure, except the ones requiring the failed hooks,
+// and all failures are joined
+func WithContinueOnError() RunOption {
+	return func(o *runOptions) {
+		o.continueOnError = true
+	}
+}
+
+// WithFeatures enables the feature flags of the run, see `WithFeature`
+func WithFeatures(flags ...string) RunOption {
+	return func(o *runOptions) {
+		if o.features == nil {
+			o.features = make(map[string]bool)
+		}
+		for _, flag := range flags {
+			o.features[flag] = true
+		}
+	}
+}
+
+// Group restricts the run to the hooks tagged for the named group(see `WithGroups`) and the untagged ones,
+// the other hooks are skipped, can be used multiple times to run several groups
+func Group(name string) RunOption {
+	return func(o *runOptions) {
+		if o.groups == nil {
+			o.groups = make(map[string]bool)
+		}
+		o.groups[name] = true
+	}
+}
+
+// enabled tells if the groups, conditions and feature flags of hk are satisfied
+func (o *runOptions) enabled(ctx context.Context, hk *hook) bool {
+	if len(o.groups) > 0 && len(hk.groups) > 0 {
+		var tagged bool
+		for _, group := range hk.groups {
+			if o.groups[group] {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			return false
+		}
+	}
+	for _, flag := range hk.features {
+		if !o.features[flag] {
+			return false
+		}
+	}
+	for _, cond := range hk.conditions {
+		if !cond(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// errHookSkipped reports the hook is skipped by its conditions, which is not a failure
+var errHookSkipped = errors.New("hook skipped")
+
+// execute executes hooks sorted by `Hooks.order`, stops starting hooks at the first failure unless `WithContinueOnError`,
+// returns the completed hooks in completion order
+func execute(ctx context.Context, hooks []*hook, o *runOptions) ([]*hook, error) {
+	position := make(map[string]int, len(hooks))
+	for i, hk := range hooks {
+		position[hk.name] = i
+	}
+	completed := make([]*hook, 0, len(hooks))
+	failed := make([]bool, len(hooks)) // failed, or not run due to failed requirements
+	var errs []error
+	// fail records the failure of hooks[i], and blocks the hooks requiring it, returns true if should stop
+	fail := func(i int, err error) bool {
+		errs = append(errs, err)
+		failed[i] = true
+		for j := i + 1; j < len(hooks); j++ {
+			for _, name := range hooks[j].requires {
+				if failed[position[name]] {
+					failed[j] = true
+					break
+				}
+			}
+		}
+		return !o.continueOnError
+	}
+	if o.parallelism <= 1 {
+		for i, hk := range hooks {
+			if failed[i] {
+				continue
+			}
+			if err := exec(ctx, hk, o); err == errHookSkipped {
+				continue
+			} else if err != nil {
+				if fail(i, err) {
+					break
+				}
+				continue
+			}
+			completed = append(completed, hk)
+		}
+		return completed, joinErrors(errs)
+	}
+	waits := make([][]int, len(hooks)) // hooks which must be done before hooks[i] starts
+	for i, hk := range hooks {
+		for _, name := range hk.requires {
+			waits[i] = append(waits[i], position[name])
+		}
+		for j := 0; j < i; j++ {
+			if hooks[j].priority < hk.priority {
+				waits[i] = append(waits[i], j)
+			}
+		}
+	}
+	type result struct {
+		i   int
+		err error
+	}
+	results := make(chan result)
+	started := make([]bool, len(hooks))
+	done := make([]bool, len(hooks))
+	var running int
+	var serial bool // a serial hook is running
+	var stop bool
+	ready := func(i int) bool {
+		for _, j := range waits[i] {
+			if !done[j] && !failed[j] {
+				return false
+			}
+		}
+		return true
+	}
+	for {
+		for i, hk := range hooks {
+			if stop || serial || running >= o.parallelism {
+				break
+			}
+			if started[i] || failed[i] || !ready(i) {
+				continue
+			}
+			if hk.serial && running > 0 {
+				break // wait for the running hooks, not to starve the serial one
+			}
+			started[i] = true
+			running++
+			serial = hk.serial
+			go func(i int, hk *hook) {
+				results <- result{i: i, err: exec(ctx, hk, o)}
+			}(i, hk)
+		}
+		if running == 0 {
+			return completed, joinErrors(errs)
+		}
+		r := <-results
+		running--
+		done[r.i] = true
+		if hooks[r.i].serial {
+			serial = false
+		}
+		if r.err == errHookSkipped {
+			continue
+		}
+		if r.err != nil {
+			stop = fail(r.i, r.err) || stop
+		} else {
+			completed = append(completed, hooks[r.i])
+		}
+	}
+}
+
+// joinErrors joins errs, returns the error itself if only one
+func joinErrors(errs []error) error {
+	if len(errs) == 1 {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// exec executes a hook in a span of the global tracer(see `SetTracer`) and reports the result,
+// the failure is returned as `*HookError`, returns `errHookSkipped` if the hook is not enabled(see `WithCondition`)
+func exec(ctx context.Context, hk *hook, o *runOptions) (err error) {
+	defer func() {
+		hk.done(err)
+	}()
+	if !o.enabled(ctx, hk) {
+		if o.report != nil {
+			o.report.skipped(hk)
+		}
+		return errHookSkipped
+	}
+	ctx, end := startSpan(ctx, nil, SpanHook, Attribute{AttributePhase, hk.phase.String()}, Attribute{AttributeHook, hk.name})
+	defer func() {
+		end(err)
+	}()
+	if o.report == nil {
+		return attempt(ctx, hk)
+	}
+	start := time.Now()
+	index := o.report.started(hk)
+	err = attempt(ctx, hk)
+	var hookErr *HookError
+	if errors.As(err, &hookErr) {
+		o.report.finished(index, start, hookErr.Err)
+	} else {
+		o.report.finished(index, start, err)
+	}
+	return err
+}
+
+// attempt executes a hook with retries(see `WithRetry`)
+func attempt(ctx context.Context, hk *hook) error {
+	if err := ctx.Err(); err != nil {
+		return &HookError{Phase: hk.phase, Name: hk.name, Err: err}
+	}
+	var errs []error
+	var n int
+	for n = 1; ; n++ {
+		err := invoke(ctx, hk)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, err)
+		if n >= hk.attempts || ctx.Err() != nil {
+			break
+		}
+		timer := time.NewTimer(backoff(hk.backoff, n))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			errs = append(errs, ctx.Err())
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 1 {
+		return &HookError{Phase: hk.phase, Name: hk.name, Err: errs[0]}
+	}
+	return &HookError{Phase: hk.phase, Name: hk.name, Err: fmt.Errorf("%d attempts: %w", n, errors.Join(errs...))}
+}
+
+// backoff returns the jittered exponential backoff after attempt, in [d*2^(attempt-1)/2, d*2^(attempt-1))
+func backoff(d time.Duration, attempt int) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	d <<= attempt - 1
+	if d <= 0 { // overflow
+		d = math.MaxInt64
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// invoke invokes the func of hook, returns `ErrHookTimeout` error without waiting for it if the timeout or deadline exceeded
+func invoke(ctx context.Context, hk *hook) error {
+	var timeout time.Time
+	if hk.timeout > 0 {
+		timeout = time.Now().Add(hk.timeout)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, timeout)
+		defer cancel()
+	}
+	if ctx.Done() == nil { // never canceled
+		return protect(ctx, hk.fn)
+	}
+	result := make(chan error, 1)
+	go func() {
+		result <- protect(ctx, hk.fn)
+	}()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		if ctx.Err() != context.DeadlineExceeded {
+			return ctx.Err()
+		}
+		if !timeout.IsZero() && !time.Now().Before(timeout) {
+			return fmt.Errorf("%w after %s: %w", ErrHookTimeout, hk.timeout, ctx.Err())
+		}
+		return fmt.Errorf("%w: %w", ErrHookTimeout, ctx.Err())
+	}
+}
+
+// ErrHookPanic defines the error of hooks which panic, use `errors.As` with `*PanicError` to retrieve the stack
+var ErrHookPanic = errors.New("hook panic")
+
+// PanicError is the error converted from a panic recovered in a hook
+type PanicError struct {
+	Value any
+	Stack []byte
+}
+
+// Error implements error
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("%v: %v", ErrHookPanic, e.Value)
+}
+
+// Is tells if target is `ErrHookPanic`
+func (e *PanicError) Is(target error) bool {
+	return target == ErrHookPanic
+}
+
+// protect invokes fn, and converts a panic to `*PanicError`
+func protect(ctx context.Context, fn HookFunc) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return fn(ctx)
+}
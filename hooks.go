@@ -0,0 +1,469 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Phase defines a phase of hooks execution
+type Phase int
+
+// hook phases, `Run` executes init hooks then start hooks
+const (
+	PhaseInit Phase = iota + 1
+	PhaseStart
+	PhaseShutdown
+	PhaseReload
+)
+
+// String returns the name of phase
+func (p Phase) String() string {
+	switch p {
+	case PhaseInit:
+		return "init"
+	case PhaseStart:
+		return "start"
+	case PhaseShutdown:
+		return "shutdown"
+	case PhaseReload:
+		return "reload"
+	default:
+		return "unknown"
+	}
+}
+
+// HookFunc is the func executed by a hook
+type HookFunc func(ctx context.Context) error
+
+// HookOption used to configure a hook
+type HookOption func(h *hook)
+
+// hook is a named HookFunc of a phase
+type hook struct {
+	name     string
+	phase    Phase
+	fn       HookFunc
+	priority int
+	requires []string
+	serial   bool
+	timeout  time.Duration
+	attempts int
+	backoff  time.Duration
+	cleanup  HookFunc
+
+	conditions []func(ctx context.Context) bool
+	features   []string
+	groups     []string
+
+	healther Healther
+	state    atomic.Int32 // hookState of the last execution
+}
+
+// WithPriority sets the priority of a hook, hooks with smaller priority are executed first within a phase,
+// hooks with equal priority are executed in registration order, default to 0
+func WithPriority(priority int) HookOption {
+	return func(h *hook) {
+		h.priority = priority
+	}
+}
+
+// WithRequires declares the hooks of the same phase which must be executed before this one,
+// requirements take precedence over priorities
+func WithRequires(names ...string) HookOption {
+	return func(h *hook) {
+		h.requires = append(h.requires, names...)
+	}
+}
+
+// WithSerial makes a hook never executed concurrently with other hooks(see `WithParallelism`),
+// used for hooks which are not goroutine-safe
+func WithSerial() HookOption {
+	return func(h *hook) {
+		h.serial = true
+	}
+}
+
+// WithTimeout sets the timeout of a hook, the ctx of the hook is canceled when exceeded,
+// and the hook is reported as `ErrHookTimeout` error without waiting for it to return
+func WithTimeout(timeout time.Duration) HookOption {
+	return func(h *hook) {
+		h.timeout = timeout
+	}
+}
+
+// WithRetry executes a failed hook again up to attempts times in total, waiting a jittered exponential backoff
+// starting from backoff between attempts, the timeout(see `WithTimeout`) applies to each attempt,
+// and the errors of all attempts are joined if the hook still fails
+func WithRetry(attempts int, backoff time.Duration) HookOption {
+	return func(h *hook) {
+		h.attempts = attempts
+		h.backoff = backoff
+	}
+}
+
+// WithCleanup sets the teardown func of a hook, which is invoked if a later hook of the same run fails,
+// the cleanups of the completed hooks are invoked in reverse completion order
+func WithCleanup(fn HookFunc) HookOption {
+	return func(h *hook) {
+		h.cleanup = fn
+	}
+}
+
+// WithCondition gates a hook by fn, the hook is skipped if fn returns false when it's about to execute,
+// hooks requiring a skipped hook are still executed
+func WithCondition(fn func(ctx context.Context) bool) HookOption {
+	return func(h *hook) {
+		h.conditions = append(h.conditions, fn)
+	}
+}
+
+// WithEnvCondition gates a hook by the environment variable name, the hook is skipped unless it's set to a truthy value,
+// i.e. a value `strconv.ParseBool` parses as true, or any other non-empty value except "off" and "no"
+func WithEnvCondition(name string) HookOption {
+	return WithCondition(func(ctx context.Context) bool {
+		value := strings.TrimSpace(os.Getenv(name))
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+		switch strings.ToLower(value) {
+		case "", "off", "no":
+			return false
+		}
+		return true
+	})
+}
+
+// WithFeature gates a hook by the named feature flag, the hook is skipped unless flag is enabled by `WithFeatures`
+func WithFeature(flag string) HookOption {
+	return func(h *hook) {
+		h.features = append(h.features, flag)
+	}
+}
+
+// WithGroups tags a hook with the named groups(profiles), e.g. "server", "worker" or "cli",
+// a run with `Group` executes only the hooks tagged for the group and the untagged ones
+func WithGroups(names ...string) HookOption {
+	return func(h *hook) {
+		h.groups = append(h.groups, names...)
+	}
+}
+
+// ErrHookTimeout defines the error of hooks exceeding the timeout(see `WithTimeout` and `WithRunTimeout`), which wraps `ErrTimeout`
+var ErrHookTimeout = fmt.Errorf("hook %w", ErrTimeout)
+
+// ErrAlreadyRan defines the error returned if a phase of hooks is run more than once, see `Hooks.Reset`
+var ErrAlreadyRan = errors.New("already ran")
+
+// ErrLateRegistration defines the error returned if a hook is registered after its phase ran, see `WithLateExecution`
+var ErrLateRegistration = errors.New("late registration")
+
+// ErrHookCycle defines the error returned if the requirements of hooks(see `WithRequires`) form a cycle, which wraps `ErrCycle`
+var ErrHookCycle = fmt.Errorf("hook %w", ErrCycle)
+
+// HookError reports the failure of a hook, use `errors.As` to retrieve it
+type HookError struct {
+	Phase Phase
+	Name  string
+	Err   error
+}
+
+// Error implements error
+func (e *HookError) Error() string {
+	return fmt.Sprintf("inithook: %s hook %s failed: %v", e.Phase, e.Name, e.Err)
+}
+
+// Unwrap returns the error of the hook
+func (e *HookError) Unwrap() error {
+	return e.Err
+}
+
+// HooksOption used to configure a Hooks
+type HooksOption func(h *Hooks)
+
+// WithLateExecution executes the hooks registered after their phase ran immediately(with `context.Background()`),
+// instead of rejecting them with `ErrLateRegistration` error, the error of the hook is returned by the registration
+func WithLateExecution() HooksOption {
+	return func(h *Hooks) {
+		h.lateExecution = true
+	}
+}
+
+// NewHooks creates a new hook runner
+func NewHooks(opts ...HooksOption) *Hooks {
+	h := &Hooks{
+		hooks: make(map[Phase][]*hook),
+		ran:   make(map[Phase]bool),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Hooks is a runner of named hooks grouped by phases, hooks of a phase are executed by priority(see `WithPriority`)
+type Hooks struct {
+	hooks  map[Phase][]*hook
+	ran    map[Phase]bool
+	report *report
+	lock   sync.Mutex
+
+	lateExecution bool
+}
+
+// OnInit registers a hook executed in the init phase,
+// if name exists in the phase then return `ErrAlreadyExists` error(use `errors.Is` to assert),
+// and if the phase ran then return `ErrLateRegistration` error which reports the registrant, unless `WithLateExecution`
+func (h *Hooks) OnInit(name string, fn HookFunc, opts ...HookOption) error {
+	return h.add(PhaseInit, name, fn, opts)
+}
+
+// OnStart registers a hook executed in the start phase,
+// if name exists in the phase then return `ErrAlreadyExists` error(use `errors.Is` to assert)
+func (h *Hooks) OnStart(name string, fn HookFunc, opts ...HookOption) error {
+	return h.add(PhaseStart, name, fn, opts)
+}
+
+// OnShutdown registers a hook executed in the shutdown phase,
+// if name exists in the phase then return `ErrAlreadyExists` error(use `errors.Is` to assert)
+func (h *Hooks) OnShutdown(name string, fn HookFunc, opts ...HookOption) error {
+	return h.add(PhaseShutdown, name, fn, opts)
+}
+
+// Run executes the init hooks and then the start hooks, stops at the first failure which is returned as `*HookError`,
+// and then the cleanups(see `WithCleanup`) of the completed hooks are invoked, whose errors are joined to the result.
+// Every phase runs at most once even if failed, otherwise return `ErrAlreadyRan` error(use `errors.Is` to assert)
+func (h *Hooks) Run(ctx context.Context, opts ...RunOption) error {
+	return h.run(ctx, []Phase{PhaseInit, PhaseStart}, newRunOptions(opts))
+}
+
+// RunPhase executes the hooks of phase, stops at the first failure which is returned as `*HookError`,
+// if the requirements of hooks form a cycle then return `ErrHookCycle` error(use `errors.Is` to assert)
+// and if a requirement is not registered then return `ErrNotFound` error, nothing executed in both cases
+func (h *Hooks) RunPhase(ctx context.Context, phase Phase, opts ...RunOption) error {
+	return h.run(ctx, []Phase{phase}, newRunOptions(opts))
+}
+
+func (h *Hooks) run(ctx context.Context, phases []Phase, o *runOptions) error {
+	if err := h.begin(phases); err != nil {
+		return err
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	o.report = h.newReport()
+	defer o.report.done()
+	var completed []*hook
+	for i, phase := range phases {
+		hooks, err := h.order(phase)
+		if err != nil {
+			return rollback(ctx, completed, err)
+		}
+		done, err := execute(ctx, hooks, o)
+		completed = append(completed, done...)
+		o.report.notRun(hooks)
+		if err != nil {
+			for _, phase := range phases[i+1:] {
+				hooks, _ := h.order(phase)
+				o.report.notRun(hooks)
+			}
+			return rollback(ctx, completed, err)
+		}
+	}
+	return nil
+}
+
+// begin marks phases as ran, if any has ran then return `ErrAlreadyRan` error and nothing marked,
+// the reload phase can run any times and is never marked
+func (h *Hooks) begin(phases []Phase) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, phase := range phases {
+		if h.ran[phase] {
+			return fmt.Errorf("inithook: %s hooks: %w", phase, ErrAlreadyRan)
+		}
+	}
+	for _, phase := range phases {
+		if phase != PhaseReload {
+			h.ran[phase] = true
+		}
+	}
+	return nil
+}
+
+// Reset forgets the runs of all phases and the report, so the hooks can be run again, usually used in tests
+func (h *Hooks) Reset() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.ran = make(map[Phase]bool)
+	h.report = nil
+	for _, hooks := range h.hooks {
+		for _, hk := range hooks {
+			hk.state.Store(int32(hookPending))
+		}
+	}
+}
+
+// newReport starts the report of a new run
+func (h *Hooks) newReport() *report {
+	r := newReport()
+	h.lock.Lock()
+	h.report = r
+	h.lock.Unlock()
+	return r
+}
+
+// rollback invokes the cleanups of completed hooks in reverse order after the run failed with err
+func rollback(ctx context.Context, completed []*hook, err error) error {
+	ctx = context.WithoutCancel(ctx) // the run may fail due to ctx
+	errs := []error{err}
+	for i := len(completed) - 1; i >= 0; i-- {
+		hk := completed[i]
+		if hk.cleanup == nil {
+			continue
+		}
+		if err := hk.cleanup(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("inithook: %s hook %s cleanup failed: %w", hk.phase, hk.name, err))
+		}
+	}
+	if len(errs) == 1 {
+		return err
+	}
+	return errors.Join(errs...)
+}
+
+// order returns the hooks of phase in execution order, i.e. topologically sorted by requirements,
+// and the hook with the smallest priority is picked first among the ones whose requirements are satisfied
+func (h *Hooks) order(phase Phase) ([]*hook, error) {
+	h.lock.Lock()
+	hooks := append([]*hook(nil), h.hooks[phase]...)
+	h.lock.Unlock()
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].priority < hooks[j].priority
+	})
+	index := make(map[string]int, len(hooks))
+	for i, hk := range hooks {
+		index[hk.name] = i
+	}
+	pending := make([]int, len(hooks)) // number of unsatisfied requirements
+	dependents := make([][]int, len(hooks))
+	for i, hk := range hooks {
+		for _, name := range hk.requires {
+			j, ok := index[name]
+			if !ok {
+				return nil, fmt.Errorf("inithook: %s hook %s requires %s: %w", phase, hk.name, name, ErrNotFound)
+			}
+			pending[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+	ordered := make([]*hook, 0, len(hooks))
+	done := make([]bool, len(hooks))
+	for len(ordered) < len(hooks) {
+		next := -1
+		for i := range hooks {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("inithook: %s hooks %s: %w", phase, strings.Join(cycle(hooks, index, done), " -> "), ErrHookCycle)
+		}
+		done[next] = true
+		ordered = append(ordered, hooks[next])
+		for _, i := range dependents[next] {
+			pending[i]--
+		}
+	}
+	return ordered, nil
+}
+
+// cycle returns the names of a cycle among the hooks not done, which all have unsatisfied requirements
+func cycle(hooks []*hook, index map[string]int, done []bool) []string {
+	start := 0
+	for done[start] {
+		start++
+	}
+	visited := map[int]int{} // hook index -> position in path
+	var path []int
+	for i := start; ; {
+		if pos, ok := visited[i]; ok {
+			names := make([]string, 0, len(path)-pos+1)
+			for _, j := range path[pos:] {
+				names = append(names, hooks[j].name)
+			}
+			return append(names, hooks[i].name)
+		}
+		visited[i] = len(path)
+		path = append(path, i)
+		for _, name := range hooks[i].requires {
+			if j := index[name]; !done[j] {
+				i = j
+				break
+			}
+		}
+	}
+}
+
+func (h *Hooks) add(phase Phase, name string, fn HookFunc, opts []HookOption) error {
+	if fn == nil {
+		return fmt.Errorf("inithook: nil %s hook %s", phase, name)
+	}
+	hk := &hook{name: name, phase: phase, fn: fn}
+	for _, opt := range opts {
+		opt(hk)
+	}
+	h.lock.Lock()
+	for _, existing := range h.hooks[phase] {
+		if existing.name == name {
+			h.lock.Unlock()
+			return fmt.Errorf("inithook: %s hook %s: %w", phase, name, ErrAlreadyExists)
+		}
+	}
+	late := h.ran[phase]
+	if late && !h.lateExecution {
+		h.lock.Unlock()
+		caller := callerOutside()
+		return fmt.Errorf("inithook: %s hook %s registered by %s at %s after the phase ran: %w",
+			phase, name, caller.Function, caller, ErrLateRegistration)
+	}
+	h.hooks[phase] = append(h.hooks[phase], hk)
+	h.lock.Unlock()
+	if late {
+		return exec(context.Background(), hk, newRunOptions(nil))
+	}
+	return nil
+}
+
+// DefaultHooks is the hook runner used by the package level `OnInit`, `OnStart`, `OnShutdown` and `Run`
+var DefaultHooks = NewHooks()
+
+// OnInit registers a hook executed in the init phase of `DefaultHooks`, usually used in library init
+func OnInit(name string, fn HookFunc, opts ...HookOption) error {
+	return DefaultHooks.OnInit(name, fn, opts...)
+}
+
+// OnStart registers a hook executed in the start phase of `DefaultHooks`
+func OnStart(name string, fn HookFunc, opts ...HookOption) error {
+	return DefaultHooks.OnStart(name, fn, opts...)
+}
+
+// OnShutdown registers a hook executed in the shutdown phase of `DefaultHooks`
+func OnShutdown(name string, fn HookFunc, opts ...HookOption) error {
+	return DefaultHooks.OnShutdown(name, fn, opts...)
+}
+
+// Run executes the init and start hooks of `DefaultHooks`, used in app code
+func Run(ctx context.Context, opts ...RunOption) error {
+	return DefaultHooks.Run(ctx, opts...)
+}
@@ -0,0 +1,578 @@
+package inithook_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooksRun(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var executed []string
+	record := func(name string) inithook.HookFunc {
+		return func(ctx context.Context) error {
+			executed = append(executed, name)
+			return nil
+		}
+	}
+	assert.Nilf(t, h.OnStart("http", record("start:http")), "on start")
+	assert.Nilf(t, h.OnInit("config", record("init:config")), "on init")
+	assert.Nilf(t, h.OnInit("db", record("init:db")), "on init")
+	assert.Nilf(t, h.OnShutdown("db", record("shutdown:db")), "on shutdown")
+	assert.Truef(t, errors.Is(h.OnInit("db", record("init:db")), inithook.ErrAlreadyExists), "duplicate")
+	assert.NotNilf(t, h.OnInit("nil", nil), "nil hook")
+
+	assert.Nilf(t, h.Run(ctx), "run")
+	assert.Equalf(t, []string{"init:config", "init:db", "start:http"}, executed, "phases in order")
+	assert.Nilf(t, h.RunPhase(ctx, inithook.PhaseShutdown), "run shutdown")
+	assert.Equalf(t, "shutdown:db", executed[len(executed)-1], "run shutdown")
+}
+
+func TestHooksRunError(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	errBoom := errors.New("boom")
+	var started bool
+	h.OnInit("db", func(ctx context.Context) error { return errBoom })
+	h.OnStart("http", func(ctx context.Context) error {
+		started = true
+		return nil
+	})
+	err := h.Run(ctx)
+	assert.Truef(t, errors.Is(err, errBoom), "hook error")
+	var hookErr *inithook.HookError
+	assert.Truef(t, errors.As(err, &hookErr), "hook error")
+	assert.Equalf(t, inithook.PhaseInit, hookErr.Phase, "hook error phase")
+	assert.Equalf(t, "db", hookErr.Name, "hook error name")
+	assert.Equalf(t, "inithook: init hook db failed: boom", err.Error(), "hook error message")
+	assert.Falsef(t, started, "start phase not executed")
+}
+
+func TestHooksTracer(t *testing.T) {
+	ctx := context.Background()
+	tracer := &recordTracer{}
+	inithook.SetTracer(tracer)
+	defer inithook.SetTracer(nil)
+	h := inithook.NewHooks()
+	errBoom := errors.New("boom")
+	h.OnInit("config", func(ctx context.Context) error { return nil })
+	h.OnInit("skipped", func(ctx context.Context) error { return nil }, inithook.WithCondition(func(ctx context.Context) bool { return false }))
+	h.OnStart("http", func(ctx context.Context) error { return errBoom })
+	err := h.Run(ctx)
+	assert.Truef(t, errors.Is(err, errBoom), "run")
+	assert.Lenf(t, tracer.spans, 2, "spans of executed hooks")
+	assert.Equalf(t, &span{name: inithook.SpanHook, attrs: []inithook.Attribute{
+		{Key: inithook.AttributePhase, Value: "init"}, {Key: inithook.AttributeHook, Value: "config"},
+	}}, tracer.spans[0], "init hook span")
+	assert.Equalf(t, inithook.SpanHook, tracer.spans[1].name, "start hook span")
+	assert.Equalf(t, []inithook.Attribute{{Key: inithook.AttributePhase, Value: "start"}, {Key: inithook.AttributeHook, Value: "http"}},
+		tracer.spans[1].attrs, "start hook span")
+	assert.Truef(t, errors.Is(tracer.spans[1].err, errBoom), "start hook span error")
+}
+
+func TestHooksPriority(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var executed []string
+	for _, item := range []struct {
+		name     string
+		priority int
+	}{
+		{"http", 30},
+		{"metrics", 0},
+		{"db", 20},
+		{"logging", 10},
+		{"cache", 20},
+		{"tracing", 0},
+	} {
+		name := item.name
+		h.OnInit(name, func(ctx context.Context) error {
+			executed = append(executed, name)
+			return nil
+		}, inithook.WithPriority(item.priority))
+	}
+	assert.Nilf(t, h.Run(ctx), "run")
+	assert.Equalf(t, []string{"metrics", "tracing", "logging", "db", "cache", "http"}, executed, "ordered by priority, stable")
+}
+
+func TestHooksRequires(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var executed []string
+	record := func(name string) inithook.HookFunc {
+		return func(ctx context.Context) error {
+			executed = append(executed, name)
+			return nil
+		}
+	}
+	h.OnInit("http", record("http"), inithook.WithRequires("db", "logger"))
+	h.OnInit("db", record("db"), inithook.WithRequires("config"), inithook.WithPriority(-10))
+	h.OnInit("logger", record("logger"), inithook.WithRequires("config"))
+	h.OnInit("config", record("config"), inithook.WithPriority(10))
+	h.OnInit("metrics", record("metrics"))
+	assert.Nilf(t, h.Run(ctx), "run")
+	assert.Equalf(t, []string{"metrics", "config", "db", "logger", "http"}, executed, "topological order")
+
+	h.Reset()
+	h.OnStart("a", record("a"), inithook.WithRequires("missing"))
+	err := h.RunPhase(ctx, inithook.PhaseStart)
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "missing requirement")
+	assert.Equalf(t, "inithook: start hook a requires missing: not found", err.Error(), "missing requirement")
+
+	h = inithook.NewHooks()
+	h.OnInit("a", record("a"), inithook.WithRequires("b"))
+	h.OnInit("b", record("b"), inithook.WithRequires("c"))
+	h.OnInit("c", record("c"), inithook.WithRequires("a"))
+	h.OnInit("d", record("d"), inithook.WithRequires("a"))
+	executed = nil
+	err = h.Run(ctx)
+	assert.Truef(t, errors.Is(err, inithook.ErrHookCycle), "cycle")
+	assert.Truef(t, errors.Is(err, inithook.ErrCycle), "cycle")
+	assert.Equalf(t, "inithook: init hooks a -> b -> c -> a: hook cycle", err.Error(), "cycle members")
+	assert.Emptyf(t, executed, "nothing executed")
+}
+
+func TestHooksParallelism(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var lock sync.Mutex
+	var running, maxRunning int
+	var executed []string
+	record := func(name string) inithook.HookFunc {
+		return func(ctx context.Context) error {
+			lock.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			lock.Unlock()
+			time.Sleep(20 * time.Millisecond)
+			lock.Lock()
+			running--
+			executed = append(executed, name)
+			lock.Unlock()
+			return nil
+		}
+	}
+	h.OnInit("config", record("config"), inithook.WithPriority(-1))
+	h.OnInit("db", record("db"))
+	h.OnInit("cache", record("cache"))
+	h.OnInit("mq", record("mq"))
+	h.OnInit("http", record("http"), inithook.WithRequires("db", "cache", "mq"))
+	begin := time.Now()
+	assert.Nilf(t, h.Run(ctx, inithook.WithParallelism(4)), "run")
+	assert.Lessf(t, time.Since(begin), 5*20*time.Millisecond, "independent hooks executed concurrently")
+	assert.Equalf(t, 3, maxRunning, "db, cache and mq executed concurrently")
+	assert.Equalf(t, "config", executed[0], "priority respected")
+	assert.Equalf(t, "http", executed[len(executed)-1], "requirements respected")
+
+	h = inithook.NewHooks()
+	maxRunning = 0
+	h.OnInit("a", record("a"))
+	h.OnInit("b", record("b"), inithook.WithSerial())
+	h.OnInit("c", record("c"))
+	assert.Nilf(t, h.Run(ctx, inithook.WithParallelism(4)), "run")
+	assert.Equalf(t, 1, maxRunning, "serial hook executed alone")
+}
+
+func TestHooksParallelismError(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	errBoom := errors.New("boom")
+	var httpExecuted atomic.Bool
+	h.OnInit("db", func(ctx context.Context) error { return errBoom })
+	h.OnInit("cache", func(ctx context.Context) error { return nil })
+	h.OnInit("http", func(ctx context.Context) error {
+		httpExecuted.Store(true)
+		return nil
+	}, inithook.WithRequires("db", "cache"))
+	err := h.Run(ctx, inithook.WithParallelism(4))
+	assert.Truef(t, errors.Is(err, errBoom), "error")
+	assert.Falsef(t, httpExecuted.Load(), "dependent not executed")
+}
+
+func TestHooksTimeout(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	h.OnInit("config", func(ctx context.Context) error { return nil }, inithook.WithTimeout(time.Second))
+	h.OnInit("remote", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, inithook.WithTimeout(10*time.Millisecond))
+	hung := make(chan struct{})
+	defer close(hung)
+	h.OnInit("hung", func(ctx context.Context) error { // ignores ctx
+		<-hung
+		return nil
+	}, inithook.WithTimeout(10*time.Millisecond))
+	err := h.Run(ctx)
+	assert.Truef(t, errors.Is(err, inithook.ErrHookTimeout), "timeout")
+	assert.Truef(t, errors.Is(err, inithook.ErrTimeout), "timeout")
+	assert.Truef(t, errors.Is(err, context.DeadlineExceeded), "timeout")
+	assert.Equalf(t, "inithook: init hook remote failed: hook timeout after 10ms: context deadline exceeded", err.Error(), "names the offender")
+
+	h = inithook.NewHooks()
+	h.OnInit("hung", func(ctx context.Context) error {
+		<-hung
+		return nil
+	})
+	begin := time.Now()
+	err = h.Run(ctx, inithook.WithRunTimeout(10*time.Millisecond))
+	assert.Lessf(t, time.Since(begin), time.Second, "not waiting for hung hook")
+	assert.Truef(t, errors.Is(err, inithook.ErrHookTimeout), "run timeout")
+	var hookErr *inithook.HookError
+	assert.Truef(t, errors.As(err, &hookErr) && hookErr.Name == "hung", "names the offender")
+}
+
+func TestHooksRetry(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var calls int
+	h.OnInit("remote", func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	}, inithook.WithRetry(3, time.Millisecond))
+	assert.Nilf(t, h.Run(ctx), "succeed at the last attempt")
+	assert.Equalf(t, 3, calls, "attempts")
+
+	h = inithook.NewHooks()
+	calls = 0
+	h.OnInit("dns", func(ctx context.Context) error {
+		calls++
+		return errors.New("attempt " + strconv.Itoa(calls))
+	}, inithook.WithRetry(3, time.Millisecond))
+	err := h.Run(ctx)
+	assert.Equalf(t, 3, calls, "attempts")
+	assert.Equalf(t, "inithook: init hook dns failed: 3 attempts: attempt 1\nattempt 2\nattempt 3", err.Error(), "aggregated error")
+}
+
+func TestHooksCleanup(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var cleaned []string
+	cleanup := func(name string) inithook.HookOption {
+		return inithook.WithCleanup(func(ctx context.Context) error {
+			cleaned = append(cleaned, name)
+			if name == "cache" {
+				return errors.New("close cache")
+			}
+			return nil
+		})
+	}
+	ok := func(ctx context.Context) error { return nil }
+	errBoom := errors.New("boom")
+	h.OnInit("db", ok, cleanup("db"))
+	h.OnInit("config", ok)
+	h.OnInit("cache", ok, cleanup("cache"))
+	h.OnStart("consumer", ok, cleanup("consumer"))
+	h.OnStart("http", func(ctx context.Context) error { return errBoom }, cleanup("http"))
+	h.OnStart("grpc", ok, cleanup("grpc"), inithook.WithPriority(1))
+	err := h.Run(ctx)
+	assert.Equalf(t, []string{"consumer", "cache", "db"}, cleaned, "completed hooks cleaned in reverse order")
+	assert.Truef(t, errors.Is(err, errBoom), "hook error")
+	var hookErr *inithook.HookError
+	assert.Truef(t, errors.As(err, &hookErr) && hookErr.Name == "http", "hook error")
+	assert.Equalf(t, "inithook: start hook http failed: boom\ninithook: init hook cache cleanup failed: close cache", err.Error(), "cleanup errors joined")
+}
+
+func TestHooksShutdown(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var executed []string
+	record := func(name string, err error) inithook.HookFunc {
+		return func(ctx context.Context) error {
+			executed = append(executed, name)
+			return err
+		}
+	}
+	errClose := errors.New("close")
+	h.OnShutdown("logger", record("logger", nil), inithook.WithPriority(-1))
+	h.OnShutdown("db", record("db", errClose))
+	h.OnShutdown("http", record("http", nil), inithook.WithRequires("db"))
+	err := h.Shutdown(ctx)
+	assert.Equalf(t, []string{"http", "db", "logger"}, executed, "reverse order, continue on error")
+	assert.Truef(t, errors.Is(err, errClose), "error")
+}
+
+func TestHooksReload(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var reloads int
+	assert.Nilf(t, h.OnReload("cache", func(ctx context.Context) error {
+		reloads++
+		return nil
+	}), "register")
+	assert.Truef(t, errors.Is(h.OnReload("cache", func(ctx context.Context) error { return nil }), inithook.ErrAlreadyExists), "duplicate")
+	assert.Nilf(t, h.Run(ctx), "run")
+	assert.Equalf(t, 0, reloads, "not run by Run")
+	assert.Nilf(t, h.Reload(ctx), "reload")
+	assert.Nilf(t, h.Reload(ctx), "reload again")
+	assert.Equalf(t, 2, reloads, "reloaded")
+	assert.Equalf(t, "reload", inithook.PhaseReload.String(), "phase")
+}
+
+func TestHooksListenSignals(t *testing.T) {
+	h := inithook.NewHooks()
+	var shutdown atomic.Bool
+	h.OnShutdown("http", func(ctx context.Context) error {
+		assert.Nilf(t, ctx.Err(), "shutdown not canceled")
+		shutdown.Store(true)
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Nilf(t, h.ListenSignals(ctx), "ctx done")
+	assert.Truef(t, shutdown.Load(), "shutdown when ctx done")
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+	shutdown.Store(false)
+	h.Reset()
+	result := make(chan error)
+	go func() { result <- h.ListenSignals(context.Background(), os.Interrupt) }()
+	time.Sleep(20 * time.Millisecond) // wait for signal.Notify
+	p, _ := os.FindProcess(os.Getpid())
+	assert.Nilf(t, p.Signal(os.Interrupt), "send signal")
+	select {
+	case err := <-result:
+		assert.Nilf(t, err, "signal")
+	case <-time.After(time.Second):
+		t.Fatal("signal not handled")
+	}
+	assert.Truef(t, shutdown.Load(), "shutdown when signaled")
+}
+
+func TestHooksReport(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	assert.Emptyf(t, h.Report().Results, "no run")
+	h.OnInit("config", func(ctx context.Context) error { return nil })
+	h.OnInit("db", func(ctx context.Context) error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	h.OnInit("remote", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, inithook.WithTimeout(time.Millisecond))
+	h.OnInit("cache", func(ctx context.Context) error { return nil }, inithook.WithPriority(1))
+	h.OnStart("http", func(ctx context.Context) error { return nil })
+	h.Run(ctx)
+
+	report := h.Report()
+	var summary []string
+	for _, result := range report.Results {
+		summary = append(summary, fmt.Sprintf("%d %s %s %s", result.Order, result.Phase, result.Name, result.Status))
+	}
+	assert.Equalf(t, []string{
+		"1 init config succeeded",
+		"2 init db succeeded",
+		"3 init remote timed out",
+		"0 init cache not run",
+		"0 start http not run",
+	}, summary, "results")
+	assert.GreaterOrEqualf(t, report.Results[1].Duration, 10*time.Millisecond, "duration")
+	assert.GreaterOrEqualf(t, report.Duration, report.Results[1].Duration, "run duration")
+	assert.Truef(t, errors.Is(report.Results[2].Err, inithook.ErrHookTimeout), "error")
+
+	table := report.String()
+	assert.Containsf(t, table, "ORDER  PHASE  NAME    STATUS", "table header")
+	assert.Containsf(t, table, "-      init   cache   not run    -", "table row")
+	assert.Containsf(t, table, "hook timeout after 1ms: context deadline exceeded", "table error")
+}
+
+func TestHooksPanic(t *testing.T) {
+	ctx := context.Background()
+	for _, opts := range [][]inithook.HookOption{nil, {inithook.WithTimeout(time.Second)}} {
+		h := inithook.NewHooks()
+		h.OnInit("plugin", func(ctx context.Context) error {
+			panic("misbehaving plugin")
+		}, opts...)
+		err := h.Run(ctx)
+		assert.Truef(t, errors.Is(err, inithook.ErrHookPanic), "panic converted")
+		assert.Equalf(t, "inithook: init hook plugin failed: hook panic: misbehaving plugin", err.Error(), "panic converted")
+		var panicErr *inithook.PanicError
+		assert.Truef(t, errors.As(err, &panicErr), "panic error")
+		assert.Containsf(t, string(panicErr.Stack), "hooks_test.go", "stack")
+	}
+}
+
+func TestHooksContinueOnError(t *testing.T) {
+	ctx := context.Background()
+	for _, parallelism := range []int{1, 4} {
+		h := inithook.NewHooks()
+		var lock sync.Mutex
+		var executed []string
+		record := func(name string, err error) inithook.HookFunc {
+			return func(ctx context.Context) error {
+				lock.Lock()
+				executed = append(executed, name)
+				lock.Unlock()
+				return err
+			}
+		}
+		errDB, errMQ := errors.New("db"), errors.New("mq")
+		h.OnInit("db", record("db", errDB))
+		h.OnInit("repo", record("repo", nil), inithook.WithRequires("db"))
+		h.OnInit("http", record("http", nil), inithook.WithRequires("repo"))
+		h.OnInit("mq", record("mq", errMQ))
+		h.OnInit("cache", record("cache", nil))
+		err := h.Run(ctx, inithook.WithContinueOnError(), inithook.WithParallelism(parallelism))
+		assert.Truef(t, errors.Is(err, errDB) && errors.Is(err, errMQ), "all failures joined")
+		sort.Strings(executed)
+		assert.Equalf(t, []string{"cache", "db", "mq"}, executed, "dependents of failed hooks not executed")
+
+		if parallelism == 1 {
+			h.Reset()
+			err = h.Run(ctx)
+			assert.Truef(t, errors.Is(err, errDB) && !errors.Is(err, errMQ), "stop at the first failure")
+		}
+	}
+}
+
+func TestHooksCondition(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var executed []string
+	record := func(name string) inithook.HookFunc {
+		return func(ctx context.Context) error {
+			executed = append(executed, name)
+			return nil
+		}
+	}
+	t.Setenv("INITHOOK_TEST_OTEL_ENABLED", "true")
+	t.Setenv("INITHOOK_TEST_PPROF_ENABLED", "off")
+	h.OnInit("config", record("config"))
+	h.OnInit("tracing", record("tracing"), inithook.WithEnvCondition("INITHOOK_TEST_OTEL_ENABLED"))
+	h.OnInit("pprof", record("pprof"), inithook.WithEnvCondition("INITHOOK_TEST_PPROF_ENABLED"))
+	h.OnInit("debug", record("debug"), inithook.WithCondition(func(ctx context.Context) bool { return false }))
+	h.OnInit("http", record("http"), inithook.WithRequires("debug"))
+	h.OnInit("beta", record("beta"), inithook.WithFeature("beta"))
+	h.OnInit("canary", record("canary"), inithook.WithFeature("canary"))
+	assert.Nilf(t, h.Run(ctx, inithook.WithFeatures("beta")), "run")
+	assert.Equalf(t, []string{"config", "tracing", "http", "beta"}, executed, "conditions")
+
+	var statuses []string
+	for _, result := range h.Report().Results {
+		statuses = append(statuses, fmt.Sprintf("%d %s %s", result.Order, result.Name, result.Status))
+	}
+	assert.Equalf(t, []string{
+		"1 config succeeded",
+		"2 tracing succeeded",
+		"3 http succeeded",
+		"4 beta succeeded",
+		"0 pprof skipped",
+		"0 debug skipped",
+		"0 canary skipped",
+	}, statuses, "report")
+}
+
+func TestHooksGroup(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var executed []string
+	record := func(name string) inithook.HookFunc {
+		return func(ctx context.Context) error {
+			executed = append(executed, name)
+			return nil
+		}
+	}
+	h.OnInit("config", record("config"))
+	h.OnInit("http", record("http"), inithook.WithGroups("server"))
+	h.OnInit("consumer", record("consumer"), inithook.WithGroups("worker"))
+	h.OnInit("db", record("db"), inithook.WithGroups("server", "worker"))
+	assert.Nilf(t, h.Run(ctx, inithook.Group("worker")), "run worker")
+	assert.Equalf(t, []string{"config", "consumer", "db"}, executed, "worker group")
+
+	executed = nil
+	h.Reset()
+	assert.Nilf(t, h.Run(ctx), "run all")
+	assert.Equalf(t, []string{"config", "http", "consumer", "db"}, executed, "no group")
+}
+
+func TestHooksRunOnce(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var calls int
+	h.OnInit("config", func(ctx context.Context) error {
+		calls++
+		return nil
+	})
+	h.OnShutdown("config", func(ctx context.Context) error { return nil })
+	assert.Nilf(t, h.Run(ctx), "run")
+	err := h.Run(ctx)
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyRan), "run twice")
+	assert.Equalf(t, "inithook: init hooks: already ran", err.Error(), "run twice")
+	assert.Truef(t, errors.Is(h.RunPhase(ctx, inithook.PhaseStart), inithook.ErrAlreadyRan), "run phase after run")
+	assert.Equalf(t, 1, calls, "executed once")
+	assert.Nilf(t, h.Shutdown(ctx), "shutdown")
+	assert.Truef(t, errors.Is(h.Shutdown(ctx), inithook.ErrAlreadyRan), "shutdown twice")
+
+	h.Reset()
+	assert.Nilf(t, h.Run(ctx), "run after reset")
+	assert.Equalf(t, 2, calls, "executed after reset")
+}
+
+func TestHooksLateRegistration(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	assert.Nilf(t, h.Run(ctx), "run")
+	err := h.OnInit("plugin", func(ctx context.Context) error { return nil })
+	assert.Truef(t, errors.Is(err, inithook.ErrLateRegistration), "late registration")
+	assert.Containsf(t, err.Error(), "inithook: init hook plugin registered by github.com/ccmonky/inithook_test.TestHooksLateRegistration at ", "reports the registrant")
+	assert.Containsf(t, err.Error(), "hooks_test.go:", "reports the registrant")
+	assert.Nilf(t, h.OnShutdown("plugin", func(ctx context.Context) error { return nil }), "phase not ran")
+
+	h = inithook.NewHooks(inithook.WithLateExecution())
+	assert.Nilf(t, h.Run(ctx), "run")
+	var executed bool
+	assert.Nilf(t, h.OnInit("plugin", func(ctx context.Context) error {
+		executed = true
+		return nil
+	}), "late execution")
+	assert.Truef(t, executed, "executed immediately")
+	errBoom := errors.New("boom")
+	assert.Truef(t, errors.Is(h.OnStart("lazy", func(ctx context.Context) error { return errBoom }), errBoom), "late execution error")
+}
+
+func TestHooksHealth(t *testing.T) {
+	ctx := context.Background()
+	h := inithook.NewHooks()
+	var dbErr error
+	errDown := errors.New("down")
+	ok := func(ctx context.Context) error { return nil }
+	h.OnInit("db", ok, inithook.WithHealther(inithook.HealthFunc(func(ctx context.Context) error { return dbErr })))
+	h.OnInit("tracing", ok, inithook.WithFeature("tracing"), inithook.WithHealther(inithook.HealthFunc(func(ctx context.Context) error {
+		return errDown
+	})))
+	h.OnStart("http", ok, inithook.WithHealther(inithook.HealthFunc(func(ctx context.Context) error { return nil })))
+	h.OnInit("config", ok)
+	err := h.Health(ctx)
+	assert.Truef(t, errors.Is(err, inithook.ErrNotReady), "not ready before run")
+
+	assert.Nilf(t, h.Run(ctx), "run")
+	assert.Nilf(t, h.Health(ctx), "healthy, skipped hook ignored")
+	dbErr = errDown
+	err = h.Health(ctx)
+	assert.Truef(t, errors.Is(err, errDown), "unhealthy")
+	assert.Equalf(t, "inithook: init hook db unhealthy: down", err.Error(), "unhealthy")
+
+	h.Reset()
+	assert.Truef(t, errors.Is(h.Health(ctx), inithook.ErrNotReady), "not ready after reset")
+}
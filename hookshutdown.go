@@ -0,0 +1,59 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Shutdown executes the shutdown hooks in reverse order, i.e. the reverse of the order in which they would be executed
+// by `RunPhase`, all hooks are executed even if some fail, and the failures are joined,
+// if the shutdown phase has ran then return `ErrAlreadyRan` error(use `errors.Is` to assert)
+func (h *Hooks) Shutdown(ctx context.Context, opts ...RunOption) error {
+	if err := h.begin([]Phase{PhaseShutdown}); err != nil {
+		return err
+	}
+	o := newRunOptions(opts)
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	o.report = h.newReport()
+	defer o.report.done()
+	hooks, err := h.order(PhaseShutdown)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := exec(ctx, hooks[i], o); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ListenSignals blocks until one of sigs is received or ctx is done, then executes `Shutdown` and returns its result,
+// sigs default to `os.Interrupt` and `syscall.SIGTERM`, the shutdown is not canceled by ctx
+func (h *Hooks) ListenSignals(ctx context.Context, sigs ...os.Signal) error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt, syscall.SIGTERM}
+	}
+	signalCtx, stop := signal.NotifyContext(ctx, sigs...)
+	<-signalCtx.Done()
+	stop()
+	return h.Shutdown(context.WithoutCancel(ctx))
+}
+
+// Shutdown executes the shutdown hooks of `DefaultHooks` in reverse order
+func Shutdown(ctx context.Context, opts ...RunOption) error {
+	return DefaultHooks.Shutdown(ctx, opts...)
+}
+
+// ListenSignals blocks until one of sigs is received or ctx is done, then executes the shutdown hooks of `DefaultHooks`
+func ListenSignals(ctx context.Context, sigs ...os.Signal) error {
+	return DefaultHooks.ListenSignals(ctx, sigs...)
+}
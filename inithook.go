@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 )
 
 // used for doc
@@ -24,7 +25,7 @@ type AttrSetter[T any] func(ctx context.Context, value T) error
 
 // RegisterAttrSetter used to register AttrSetter, and should be used in library init
 // NOTE: the execution order of setters cannot be guaranteed!
-func RegisterAttrSetter[T any](attr, setterName string, setter AttrSetter[T]) error {
+func RegisterAttrSetter[T any](attr, setterName string, setter AttrSetter[T], opts ...SetterOption) error {
 	if setter == nil {
 		return fmt.Errorf("inithook: nil attr setter")
 	}
@@ -32,20 +33,37 @@ func RegisterAttrSetter[T any](attr, setterName string, setter AttrSetter[T]) er
 	if err != nil && !errors.Is(err, ErrAlreadyExists) {
 		return err
 	}
+	as := &attrSetter{
+		fn: func(ctx context.Context, value any) error {
+			if typed, ok := value.(T); ok {
+				return setter(ctx, typed)
+			}
+			return fmt.Errorf("attr %s setter value type should be %T but got %T", attr, *new(T), value)
+		},
+	}
+	for _, opt := range opts {
+		opt(as)
+	}
 	settersLock.Lock()
 	if setters[attr] == nil {
 		setters[attr] = map[SetterName]any{}
 	}
-	setters[attr][setterName] = genericAttrSetter(func(ctx context.Context, value any) error {
-		if typed, ok := value.(T); ok {
-			return setter(ctx, typed)
-		}
-		return fmt.Errorf("attr %s setter value type should be %T but got %T", attr, *new(T), value)
-	})
+	setters[attr][setterName] = as
 	settersLock.Unlock()
 	return nil
 }
 
+// SetterOption used to configure an attr setter
+type SetterOption func(*attrSetter)
+
+// WithDynamic makes the setter re-executed every time the attr is set, e.g. to hot-reload log level or feature flags,
+// otherwise the setter is executed only once, the later sets of the attr are ignored by it
+func WithDynamic() SetterOption {
+	return func(as *attrSetter) {
+		as.dynamic = true
+	}
+}
+
 // ExecuteMapAttrSetters execute a map of attr setters with json format value, used in app code
 func ExecuteMapAttrSetters(ctx context.Context, attrsData map[Attr]json.RawMessage) error {
 	for attr, data := range attrsData {
@@ -66,22 +84,30 @@ func ExecuteMapAttrSetters(ctx context.Context, attrsData map[Attr]json.RawMessa
 	return nil
 }
 
-// ExecuteAttrSetters execute attr setters, used in app code
+// ExecuteAttrSetters execute attr setters, used in app code,
+// the attr can be set again after init, which only re-executes the setters registered `WithDynamic`
 func ExecuteAttrSetters(ctx context.Context, attr string, value any) error {
 	settersLock.Lock()
 	attrSetters := setters[attr]
 	settersLock.Unlock()
 	for name, setter := range attrSetters {
-		gas, ok := setter.(genericAttrSetter)
+		as, ok := setter.(*attrSetter)
 		if !ok {
-			return fmt.Errorf("inithook: attr %s setter %s should be `func(context.Context, any) error` but got %T", attr, name, setter)
+			return fmt.Errorf("inithook: attr %s setter %s should be `*attrSetter` but got %T", attr, name, setter)
+		}
+		if as.executed.Load() && !as.dynamic {
+			continue
 		}
-		err := gas(ctx, value)
+		spanCtx, end := startSpan(ctx, nil, SpanAttrSetter, Attribute{AttributeAttr, attr}, Attribute{AttributeSetter, name})
+		err := as.fn(spanCtx, value)
+		end(err)
 		if err != nil {
 			return fmt.Errorf("inithook: attr %s setters %s executed failed: %v", attr, name, err)
 		}
+		as.executed.Store(true)
 	}
 	settersUsed.Set(context.Background(), attr, struct{}{})
+	attrValues.Set(context.Background(), attr, value)
 	return nil
 }
 
@@ -146,6 +172,15 @@ var (
 	attrConstructors = NewMap[Attr, func() any]()
 
 	settersUsed = NewMap[Attr, struct{}]()
+
+	attrValues = NewMap[Attr, any]()
 )
 
 type genericAttrSetter func(ctx context.Context, value any) error
+
+// attrSetter is a registered attr setter
+type attrSetter struct {
+	fn       genericAttrSetter
+	dynamic  bool
+	executed atomic.Bool // executed successfully at least once
+}
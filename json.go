@@ -0,0 +1,109 @@
+package inithook
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// MarshalJSON implements `json.Marshaler`, serializes the instances as a json object under the read lock,
+// keys are encoded by the key codec if `WithKeyCodec` is used,
+// otherwise K should be a string, an integer or implement `encoding.TextMarshaler`
+func (m *Map[K, V]) MarshalJSON() ([]byte, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if m.keyEncoder == nil {
+		values := make(map[K]V, m.store.Len())
+		m.each(func(key K, value V) bool {
+			values[key] = value
+			return true
+		})
+		return json.Marshal(values)
+	}
+	values := make(map[string]V, m.store.Len())
+	var err error
+	m.each(func(key K, value V) bool {
+		var s string
+		s, err = m.keyEncoder(key)
+		if err != nil {
+			err = m.newError("MarshalJSON", key, ErrInvalidKey, err)
+			return false
+		}
+		values[s] = value
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(values)
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`, sets all instances of the json object(see `SetMany`),
+// keys are decoded by the key codec if `WithKeyCodec` is used
+func (m *Map[K, V]) UnmarshalJSON(data []byte) error {
+	if m.store == nil { // zero Map, e.g. a struct field
+		m.store = NewMapStore[K, V]()
+	}
+	if m.keyDecoder == nil {
+		var values map[K]V
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		return m.SetMany(context.Background(), values)
+	}
+	var raw map[string]V
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	values := make(map[K]V, len(raw))
+	for s, value := range raw {
+		key, err := m.keyDecoder(s)
+		if err != nil {
+			return m.newError("UnmarshalJSON", s, ErrInvalidKey, err)
+		}
+		values[key] = value
+	}
+	return m.SetMany(context.Background(), values)
+}
+
+// StageJSON stages the replacement of the instances decoded from the json object old(e.g. the previous config)
+// with the ones decoded from the json object new, i.e. the keys of old absent in new are deleted,
+// both are decoded and the new instances validated in a staging Map(see `UnmarshalJSON`) ahead,
+// returns the swap func which applies the replacement atomically(see `Tx`) so readers never see a partial reload,
+// nothing changes until swap is called, old can be nil to just set the new instances
+func (m *Map[K, V]) StageJSON(ctx context.Context, old, new []byte) (swap func(ctx context.Context) error, err error) {
+	stagedOld := &Map[K, V]{keyDecoder: m.keyDecoder, store: NewMapStore[K, V]()}
+	if len(old) > 0 {
+		if err := stagedOld.UnmarshalJSON(old); err != nil {
+			return nil, err
+		}
+	}
+	stagedNew := &Map[K, V]{keyDecoder: m.keyDecoder, store: NewMapStore[K, V]()}
+	if err := stagedNew.UnmarshalJSON(new); err != nil {
+		return nil, err
+	}
+	values := m.keys(stagedNew.Map(ctx))
+	if err := m.validateMany(ctx, "StageJSON", values); err != nil {
+		return nil, err
+	}
+	var stale []K
+	for _, key := range stagedOld.Keys(ctx) {
+		if _, ok := values[m.key(key)]; !ok {
+			stale = append(stale, m.key(key))
+		}
+	}
+	return func(ctx context.Context) error {
+		return m.Tx(ctx, func(tx Txn[K, V]) error {
+			for _, key := range stale {
+				if tx.Has(ctx, key) {
+					tx.Delete(ctx, key)
+				}
+			}
+			for key, value := range values {
+				if err := tx.Set(ctx, key, value); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}, nil
+}
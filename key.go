@@ -0,0 +1,97 @@
+package inithook
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Key2 is a composite key of 2 elements, e.g. (service, version) or (method, path), it's encoded as a json array,
+// and implements `encoding.TextMarshaler` so maps keyed by it can be marshaled to json(see `Map.MarshalJSON`)
+type Key2[A, B comparable] struct {
+	First  A
+	Second B
+}
+
+// NewKey2 creates a `Key2`
+func NewKey2[A, B comparable](first A, second B) Key2[A, B] {
+	return Key2[A, B]{First: first, Second: second}
+}
+
+// String returns `(first, second)`
+func (k Key2[A, B]) String() string {
+	return fmt.Sprintf("(%v, %v)", k.First, k.Second)
+}
+
+// MarshalJSON implements `json.Marshaler`, encodes k as `[first, second]`
+func (k Key2[A, B]) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{k.First, k.Second})
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`
+func (k *Key2[A, B]) UnmarshalJSON(data []byte) error {
+	return unmarshalTuple(data, &k.First, &k.Second)
+}
+
+// MarshalText implements `encoding.TextMarshaler`, same as `MarshalJSON`
+func (k Key2[A, B]) MarshalText() ([]byte, error) {
+	return k.MarshalJSON()
+}
+
+// UnmarshalText implements `encoding.TextUnmarshaler`, same as `UnmarshalJSON`
+func (k *Key2[A, B]) UnmarshalText(text []byte) error {
+	return k.UnmarshalJSON(text)
+}
+
+// Key3 is a composite key of 3 elements, e.g. (service, method, version), see `Key2`
+type Key3[A, B, C comparable] struct {
+	First  A
+	Second B
+	Third  C
+}
+
+// NewKey3 creates a `Key3`
+func NewKey3[A, B, C comparable](first A, second B, third C) Key3[A, B, C] {
+	return Key3[A, B, C]{First: first, Second: second, Third: third}
+}
+
+// String returns `(first, second, third)`
+func (k Key3[A, B, C]) String() string {
+	return fmt.Sprintf("(%v, %v, %v)", k.First, k.Second, k.Third)
+}
+
+// MarshalJSON implements `json.Marshaler`, encodes k as `[first, second, third]`
+func (k Key3[A, B, C]) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]any{k.First, k.Second, k.Third})
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`
+func (k *Key3[A, B, C]) UnmarshalJSON(data []byte) error {
+	return unmarshalTuple(data, &k.First, &k.Second, &k.Third)
+}
+
+// MarshalText implements `encoding.TextMarshaler`, same as `MarshalJSON`
+func (k Key3[A, B, C]) MarshalText() ([]byte, error) {
+	return k.MarshalJSON()
+}
+
+// UnmarshalText implements `encoding.TextUnmarshaler`, same as `UnmarshalJSON`
+func (k *Key3[A, B, C]) UnmarshalText(text []byte) error {
+	return k.UnmarshalJSON(text)
+}
+
+// unmarshalTuple decodes the json array data into elems, the length should be the same
+func unmarshalTuple(data []byte, elems ...any) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("inithook: invalid composite key %s: %w", data, err)
+	}
+	if len(raw) != len(elems) {
+		return fmt.Errorf("inithook: invalid composite key %s: should have %d elements", data, len(elems))
+	}
+	for i, elem := range elems {
+		if err := json.Unmarshal(raw[i], elem); err != nil {
+			return fmt.Errorf("inithook: invalid composite key %s: %w", data, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+)
+
+// Shutdowner is implemented by values which need a context to release their resources
+type Shutdowner interface {
+	Shutdown(ctx context.Context) error
+}
+
+// release closes the old values which have been deleted, cleared or replaced if `WithAutoClose` is used,
+// the replaced ones kept by `WithHistory` are closed once dropped from the history rather than when replaced,
+// returns the aggregated errors, must be called without holding the map lock
+func (m *Map[K, V]) release(ctx context.Context, events ...Event[K, V]) error {
+	if !m.autoClose {
+		return nil
+	}
+	var errs []error
+	var closed []V
+	closeOnce := func(v V) {
+		for _, c := range closed {
+			if same(c, v) {
+				return
+			}
+		}
+		closed = append(closed, v)
+		if err := closeValue(ctx, v); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	for _, ev := range events {
+		if !ev.Loaded {
+			continue
+		}
+		if ev.Type != EventDelete && ev.Type != EventClear {
+			if m.historySize > 0 || same(ev.OldValue, ev.NewValue) {
+				continue // the replaced one is kept in the history, and closed when dropped from it
+			}
+		}
+		closeOnce(ev.OldValue)
+	}
+	for _, v := range m.takeRetired() {
+		closeOnce(v)
+	}
+	return errors.Join(errs...)
+}
+
+// closeValue closes v if it implements `Shutdowner` or `io.Closer`
+func closeValue(ctx context.Context, v any) error {
+	switch c := v.(type) {
+	case Shutdowner:
+		return c.Shutdown(ctx)
+	case io.Closer:
+		return c.Close()
+	}
+	return nil
+}
+
+// same tells if a and b are the same comparable value, the values of a comparable type holding uncomparable dynamic values
+// in their interface fields(e.g. a struct with an `any` field holding a slice) are never the same instead of panicking
+func same(a, b any) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.ValueOf(a).Comparable() || !reflect.ValueOf(b).Comparable() {
+		return false
+	}
+	return a == b
+}
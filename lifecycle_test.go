@@ -0,0 +1,131 @@
+package inithook_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+type pool struct {
+	name   string
+	closed int
+	err    error
+}
+
+func (p *pool) Close() error {
+	p.closed++
+	return p.err
+}
+
+type server struct {
+	shutdown int
+}
+
+func (s *server) Shutdown(ctx context.Context) error {
+	s.shutdown++
+	return nil
+}
+
+func TestMapWithAutoClose(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithAutoClose[string, any]())
+	db := &pool{name: "db"}
+	m.MustSet(ctx, "db", db)
+	m.MustSet(ctx, "db", db)
+	assert.Equalf(t, 0, db.closed, "same value is not closed")
+	m.MustSet(ctx, "db", &pool{name: "db2"})
+	assert.Equalf(t, 1, db.closed, "replaced value closed")
+
+	srv := &server{}
+	m.MustSet(ctx, "srv", srv)
+	m.MustDelete(ctx, "srv")
+	assert.Equalf(t, 1, srv.shutdown, "deleted value shutdown")
+
+	failed := &pool{name: "failed", err: errors.New("close failed")}
+	other := &pool{name: "other", err: errors.New("close other failed")}
+	m.MustSet(ctx, "failed", failed)
+	m.MustSet(ctx, "other", other)
+	m.MustSet(ctx, "plain", 1)
+	err := m.Clear(ctx)
+	assert.ErrorContainsf(t, err, "close failed", "aggregated errors")
+	assert.ErrorContainsf(t, err, "close other failed", "aggregated errors")
+	assert.Equalf(t, 1, failed.closed, "cleared value closed")
+
+	updated := &pool{name: "updated"}
+	m.MustSet(ctx, "updated", updated)
+	assert.Nilf(t, m.Update(ctx, "updated", func(old any) (any, error) { return &pool{name: "new"}, nil }), "update")
+	assert.Equalf(t, 1, updated.closed, "value replaced by update closed")
+	kept := &pool{name: "kept"}
+	m.MustSet(ctx, "kept", kept)
+	assert.Nilf(t, m.Update(ctx, "kept", func(old any) (any, error) { return old, nil }), "update same")
+	assert.Equalf(t, 0, kept.closed, "same value is not closed by update")
+	swapped := &pool{name: "swapped"}
+	m.MustSet(ctx, "swapped", swapped)
+	assert.Truef(t, m.CompareAndSwap(ctx, "swapped", swapped, &pool{name: "new"}), "compare and swap")
+	assert.Equalf(t, 1, swapped.closed, "value replaced by compare and swap closed")
+	expiring := &pool{name: "expiring"}
+	m.MustSet(ctx, "expiring", expiring)
+	m.MustSetWithTTL(ctx, "expiring", &pool{name: "new"}, time.Hour)
+	assert.Equalf(t, 1, expiring.closed, "value replaced by set with ttl closed")
+
+	popped := &pool{name: "popped"}
+	m.MustSet(ctx, "popped", popped)
+	m.Pop(ctx, "popped")
+	assert.Equalf(t, 0, popped.closed, "popped value is not closed")
+
+	plain := inithook.NewMap[string, *pool]()
+	p := &pool{}
+	plain.MustSet(ctx, "p", p)
+	plain.MustDelete(ctx, "p")
+	assert.Equalf(t, 0, p.closed, "not closed without option")
+}
+
+func TestMapWithAutoCloseHistory(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithAutoClose[string, *pool](), inithook.WithHistory[string, *pool](2))
+	v1, v2, v3, v4 := &pool{name: "v1"}, &pool{name: "v2"}, &pool{name: "v3"}, &pool{name: "v4"}
+	m.MustSet(ctx, "db", v1)
+	m.MustSet(ctx, "db", v2)
+	assert.Equalf(t, 0, v1.closed, "replaced value kept in history is not closed")
+	assert.Nilf(t, m.Rollback(ctx, "db"), "rollback")
+	assert.Samef(t, v1, m.MustGet(ctx, "db"), "rolled back")
+	assert.Equalf(t, 0, v1.closed, "rolled back value is not closed")
+	assert.Equalf(t, 1, v2.closed, "value rolled back over is closed")
+
+	m.MustSet(ctx, "db", v3)
+	m.MustSet(ctx, "db", v4)
+	assert.Equalf(t, 0, v1.closed, "within the history limit")
+	m.MustSet(ctx, "db", &pool{name: "v5"})
+	assert.Equalf(t, 1, v1.closed, "value trimmed out of history is closed")
+	assert.Equalf(t, 0, v3.closed, "kept in history")
+	m.MustDelete(ctx, "db")
+	assert.Equalf(t, 1, v3.closed, "history closed when deleted")
+	assert.Equalf(t, 1, v4.closed, "history closed when deleted")
+
+	m.MustSet(ctx, "cache", v1)
+	m.MustSet(ctx, "cache", v2)
+	m.MustSet(ctx, "cache", v1)
+	m.MustDelete(ctx, "cache")
+	assert.Equalf(t, 2, v1.closed, "closed once though both current and in history")
+	assert.Equalf(t, 2, v2.closed, "history closed when deleted")
+}
+
+func TestMapWithAutoCloseUncomparable(t *testing.T) {
+	ctx := context.Background()
+	type handle struct {
+		pool  *pool
+		extra any
+	}
+	m := inithook.NewMap(inithook.WithAutoClose[string, handle](), inithook.WithHistory[string, handle](1))
+	p := &pool{name: "p"}
+	m.MustSet(ctx, "h", handle{pool: p, extra: []int{1}})
+	assert.NotPanicsf(t, func() {
+		m.MustSet(ctx, "h", handle{pool: p, extra: []int{2}})
+		m.MustSet(ctx, "h", handle{pool: p, extra: map[string]int{}})
+		m.MustDelete(ctx, "h")
+	}, "uncomparable dynamic values are not the same")
+}
@@ -0,0 +1,67 @@
+package inithook
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+)
+
+// LogLevels decides the levels of the lines logged by the logger of `WithLogger`
+type LogLevels struct {
+	// Register is the level of registrations, including sets of new keys
+	Register slog.Level
+	// Override is the level of sets which replace existing instances
+	Override slog.Level
+	// Delete is the level of deletions, clears and evictions
+	Delete slog.Level
+	// Error is the level of failed operations, e.g. conflicts, invalid values and provider errors
+	Error slog.Level
+}
+
+// DefaultLogLevels is the default LogLevels used by `WithLogger`
+var DefaultLogLevels = LogLevels{
+	Register: slog.LevelDebug,
+	Override: slog.LevelInfo,
+	Delete:   slog.LevelDebug,
+	Error:    slog.LevelWarn,
+}
+
+// logEvents logs the mutations of events
+func (m *Map[K, V]) logEvents(events []Event[K, V]) {
+	if m.logger == nil {
+		return
+	}
+	ctx := context.Background()
+	for _, ev := range events {
+		switch {
+		case ev.Type == EventRegister:
+			m.log(ctx, m.logLevels.Register, "inithook: register", ev.Key)
+		case ev.Type == EventSet && ev.Loaded:
+			m.log(ctx, m.logLevels.Override, "inithook: override", ev.Key)
+		case ev.Type == EventSet:
+			m.log(ctx, m.logLevels.Register, "inithook: set", ev.Key)
+		case ev.Type == EventDelete || ev.Type == EventClear:
+			m.log(ctx, m.logLevels.Delete, "inithook: delete", ev.Key)
+		}
+	}
+}
+
+// logError logs the failed operation on key
+func (m *Map[K, V]) logError(ctx context.Context, msg string, key K, err error) {
+	if m.logger == nil {
+		return
+	}
+	m.log(ctx, m.logLevels.Error, msg, key, slog.String("error", err.Error()))
+}
+
+func (m *Map[K, V]) log(ctx context.Context, level slog.Level, msg string, key K, attrs ...slog.Attr) {
+	if !m.logger.Enabled(ctx, level) {
+		return
+	}
+	attrs = append([]slog.Attr{
+		slog.String("registry", m.name),
+		slog.String("key", fmt.Sprint(key)),
+		slog.String("type", typeName[V]()),
+	}, attrs...)
+	m.logger.LogAttrs(ctx, level, msg, attrs...)
+}
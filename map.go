@@ -1,11 +1,14 @@
 package inithook
 
 import (
+	"container/list"
 	"context"
+	"errors"
+	"log/slog"
 	"reflect"
 	"sync"
-
-	"github.com/pkg/errors"
+	"sync/atomic"
+	"time"
 )
 
 var (
@@ -14,38 +17,153 @@ var (
 
 	// ErrAlreadyExists defines already exists error
 	ErrAlreadyExists = errors.New("already exists")
+
+	// ErrInvalidValue defines invalid value error, which returned if a value rejected by validators
+	ErrInvalidValue = errors.New("invalid value")
+
+	// ErrInvalidKey defines invalid key error, which returned if a key rejected by key validators or failed to decode
+	ErrInvalidKey = errors.New("invalid key")
+
+	// ErrSealed defines sealed error, which returned if a sealed(see `Seal`) map is modified
+	ErrSealed = errors.New("sealed")
+
+	// ErrTimeout defines timeout error, `ErrHookTimeout` and provider construction exceeding the ctx deadline wrap it
+	ErrTimeout = errors.New("timeout")
+
+	// ErrCycle defines cycle error, `ErrHookCycle` and alias cycles wrap it
+	ErrCycle = errors.New("cycle")
 )
 
-// Map is a instances map of specified Type
+// Map is a instances map of specified Type,
+// ctx is respected by long operations: ranges stop when ctx is done, while provider construction,
+// default loading and waiting for in-flight computations return `ctx.Err()`
 type Map[K comparable, V any] struct {
-	instances map[K]V
-	lock      sync.RWMutex
+	store Store[K, V]
+	lock  rwLock
+
+	calls     map[K]*call[V] // the in-flight providers and `GetOrCompute`
+	doCalls   map[K]*call[V] // the in-flight `Do`, which never mix with the resolving of key
+	callsLock sync.Mutex
+
+	watchers     map[uint64]func(ev Event[K, V])
+	watcherID    uint64
+	watchersLock sync.RWMutex
+
+	expires map[K]time.Time
+	onEvict func(key K, value V)
+
+	pending     []Event[K, V]
+	pendingLock sync.Mutex
+
+	opts []Option[K, V]
+
+	normalizer func(key K) K
+
+	meta       map[K]*entryMeta
+	recordInfo bool
+
+	validators    []func(ctx context.Context, key K, value V) error
+	keyValidators []func(key K) error
+
+	sealed bool
+
+	autoClose bool
+
+	providers map[K]*provider[V]
+
+	memoized      map[K]*list.Element
+	memoizedOrder *list.List
+	weight        int64
+	weightLimit   int64
+	weigher       func(key K, value V) int64
+
+	defaults      map[K]V
+	defaultsLock  sync.Mutex
+	cacheDefaults bool
+	defaultFunc   func(ctx context.Context, key K) (V, error)
+
+	copyOnRead func(v V) V
+
+	hasher    func(v V) uint64
+	checksums map[K]uint64
+
+	history     map[K][]V
+	historySize int
+	retired     map[K][]V // the instances dropped from the history, closed by release if `WithAutoClose` is used
+
+	aliases     map[K]K
+	hasAliases  atomic.Bool
+	aliasesLock sync.RWMutex
+
+	deprecated         map[K]string
+	hasDeprecated      atomic.Bool
+	deprecatedLock     sync.RWMutex
+	deprecationHandler func(ctx context.Context, key K, message string)
+
+	keyEncoder func(key K) (string, error)
+	keyDecoder func(s string) (K, error)
+
+	valueCodec Codec
+
+	counters counters
+
+	name   string
+	tracer Tracer
+
+	logger    *slog.Logger
+	logLevels LogLevels
 }
 
 // NewMap creates a new map
-func NewMap[K comparable, V any]() *Map[K, V] {
-	return &Map[K, V]{
-		instances: make(map[K]V),
+func NewMap[K comparable, V any](opts ...Option[K, V]) *Map[K, V] {
+	return NewMapWithStore(NewMapStore[K, V](), opts...)
+}
+
+// NewMapWithStore creates a new map backed by store
+func NewMapWithStore[K comparable, V any](store Store[K, V], opts ...Option[K, V]) *Map[K, V] {
+	m := &Map[K, V]{
+		store:     store,
+		opts:      opts,
+		logLevels: DefaultLogLevels,
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 // MustRegister register a V's instance with key, if failed(e.g. already exists) then panic
-func (m *Map[K, V]) MustRegister(ctx context.Context, key K, value V) {
-	err := m.Register(ctx, key, value)
+func (m *Map[K, V]) MustRegister(ctx context.Context, key K, value V, opts ...RegisterOption) {
+	err := m.Register(ctx, key, value, opts...)
 	if err != nil {
 		panic(err)
 	}
 }
 
-// Register register a V's instance with key, if exists then return `ErrAlreadyExists` error(use `errors.Is` to assert)
-func (m *Map[K, V]) Register(ctx context.Context, key K, value V) error {
+// Register register a V's instance with key, if exists then return `ErrAlreadyExists` error(use `errors.Is` to assert),
+// unless `WithOverwrite` is used, the metadata attached by opts can be retrieved by `Describe`
+func (m *Map[K, V]) Register(ctx context.Context, key K, value V, opts ...RegisterOption) error {
+	key = m.key(key)
+	if err := m.validate(ctx, "Register", key, value); err != nil {
+		return err
+	}
+	o := newRegisterOptions(opts)
 	m.lock.Lock()
-	defer m.lock.Unlock()
-	if _, ok := m.instances[key]; ok {
-		return errors.WithMessagef(ErrAlreadyExists, "type %T instance %v", value, key)
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("Register", key)
+	}
+	old, loaded := m.load(key)
+	if m.exists(key) && !o.overwrite {
+		m.lock.Unlock()
+		return m.errAlreadyExists("Register", key)
 	}
-	m.instances[key] = value
-	return nil
+	m.put(key, value)
+	m.describe(key, o, m.registrationInfo())
+	m.lock.Unlock()
+	ev := Event[K, V]{Type: EventRegister, Key: key, OldValue: old, NewValue: value, Loaded: loaded}
+	m.notify(ev)
+	return m.release(ctx, ev)
 }
 
 // MustSet set a V's instance with key, if exists then override, if failed then panic
@@ -56,12 +174,143 @@ func (m *Map[K, V]) MustSet(ctx context.Context, key K, value V) {
 	}
 }
 
-// Set set a V's instance with key, if exists then override
+// Set set a V's instance with key, if exists then override, and the old one is closed if `WithAutoClose` is used
 func (m *Map[K, V]) Set(ctx context.Context, key K, value V) error {
+	key = m.key(key)
+	if err := m.validate(ctx, "Set", key, value); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("Set", key)
+	}
+	old, loaded := m.load(key)
+	m.put(key, value)
+	m.lock.Unlock()
+	ev := Event[K, V]{Type: EventSet, Key: key, OldValue: old, NewValue: value, Loaded: loaded}
+	m.notify(ev)
+	return m.release(ctx, ev)
+}
+
+// GetOrSet returns the existing V's instance of key if present(resolved by its provider if any), otherwise set value with key
+// and returns it, the check and set are done under a single lock acquisition, so concurrent initializers never race
+func (m *Map[K, V]) GetOrSet(ctx context.Context, key K, value V) (V, error) {
+	key = m.key(key)
+	m.lock.RLock()
+	v, ok := m.load(key)
+	_, provided := m.providers[key]
+	m.lock.RUnlock()
+	if ok {
+		return m.copy(v), nil
+	}
+	if provided {
+		if v, ok, err := m.get(ctx, key); ok || err != nil {
+			return v, err
+		}
+	}
+	if err := m.validate(ctx, "GetOrSet", key, value); err != nil {
+		return value, err
+	}
+	m.lock.Lock()
+	if m.exists(key) { // stored or provided meanwhile
+		m.lock.Unlock()
+		return m.GetOrSet(ctx, key, value)
+	}
+	if m.sealed {
+		m.lock.Unlock()
+		return value, m.errSealed("GetOrSet", key)
+	}
+	m.put(key, value)
+	m.lock.Unlock()
+	m.notify(Event[K, V]{Type: EventSet, Key: key, NewValue: value})
+	return m.copy(value), nil
+}
+
+// GetOrCompute returns the existing V's instance of key if present, otherwise construct it by fn and set it with key,
+// fn is invoked at most once per key even under heavy concurrency, concurrent callers wait for and share its result,
+// if fn returns an error nothing is set, and the next call will invoke fn again
+func (m *Map[K, V]) GetOrCompute(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	key = m.key(key)
+	m.lock.RLock()
+	v, ok := m.load(key)
+	m.lock.RUnlock()
+	if ok {
+		return m.copy(v), nil
+	}
+	v, err := m.flight(ctx, key, func(ctx context.Context) (V, error) {
+		m.lock.RLock()
+		v, ok := m.load(key)
+		m.lock.RUnlock()
+		if ok {
+			return v, nil
+		}
+		v, err := fn(ctx)
+		if err != nil {
+			return v, err
+		}
+		return m.GetOrSet(ctx, key, v)
+	})
+	if err != nil {
+		return v, err
+	}
+	return m.copy(v), nil // the result is shared by the concurrent callers
+}
+
+// Update update the V's instance of key by fn under the write lock, fn receives the current instance and returns the new one,
+// if key not found return `ErrNotFound` error(use `errors.Is` to assert), if fn returns an error the instance is kept unchanged,
+// the replaced one is closed if `WithAutoClose` is used
+func (m *Map[K, V]) Update(ctx context.Context, key K, fn func(old V) (V, error)) error {
+	ev, err := m.update(ctx, m.key(key), fn)
+	if err != nil {
+		return err
+	}
+	m.notify(ev)
+	return m.release(ctx, ev)
+}
+
+// update updates the V's instance of key by fn under the write lock, which is released even if fn panics
+func (m *Map[K, V]) update(ctx context.Context, key K, fn func(old V) (V, error)) (Event[K, V], error) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
-	m.instances[key] = value
-	return nil
+	if m.sealed {
+		return Event[K, V]{}, m.errSealed("Update", key)
+	}
+	old, ok := m.load(key)
+	if !ok {
+		return Event[K, V]{}, m.newError("Update", key, ErrNotFound, nil)
+	}
+	value, err := fn(old)
+	if err == nil {
+		err = m.validate(ctx, "Update", key, value)
+	}
+	if err != nil {
+		return Event[K, V]{}, err
+	}
+	m.put(key, value)
+	return Event[K, V]{Type: EventSet, Key: key, OldValue: old, NewValue: value, Loaded: true}, nil
+}
+
+// CompareAndSwap swaps the old and new V's instance of key if the instance stored in the map is equal to old,
+// returns false if new is rejected by validators or the map is sealed, the replaced one is closed if `WithAutoClose` is used
+// and the close error is ignored, NOTE: like `sync.Map`, it panics if the instance stored and old are not comparable
+func (m *Map[K, V]) CompareAndSwap(ctx context.Context, key K, old, new V) bool {
+	key = m.key(key)
+	if m.validate(ctx, "CompareAndSwap", key, new) != nil {
+		return false
+	}
+	m.lock.Lock()
+	v, ok := m.load(key)
+	if m.sealed || !ok || any(v) != any(old) {
+		m.lock.Unlock()
+		return false
+	}
+	m.put(key, new)
+	m.lock.Unlock()
+	ev := Event[K, V]{Type: EventSet, Key: key, OldValue: v, NewValue: new, Loaded: true}
+	m.notify(ev)
+	_ = m.release(ctx, ev) // CompareAndSwap reports only whether swapped
+	return true
 }
 
 // MustDelete delete a V's instance specified by key, if failed then panic
@@ -72,12 +321,62 @@ func (m *Map[K, V]) MustDelete(ctx context.Context, key K) {
 	}
 }
 
-// Delete delete a V's instance specified by key
+// Delete delete a V's instance specified by key, which is closed if `WithAutoClose` is used
 func (m *Map[K, V]) Delete(ctx context.Context, key K) error {
+	key = m.key(key)
 	m.lock.Lock()
-	defer m.lock.Unlock()
-	delete(m.instances, key)
-	return nil
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("Delete", key)
+	}
+	old, loaded := m.load(key)
+	m.remove(key)
+	delete(m.providers, key)
+	m.lock.Unlock()
+	if !loaded {
+		return nil
+	}
+	ev := Event[K, V]{Type: EventDelete, Key: key, OldValue: old, Loaded: true}
+	m.notify(ev)
+	return m.release(ctx, ev)
+}
+
+// Pop atomically get and delete a V's instance specified by key, if not found return `NotFound` error(use `errors.Is` to assert)
+func (m *Map[K, V]) Pop(ctx context.Context, key K) (V, error) {
+	key = m.key(key)
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return *new(V), m.errSealed("Pop", key)
+	}
+	old, loaded := m.load(key)
+	if !loaded {
+		m.lock.Unlock()
+		return old, m.newError("Pop", key, ErrNotFound, nil)
+	}
+	m.remove(key)
+	m.lock.Unlock()
+	m.notify(Event[K, V]{Type: EventDelete, Key: key, OldValue: old, Loaded: true})
+	return old, nil
+}
+
+// Swap atomically set a V's instance with key and returns the previous one if any, loaded tells if key was present,
+// it panics if value is rejected by validators or the map is sealed
+func (m *Map[K, V]) Swap(ctx context.Context, key K, value V) (old V, loaded bool) {
+	key = m.key(key)
+	if err := m.validate(ctx, "Swap", key, value); err != nil {
+		panic(err)
+	}
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		panic(m.errSealed("Swap", key))
+	}
+	old, loaded = m.load(key)
+	m.put(key, value)
+	m.lock.Unlock()
+	m.notify(Event[K, V]{Type: EventSet, Key: key, OldValue: old, NewValue: value, Loaded: loaded})
+	return old, loaded
 }
 
 // MustClear clear all V's instances, if failed then panic
@@ -88,41 +387,166 @@ func (m *Map[K, V]) MustClear(ctx context.Context) {
 	}
 }
 
-// Clear clear all V's instances
+// Clear clear all V's instances, which are closed if `WithAutoClose` is used
 func (m *Map[K, V]) Clear(ctx context.Context) error {
 	m.lock.Lock()
-	defer m.lock.Unlock()
-	m.instances = make(map[K]V)
-	return nil
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("Clear")
+	}
+	events := make([]Event[K, V], 0, m.store.Len())
+	m.each(func(k K, v V) bool {
+		events = append(events, Event[K, V]{Type: EventClear, Key: k, OldValue: v, Loaded: true})
+		return true
+	})
+	m.store.Clear()
+	m.checksums = nil
+	for k, h := range m.history {
+		m.retire(k, h...)
+	}
+	m.history = nil
+	m.memoized, m.memoizedOrder, m.weight = nil, nil, 0
+	m.expires = nil
+	m.meta = nil
+	m.providers = nil
+	m.lock.Unlock()
+	m.notify(events...)
+	return m.release(ctx, events...)
+}
+
+// MustGet get a V's instance by key, if failed(e.g. not found) then panic with the `*RegistryError`
+// naming the type and key, usually used in init where a missing instance is a programming error
+func (m *Map[K, V]) MustGet(ctx context.Context, key K) V {
+	v, err := m.Get(ctx, key)
+	if err != nil {
+		panic(err)
+	}
+	return v
 }
 
-// GetDefault get a V's instance by key, if not found return `NotFound` error(use `errors.Is` to assert)
+// Get get a V's instance by key, if not found return `NotFound` error(use `errors.Is` to assert)
 func (m *Map[K, V]) Get(ctx context.Context, key K) (V, error) {
-	m.lock.RLock()
-	defer m.lock.RUnlock()
-	if v, ok := m.instances[key]; ok {
-		return v, nil
+	m.warnDeprecated(ctx, key)
+	key = m.key(key)
+	v, ok, err := m.get(ctx, key)
+	if err != nil || ok {
+		return v, err
 	}
-	value := *new(V)
-	return value, errors.WithMessagef(ErrNotFound, "type %T instance %v", value, key)
+	return v, m.newError("Get", key, ErrNotFound, nil)
+}
+
+// GetOK get a V's instance by key like `Get`, ok tells if found, it never allocates an error on a miss,
+// for the hot paths where a miss is expected and should be cheap, if the provider of key fails then return false
+func (m *Map[K, V]) GetOK(ctx context.Context, key K) (value V, ok bool) {
+	m.warnDeprecated(ctx, key)
+	v, ok, err := m.get(ctx, m.key(key))
+	return v, ok && err == nil
 }
 
-// GetDefault get a V's instance by key, if not found, then try to returns a default one
-func (m *Map[K, V]) GetDefault(ctx context.Context, key K) (V, error) {
+// get gets the V's instance of the normalized key, resolves its provider if any, ok is false on a miss without an error,
+// err is the error of the provider
+func (m *Map[K, V]) get(ctx context.Context, key K) (value V, ok bool, err error) {
 	m.lock.RLock()
-	defer m.lock.RUnlock()
-	if v, ok := m.instances[key]; ok {
-		return v, nil
+	v, ok := m.load(key)
+	if ok && m.hasher == nil { // fast path
+		m.lock.RUnlock()
+		m.counters.hits.Add(1)
+		return m.copy(v), true, nil
+	}
+	expired := !ok && m.expired(key, time.Now())
+	p := m.providers[key]
+	mutated := ok && m.mutated(key, v)
+	m.lock.RUnlock()
+	if mutated {
+		m.panicMutated(key)
+	}
+	if ok {
+		m.counters.hits.Add(1)
+		return m.copy(v), true, nil
+	}
+	if expired {
+		m.evict(key)
+	}
+	if p != nil {
+		m.counters.hits.Add(1)
+		v, err := m.provide(ctx, key, p)
+		if err == errProviderChanged {
+			return m.get(ctx, key)
+		}
+		if err != nil {
+			return v, false, err
+		}
+		return m.copy(v), true, nil
+	}
+	m.counters.misses.Add(1)
+	return v, false, nil
+}
+
+// GetDefault get a V's instance by key, if not found, then try to returns a default one(see `Default`),
+// defaulted tells if the default path is taken, i.e. the instance is the default one rather than a registered one
+func (m *Map[K, V]) GetDefault(ctx context.Context, key K) (value V, defaulted bool, err error) {
+	m.warnDeprecated(ctx, key)
+	key = m.key(key)
+	v, ok, err := m.get(ctx, key)
+	if err != nil || ok {
+		return v, false, err
 	}
-	return m.Default(ctx, key)
+	m.counters.defaults.Add(1)
+	v, err = m.Default(ctx, key)
+	return v, true, err
 }
 
-// Default returns V's default value if it implement the `DefaultLoader` or `Default`, otherwise return `Zero[V]()`
+// Default returns the default value loaded by the func of `WithDefaultFunc` if used,
+// otherwise V's default value if it implement the `DefaultLoaderK`, `DefaultLoader` or `Default`, otherwise return `Zero[V]()`,
+// the default value loaded successfully is memoized per key if `WithDefaultCache` is used
 func (m *Map[K, V]) Default(ctx context.Context, key K) (V, error) {
+	key = m.key(key)
+	if err := ctx.Err(); err != nil {
+		return *new(V), err
+	}
+	if m.cacheDefaults {
+		m.defaultsLock.Lock()
+		v, ok := m.defaults[key]
+		m.defaultsLock.Unlock()
+		if ok {
+			return v, nil
+		}
+	}
+	ctx, end := m.trace(ctx, SpanDefault, key)
+	var value V
+	var err error
+	if m.defaultFunc != nil {
+		value, err = m.defaultFunc(ctx, key)
+	} else {
+		value, err = defaultValue[K, V](ctx, key)
+	}
+	end(err)
+	if err == nil && m.cacheDefaults {
+		m.defaultsLock.Lock()
+		if m.defaults == nil {
+			m.defaults = make(map[K]V)
+		}
+		m.defaults[key] = value
+		m.defaultsLock.Unlock()
+	}
+	return value, err
+}
+
+// ForgetDefaults drops the default values memoized by `WithDefaultCache`, so they are loaded again
+func (m *Map[K, V]) ForgetDefaults(ctx context.Context) {
+	m.defaultsLock.Lock()
+	m.defaults = nil
+	m.defaultsLock.Unlock()
+}
+
+// defaultValue returns V's default value of key, shared by all map variants
+func defaultValue[K comparable, V any](ctx context.Context, key K) (V, error) {
 	var value = Zero[V]()
+	if defLoader, ok := any(value).(DefaultLoaderK[K, V]); ok {
+		return defLoader.LoadDefault(ctx, key)
+	}
 	if defLoader, ok := any(value).(DefaultLoader[V]); ok {
 		return defLoader.LoadDefault(ctx, key)
-
 	}
 	if def, ok := any(value).(Default[V]); ok {
 		return def.Default(), nil
@@ -130,84 +554,296 @@ func (m *Map[K, V]) Default(ctx context.Context, key K) (V, error) {
 	return value, nil
 }
 
-// Has tells if map has key
+// Has tells if map has key, including keys registered by `RegisterProvider` which are not resolved yet
 func (m *Map[K, V]) Has(ctx context.Context, key K) bool {
+	key = m.key(key)
 	m.lock.RLock()
-	defer m.lock.RUnlock()
-	_, ok := m.instances[key]
+	ok := m.exists(key)
+	m.lock.RUnlock()
 	return ok
 }
 
-// Range calls f sequentially for each key and value present in the map. If f returns false, range stops the iteration.
+// Len returns the number of V's instances, including the ones registered by `RegisterProvider` which are not resolved yet
+func (m *Map[K, V]) Len(ctx context.Context) int {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	var n int
+	if len(m.expires) == 0 {
+		n = m.store.Len()
+	} else {
+		m.each(func(K, V) bool {
+			n++
+			return true
+		})
+	}
+	for k := range m.providers {
+		if _, ok := m.load(k); !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// IsEmpty tells if map has no V's instance
+func (m *Map[K, V]) IsEmpty(ctx context.Context) bool {
+	return m.Len(ctx) == 0
+}
+
+// Range calls f sequentially for each key and value present in the map. If f returns false or ctx is done, range stops the iteration.
+// NOTE: f is called with the read lock held, so it must not mutate the map which deadlocks, use `RangeSnapshot` instead
 func (m *Map[K, V]) Range(ctx context.Context, fn func(key, value any) bool) {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	for k, v := range m.instances {
-		shouldContinue := fn(k, v)
-		if !shouldContinue {
+	m.each(func(k K, v V) bool {
+		return ctx.Err() == nil && fn(k, v)
+	})
+}
+
+// RangeTyped calls f sequentially for each key and value present in the map with typed arguments. If f returns false or ctx is done, range stops the iteration.
+// NOTE: f is called with the read lock held, so it must not mutate the map which deadlocks, use `RangeSnapshot` instead
+func (m *Map[K, V]) RangeTyped(ctx context.Context, fn func(key K, value V) bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	m.each(func(k K, v V) bool {
+		return ctx.Err() == nil && fn(k, v)
+	})
+}
+
+// RangeSnapshot copies all items under the read lock, then calls f sequentially for each of them without holding the lock,
+// so f can safely call back into the map(e.g. Register, Delete), the mutations made during the iteration are not visited.
+// If f returns false or ctx is done, range stops the iteration.
+func (m *Map[K, V]) RangeSnapshot(ctx context.Context, fn func(key K, value V) bool) {
+	type item struct {
+		key   K
+		value V
+	}
+	m.lock.RLock()
+	items := make([]item, 0, m.store.Len())
+	m.each(func(k K, v V) bool {
+		items = append(items, item{key: k, value: m.copy(v)})
+		return true
+	})
+	m.lock.RUnlock()
+	for _, it := range items {
+		if ctx.Err() != nil || !fn(it.key, it.value) {
 			return
 		}
 	}
 }
 
-// Keys return all keys
+// Keys return all keys in a new slice owned by the caller, including keys registered by `RegisterProvider` which are not resolved yet,
+// see `AppendKeys` to reuse a slice
 func (m *Map[K, V]) Keys(ctx context.Context) []K {
+	return m.AppendKeys(ctx, nil)
+}
+
+// AppendKeys appends all keys to dst and returns the extended slice like `Keys`, so hot paths can reuse dst[:0],
+// it doesn't allocate if dst has enough capacity and the map uses the default `Store`
+func (m *Map[K, V]) AppendKeys(ctx context.Context, dst []K) []K {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	var keys []K
-	for k := range m.instances {
-		keys = append(keys, k)
+	if s, ok := m.store.(mapStore[K, V]); ok && len(m.expires) == 0 { // avoids the allocation of closures
+		for k := range s {
+			dst = append(dst, k)
+		}
+	} else {
+		dst = m.appendKeys(dst)
+	}
+	for k := range m.providers {
+		if _, ok := m.load(k); !ok {
+			dst = append(dst, k)
+		}
 	}
-	return keys
+	return dst
 }
 
-// Values return all values
+// Values return all values in a new slice owned by the caller, the values themselves are copied only if `WithCopyOnRead` is used,
+// see `AppendValues` to reuse a slice
 func (m *Map[K, V]) Values(ctx context.Context) []V {
+	return m.AppendValues(ctx, nil)
+}
+
+// AppendValues appends all values to dst and returns the extended slice like `Values`, so hot paths can reuse dst[:0],
+// it doesn't allocate if dst has enough capacity, the map uses the default `Store` and `WithCopyOnRead` is not used
+func (m *Map[K, V]) AppendValues(ctx context.Context, dst []V) []V {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	var values []V
-	for _, v := range m.instances {
-		values = append(values, v)
+	if s, ok := m.store.(mapStore[K, V]); ok && len(m.expires) == 0 { // avoids the allocation of closures
+		for _, v := range s {
+			dst = append(dst, m.copy(v))
+		}
+		return dst
 	}
-	return values
+	return m.appendValues(dst)
+}
+
+// appendKeys appends the keys of V's instances which are not expired to dst, must be called with lock held
+func (m *Map[K, V]) appendKeys(dst []K) []K {
+	m.each(func(k K, _ V) bool {
+		dst = append(dst, k)
+		return true
+	})
+	return dst
+}
+
+// appendValues appends the V's instances which are not expired to dst, must be called with lock held
+func (m *Map[K, V]) appendValues(dst []V) []V {
+	m.each(func(_ K, v V) bool {
+		dst = append(dst, m.copy(v))
+		return true
+	})
+	return dst
 }
 
 // Map return map with all items
 func (m *Map[K, V]) Map(ctx context.Context) map[K]V {
 	m.lock.RLock()
 	defer m.lock.RUnlock()
-	kvs := make(map[K]V, len(m.instances))
-	for k, v := range m.instances {
-		kvs[k] = v
-	}
+	kvs := make(map[K]V, m.store.Len())
+	m.each(func(k K, v V) bool {
+		kvs[k] = m.copy(v)
+		return true
+	})
 	return kvs
 }
 
-// DefaultLoader load default instance of V according to key
+// key returns the normalized key, which is resolved if it's an alias
+func (m *Map[K, V]) key(key K) K {
+	if m.normalizer != nil {
+		key = m.normalizer(key)
+	}
+	if m.hasAliases.Load() {
+		m.aliasesLock.RLock()
+		key = m.resolveAlias(key)
+		m.aliasesLock.RUnlock()
+	}
+	return key
+}
+
+// keys returns values keyed by normalized and resolved keys
+func (m *Map[K, V]) keys(values map[K]V) map[K]V {
+	if m.normalizer == nil && !m.hasAliases.Load() {
+		return values
+	}
+	normalized := make(map[K]V, len(values))
+	for k, v := range values {
+		normalized[m.key(k)] = v
+	}
+	return normalized
+}
+
+// load returns the V's instance of key which is not expired, must be called with lock held
+func (m *Map[K, V]) load(key K) (V, bool) {
+	v, ok := m.store.Load(key)
+	if ok && len(m.expires) > 0 && m.expired(key, time.Now()) { // avoids time.Now on the hot path
+		return *new(V), false
+	}
+	return v, ok
+}
+
+// put stores the V's instance with key without expiration, and keeps the replaced one in the history if `WithHistory` is used,
+// must be called with write lock held
+func (m *Map[K, V]) put(key K, value V) {
+	m.keepHistory(key)
+	m.forget(key)
+	m.store.Store(key, value)
+	m.checksum(key, value)
+	if m.expires != nil {
+		delete(m.expires, key)
+	}
+}
+
+// remove deletes the V's instance of key, must be called with write lock held
+func (m *Map[K, V]) remove(key K) {
+	m.store.Delete(key)
+	m.cleanup(key)
+}
+
+// cleanup drops the per-key state of key(e.g. the expiration, checksum, history and metadata) whose instance is gone,
+// must be called with write lock held
+func (m *Map[K, V]) cleanup(key K) {
+	delete(m.checksums, key)
+	m.retire(key, m.history[key]...)
+	delete(m.history, key)
+	m.forget(key)
+	if m.expires != nil {
+		delete(m.expires, key)
+	}
+	if m.meta != nil {
+		delete(m.meta, key)
+	}
+}
+
+// each calls fn for each V's instance which is not expired, must be called with lock held
+func (m *Map[K, V]) each(fn func(key K, value V) bool) {
+	if len(m.expires) == 0 {
+		m.store.Range(fn)
+		return
+	}
+	now := time.Now()
+	m.store.Range(func(k K, v V) bool {
+		if m.expired(k, now) {
+			return true
+		}
+		return fn(k, v)
+	})
+}
+
+// DefaultLoader load default instance of V according to key, see `DefaultLoaderK` for a typed key
 type DefaultLoader[V any] interface {
 	LoadDefault(ctx context.Context, key any) (V, error)
 }
 
+// DefaultLoaderK load default instance of V according to the typed key of a Map[K, V],
+// which is preferred to `DefaultLoader` by `Map.Default`
+type DefaultLoaderK[K comparable, V any] interface {
+	LoadDefault(ctx context.Context, key K) (V, error)
+}
+
 // Default giving a type a useful default value.
 type Default[V any] interface {
 	Default() V
 }
 
-// Zero create a new V's instance, and New will indirect reflect.Ptr recursively to ensure not return nil pointer
+// Zero create a new V's instance, and New will indirect reflect.Ptr recursively to ensure not return nil pointer,
+// and maps, slices and channels are initialized(empty and unbuffered) to ensure not return nil ones which panic on writes
 func Zero[V any]() V {
-	var level int
 	typ := reflect.TypeOf(new(V)).Elem()
+	if typ.Kind() == reflect.Interface {
+		return *new(V)
+	}
+	return zeroOf(typ).Interface().(V)
+}
+
+// ZeroOf is the non-generic `Zero` of typ, returns nil if typ is nil or an interface
+func ZeroOf(typ reflect.Type) any {
+	if typ == nil || typ.Kind() == reflect.Interface {
+		return nil
+	}
+	return zeroOf(typ).Interface()
+}
+
+// zeroOf returns the zero value of typ, with pointers, maps, slices and channels initialized
+func zeroOf(typ reflect.Type) reflect.Value {
+	var level int
 	for ; typ.Kind() == reflect.Ptr; typ = typ.Elem() {
 		level++
 	}
-	if level == 0 {
-		return *new(V)
+	var value reflect.Value
+	switch typ.Kind() {
+	case reflect.Map:
+		value = reflect.MakeMap(typ)
+	case reflect.Slice:
+		value = reflect.MakeSlice(typ, 0, 0)
+	case reflect.Chan: // directional channels are converted from a bidirectional one
+		value = reflect.MakeChan(reflect.ChanOf(reflect.BothDir, typ.Elem()), 0).Convert(typ)
+	default:
+		value = reflect.Zero(typ)
 	}
-	value := reflect.Zero(typ)
 	for i := 0; i < level; i++ {
 		p := reflect.New(value.Type())
 		p.Elem().Set(value)
 		value = p
 	}
-	return value.Interface().(V)
+	return value
 }
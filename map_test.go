@@ -1,21 +1,71 @@
 package inithook_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/ccmonky/inithook"
-	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestError(t *testing.T) {
-	err := errors.WithMessagef(inithook.ErrNotFound, "%d:%d", 1, 3)
+	err := fmt.Errorf("%d:%d: %w", 1, 3, inithook.ErrNotFound)
 	if !errors.Is(err, inithook.ErrNotFound) {
 		t.Fatal("should is")
 	}
 }
 
+func TestRegistryError(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithRegistrationInfo[string, io.Reader](), inithook.WithRejectNil[string, io.Reader]())
+	m.MustRegister(ctx, "stdin", strings.NewReader(""))
+	err := m.Register(ctx, "stdin", strings.NewReader(""))
+	var regErr *inithook.RegistryError
+	assert.Truef(t, errors.As(err, &regErr), "should be registry error: %T", err)
+	assert.Equalf(t, "Register", regErr.Op, "op")
+	assert.Equalf(t, "stdin", regErr.Key, "key")
+	assert.Equalf(t, "io.Reader", regErr.TypeName, "type name of interface")
+	assert.Containsf(t, regErr.Origin, "map_test.go:", "origin")
+	assert.Containsf(t, regErr.Caller, "map_test.go:", "caller")
+	assert.Equalf(t, inithook.ErrAlreadyExists, regErr.Err, "sentinel")
+
+	err = m.Set(ctx, "nil", nil)
+	assert.Truef(t, errors.As(err, &regErr), "should be registry error: %T", err)
+	assert.Equalf(t, "Set", regErr.Op, "op")
+	assert.Truef(t, errors.Is(err, inithook.ErrInvalidValue), "sentinel")
+	assert.NotNilf(t, regErr.Cause, "cause of validator")
+
+	_, err = m.Get(ctx, "missing")
+	assert.Truef(t, errors.As(err, &regErr), "should be registry error: %T", err)
+	assert.Equalf(t, "Get", regErr.Op, "op")
+	assert.Equalf(t, "type io.Reader instance missing: not found", err.Error(), "message")
+	assert.Nilf(t, regErr.StackTrace(), "no stack by default")
+
+	inithook.CaptureStacks(true)
+	defer inithook.CaptureStacks(false)
+	_, err = m.Get(ctx, "missing")
+	assert.Truef(t, errors.As(err, &regErr), "should be registry error: %T", err)
+	assert.NotEmptyf(t, regErr.StackTrace(), "stack captured")
+	assert.Equalf(t, "type io.Reader instance missing: not found", fmt.Sprintf("%v", err), "%%v")
+	assert.Containsf(t, fmt.Sprintf("%+v", err), "inithook_test.TestRegistryError", "%%+v prints stack")
+}
+
 func TestMap(t *testing.T) {
 	m := inithook.NewMap[int, string]()
 	ctx := context.Background()
@@ -25,8 +75,1452 @@ func TestMap(t *testing.T) {
 	assert.Nilf(t, err, "set one")
 	assert.ElementsMatchf(t, []int{1, 2}, m.Keys(ctx), "keys")
 	assert.ElementsMatchf(t, []string{"one", "two"}, m.Values(ctx), "value")
+	assert.Equalf(t, 2, m.Len(ctx), "len")
+	assert.Falsef(t, m.IsEmpty(ctx), "not empty")
 	assert.Equalf(t, map[int]string{
 		1: "one",
 		2: "two",
 	}, m.Map(ctx), "map")
 }
+
+func TestMapMustGet(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustRegister(ctx, "a", 1)
+	assert.Equalf(t, 1, m.MustGet(ctx, "a"), "must get")
+	assert.PanicsWithErrorf(t, "type int instance b: not found", func() { m.MustGet(ctx, "b") }, "must get missing")
+	assert.Equalf(t, 1, inithook.Must(m.Get(ctx, "a")), "must")
+	assert.PanicsWithErrorf(t, "type int instance b: not found", func() { inithook.Must(m.Get(ctx, "b")) }, "must missing")
+}
+
+func TestMapGetOK(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustSet(ctx, "a", 1)
+	v, ok := m.GetOK(ctx, "a")
+	assert.Truef(t, ok, "found")
+	assert.Equalf(t, 1, v, "found")
+	v, ok = m.GetOK(ctx, "b")
+	assert.Falsef(t, ok, "miss")
+	assert.Equalf(t, 0, v, "zero on miss")
+	m.MustRegisterProvider(ctx, "p", func(ctx context.Context) (int, error) { return 2, nil })
+	m.MustRegisterProvider(ctx, "bad", func(ctx context.Context) (int, error) { return 0, errors.New("failed") })
+	v, ok = m.GetOK(ctx, "p")
+	assert.Truef(t, ok && v == 2, "provided")
+	_, ok = m.GetOK(ctx, "bad")
+	assert.Falsef(t, ok, "provider failed")
+	m.MustSetWithTTL(ctx, "ttl", 3, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	_, ok = m.GetOK(ctx, "ttl")
+	assert.Falsef(t, ok, "expired")
+	stats := m.Stats(ctx)
+	assert.Equalf(t, uint64(3), stats.Hits, "hits including the failed provider")
+	assert.Equalf(t, uint64(2), stats.Misses, "misses")
+	assert.Zerof(t, testing.AllocsPerRun(100, func() { m.GetOK(ctx, "b") }), "no allocation on miss")
+}
+
+func TestMapAppendKeysValues(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustSet(ctx, "a", 1)
+	m.MustSet(ctx, "b", 2)
+	m.MustRegisterProvider(ctx, "p", func(ctx context.Context) (int, error) { return 3, nil })
+	keys := m.AppendKeys(ctx, []string{"x"})
+	assert.ElementsMatchf(t, []string{"x", "a", "b", "p"}, keys, "appended keys")
+	values := m.AppendValues(ctx, nil)
+	assert.ElementsMatchf(t, []int{1, 2}, values, "appended values")
+
+	keys, values = make([]string, 0, 8), make([]int, 0, 8)
+	assert.Zerof(t, testing.AllocsPerRun(100, func() {
+		keys = m.AppendKeys(ctx, keys[:0])
+		values = m.AppendValues(ctx, values[:0])
+	}), "reused buffers")
+	assert.Lenf(t, keys, 3, "keys")
+
+	keys[0] = "mutated"
+	assert.NotContainsf(t, m.Keys(ctx), "mutated", "keys are copies")
+	ttl := inithook.NewMap[string, int]()
+	ttl.MustSetWithTTL(ctx, "expired", 1, time.Nanosecond)
+	ttl.MustSet(ctx, "kept", 2)
+	time.Sleep(time.Millisecond)
+	assert.Equalf(t, []string{"kept"}, ttl.AppendKeys(ctx, nil), "expired skipped")
+	assert.Equalf(t, []int{2}, ttl.AppendValues(ctx, nil), "expired skipped")
+}
+
+func TestMapGetOrSet(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	results := make([]int, 100)
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := m.GetOrSet(ctx, "key", i)
+			assert.Nilf(t, err, "get or set %d", i)
+			results[i] = v
+		}(i)
+	}
+	wg.Wait()
+	stored, err := m.Get(ctx, "key")
+	assert.Nilf(t, err, "get")
+	for i, v := range results {
+		assert.Equalf(t, stored, v, "result %d", i)
+	}
+	v, err := m.GetOrSet(ctx, "other", 1)
+	assert.Nilf(t, err, "get or set other")
+	assert.Equalf(t, 1, v, "other")
+
+	m.MustRegisterProvider(ctx, "provided", func(ctx context.Context) (int, error) { return 2, nil })
+	v, err = m.GetOrSet(ctx, "provided", 3)
+	assert.Nilf(t, err, "get or set provided")
+	assert.Equalf(t, 2, v, "resolved by provider rather than set")
+	assert.Equalf(t, 2, m.MustGet(ctx, "provided"), "provided")
+
+	var validated *inithook.Map[string, int]
+	validated = inithook.NewMap(inithook.WithValidator(func(ctx context.Context, key string, value int) error {
+		if validated.Has(ctx, "banned") {
+			return errors.New("banned")
+		}
+		return nil
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		v, err = validated.GetOrSet(ctx, "key", 1)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("validator touching the map deadlocks")
+	}
+	assert.Nilf(t, err, "validated get or set")
+	assert.Equalf(t, 1, v, "validated")
+}
+
+func TestMapGetOrCompute(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	ctx := context.Background()
+	var calls int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := m.GetOrCompute(ctx, "key", func(ctx context.Context) (int, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(10 * time.Millisecond)
+				return 1, nil
+			})
+			assert.Nilf(t, err, "get or compute")
+			assert.Equalf(t, 1, v, "value")
+		}()
+	}
+	wg.Wait()
+	assert.Equalf(t, int32(1), atomic.LoadInt32(&calls), "calls")
+
+	_, err := m.GetOrCompute(ctx, "fail", func(ctx context.Context) (int, error) {
+		return 0, errors.New("construct failed")
+	})
+	assert.NotNilf(t, err, "should fail")
+	assert.Falsef(t, m.Has(ctx, "fail"), "failed value should not be set")
+	v, err := m.GetOrCompute(ctx, "fail", func(ctx context.Context) (int, error) {
+		return 2, nil
+	})
+	assert.Nilf(t, err, "retry")
+	assert.Equalf(t, 2, v, "retry value")
+}
+
+func TestMapDo(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	ctx := context.Background()
+	var calls, shared int32
+	release := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, s, err := m.Do(ctx, "key", func(ctx context.Context) (int, error) {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return 1, nil
+			})
+			assert.Nilf(t, err, "do")
+			assert.Equalf(t, 1, v, "value")
+			if s {
+				atomic.AddInt32(&shared, 1)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equalf(t, int32(1), atomic.LoadInt32(&calls), "deduplicated")
+	assert.Equalf(t, int32(10), atomic.LoadInt32(&shared), "shared by all")
+	assert.Falsef(t, m.Has(ctx, "key"), "not stored")
+
+	v, s, err := m.Do(ctx, "key", func(ctx context.Context) (int, error) { return 2, nil }, inithook.StoreResult())
+	assert.Nilf(t, err, "do and store")
+	assert.Equalf(t, 2, v, "value")
+	assert.Falsef(t, s, "not shared")
+	assert.Equalf(t, 2, m.MustGet(ctx, "key"), "stored")
+	v, _, _ = m.Do(ctx, "key", func(ctx context.Context) (int, error) { return 3, nil })
+	assert.Equalf(t, 3, v, "executed even if exists")
+	assert.Equalf(t, 2, m.MustGet(ctx, "key"), "kept")
+
+	_, _, err = m.Do(ctx, "fail", func(ctx context.Context) (int, error) {
+		return 0, errors.New("do failed")
+	}, inithook.StoreResult())
+	assert.NotNilf(t, err, "should fail")
+	assert.Falsef(t, m.Has(ctx, "fail"), "failed result should not be stored")
+
+	started, release := make(chan struct{}), make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Do(ctx, "provided", func(ctx context.Context) (int, error) {
+			close(started)
+			<-release
+			return -1, nil
+		})
+	}()
+	<-started
+	m.MustRegisterProvider(ctx, "provided", func(ctx context.Context) (int, error) { return 4, nil })
+	assert.Equalf(t, 4, m.MustGet(ctx, "provided"), "provider is not mixed with do")
+	v, err = m.GetOrCompute(ctx, "provided", func(ctx context.Context) (int, error) { return 5, nil })
+	assert.Nilf(t, err, "get or compute during do")
+	assert.Equalf(t, 4, v, "get or compute is not mixed with do")
+	m.MustDelete(ctx, "provided")
+	v, err = m.GetOrCompute(ctx, "provided", func(ctx context.Context) (int, error) { return 5, nil })
+	assert.Nilf(t, err, "compute during do")
+	assert.Equalf(t, 5, v, "computed during do")
+	assert.Truef(t, m.Has(ctx, "provided"), "computed is stored")
+	close(release)
+	<-done
+}
+
+func TestMapUpdate(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	ctx := context.Background()
+	err := m.Update(ctx, "counter", func(old int) (int, error) { return old + 1, nil })
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "update not found")
+	m.MustSet(ctx, "counter", 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := m.Update(ctx, "counter", func(old int) (int, error) { return old + 1, nil })
+			assert.Nilf(t, err, "update")
+		}()
+	}
+	wg.Wait()
+	v, _ := m.Get(ctx, "counter")
+	assert.Equalf(t, 100, v, "counter")
+	err = m.Update(ctx, "counter", func(old int) (int, error) { return 0, errors.New("update failed") })
+	assert.NotNilf(t, err, "update failed")
+	v, _ = m.Get(ctx, "counter")
+	assert.Equalf(t, 100, v, "counter should be unchanged")
+	assert.Panicsf(t, func() {
+		m.Update(ctx, "counter", func(old int) (int, error) { panic("update panic") })
+	}, "fn panics")
+	assert.Nilf(t, m.Set(ctx, "counter", 1), "lock released after panic")
+}
+
+func TestMapCompareAndSwap(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	ctx := context.Background()
+	assert.Falsef(t, m.CompareAndSwap(ctx, "key", 0, 1), "swap not found")
+	m.MustSet(ctx, "key", 1)
+	assert.Falsef(t, m.CompareAndSwap(ctx, "key", 0, 2), "swap mismatch")
+	assert.Truef(t, m.CompareAndSwap(ctx, "key", 1, 2), "swap")
+	v, _ := m.Get(ctx, "key")
+	assert.Equalf(t, 2, v, "swapped")
+}
+
+func TestMapWatch(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	ctx, cancel := context.WithCancel(context.Background())
+	var events []inithook.Event[string, int]
+	stop := m.Watch(ctx, func(ev inithook.Event[string, int]) {
+		events = append(events, ev)
+	})
+	defer stop()
+	m.MustRegister(ctx, "one", 1)
+	m.MustSet(ctx, "one", 11)
+	m.MustSet(ctx, "two", 2)
+	m.MustDelete(ctx, "one")
+	m.MustClear(ctx)
+	assert.Equalf(t, []inithook.Event[string, int]{
+		{Type: inithook.EventRegister, Key: "one", NewValue: 1},
+		{Type: inithook.EventSet, Key: "one", OldValue: 1, NewValue: 11, Loaded: true},
+		{Type: inithook.EventSet, Key: "two", NewValue: 2},
+		{Type: inithook.EventDelete, Key: "one", OldValue: 11, Loaded: true},
+		{Type: inithook.EventClear, Key: "two", OldValue: 2, Loaded: true},
+	}, events, "events")
+
+	stop()
+	m.MustSet(ctx, "three", 3)
+	assert.Lenf(t, events, 5, "events after cancel")
+
+	var count int32
+	m.Watch(ctx, func(ev inithook.Event[string, int]) {
+		atomic.AddInt32(&count, 1)
+	})
+	m.MustSet(ctx, "four", 4)
+	cancel()
+	assert.Eventuallyf(t, func() bool {
+		before := atomic.LoadInt32(&count)
+		m.MustSet(ctx, "five", 5)
+		return atomic.LoadInt32(&count) == before
+	}, time.Second, 10*time.Millisecond, "ctx done should unsubscribe")
+
+	long, cancelLong := context.WithCancel(context.Background())
+	defer cancelLong()
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		m.Watch(long, func(ev inithook.Event[string, int]) {})()
+	}
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqualf(t, runtime.NumGoroutine(), before, "cancel should end the goroutines watching ctx")
+}
+
+type countingStore struct {
+	store  inithook.Store[string, int]
+	stores int
+}
+
+func (s *countingStore) Load(key string) (int, bool)               { return s.store.Load(key) }
+func (s *countingStore) Delete(key string)                         { s.store.Delete(key) }
+func (s *countingStore) Clear()                                    { s.store.Clear() }
+func (s *countingStore) Len() int                                  { return s.store.Len() }
+func (s *countingStore) Range(fn func(key string, value int) bool) { s.store.Range(fn) }
+
+func (s *countingStore) Store(key string, value int) {
+	s.stores++
+	s.store.Store(key, value)
+}
+
+func TestMapWithStore(t *testing.T) {
+	store := &countingStore{store: inithook.NewMapStore[string, int]()}
+	m := inithook.NewMapWithStore[string, int](store)
+	ctx := context.Background()
+	m.MustRegister(ctx, "one", 1)
+	m.MustSet(ctx, "two", 2)
+	assert.Equalf(t, 2, store.stores, "stores")
+	assert.Equalf(t, 2, store.Len(), "len")
+	v, err := m.Get(ctx, "one")
+	assert.Nilf(t, err, "get")
+	assert.Equalf(t, 1, v, "one")
+	m.MustClear(ctx)
+	assert.Equalf(t, 0, store.Len(), "cleared")
+}
+
+func TestMapBatch(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	ctx := context.Background()
+	err := m.RegisterMany(ctx, map[string]int{"one": 1, "two": 2})
+	assert.Nilf(t, err, "register many")
+	err = m.RegisterMany(ctx, map[string]int{"two": 2, "three": 3})
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "register many conflict")
+	assert.Falsef(t, m.Has(ctx, "three"), "register many is all-or-nothing")
+	err = m.SetMany(ctx, map[string]int{"two": 22, "three": 3})
+	assert.Nilf(t, err, "set many")
+	assert.Equalf(t, map[string]int{"one": 1, "two": 22}, m.GetMany(ctx, []string{"one", "two", "four"}), "get many")
+	err = m.DeleteMany(ctx, []string{"one", "three", "four"})
+	assert.Nilf(t, err, "delete many")
+	assert.Equalf(t, map[string]int{"two": 22}, m.Map(ctx), "after delete many")
+}
+
+func TestMapTx(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	ctx := context.Background()
+	m.MustSet(ctx, "old", 1)
+	var events []inithook.Event[string, int]
+	m.Watch(ctx, func(ev inithook.Event[string, int]) {
+		events = append(events, ev)
+	})
+	err := m.Tx(ctx, func(tx inithook.Txn[string, int]) error {
+		if err := tx.Register(ctx, "new", 2); err != nil {
+			return err
+		}
+		assert.Truef(t, tx.Has(ctx, "new"), "tx sees staged register")
+		if err := tx.Delete(ctx, "old"); err != nil {
+			return err
+		}
+		_, err := tx.Get(ctx, "old")
+		assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "tx sees staged delete")
+		return nil
+	})
+	assert.Nilf(t, err, "tx")
+	assert.Equalf(t, map[string]int{"new": 2}, m.Map(ctx), "committed")
+	assert.Equalf(t, []inithook.Event[string, int]{
+		{Type: inithook.EventRegister, Key: "new", NewValue: 2},
+		{Type: inithook.EventDelete, Key: "old", OldValue: 1, Loaded: true},
+	}, events, "events")
+
+	err = m.Tx(ctx, func(tx inithook.Txn[string, int]) error {
+		if err := tx.Set(ctx, "other", 3); err != nil {
+			return err
+		}
+		return tx.Register(ctx, "new", 4)
+	})
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "tx conflict")
+	assert.Equalf(t, map[string]int{"new": 2}, m.Map(ctx), "rolled back")
+
+	assert.Panicsf(t, func() {
+		m.Tx(ctx, func(tx inithook.Txn[string, int]) error {
+			tx.Set(ctx, "staged", 5)
+			panic("tx panic")
+		})
+	}, "fn panics")
+	assert.Equalf(t, map[string]int{"new": 2}, m.Map(ctx), "discarded after panic")
+	assert.Nilf(t, m.Set(ctx, "after", 6), "lock released after panic")
+
+	m.MustRegisterProvider(ctx, "provided", func(ctx context.Context) (int, error) { return 7, nil })
+	err = m.Tx(ctx, func(tx inithook.Txn[string, int]) error {
+		return tx.Register(ctx, "provided", 8)
+	})
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "tx register over provider")
+	assert.Nilf(t, m.Tx(ctx, func(tx inithook.Txn[string, int]) error {
+		return tx.Delete(ctx, "provided")
+	}), "tx delete provider")
+	assert.Falsef(t, m.Has(ctx, "provided"), "provider deleted")
+	_, err = m.Get(ctx, "provided")
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "provider does not come back")
+}
+
+type cloneableConfig struct {
+	Tags []string
+}
+
+func (c *cloneableConfig) Clone() *cloneableConfig {
+	return &cloneableConfig{Tags: append([]string(nil), c.Tags...)}
+}
+
+func TestMapSnapshotRestore(t *testing.T) {
+	m := inithook.NewMap[string, *cloneableConfig]()
+	ctx := context.Background()
+	m.MustSet(ctx, "one", &cloneableConfig{Tags: []string{"a"}})
+	m.MustSet(ctx, "two", &cloneableConfig{Tags: []string{"b"}})
+	snapshot := m.Snapshot(ctx)
+
+	one, _ := m.Get(ctx, "one")
+	one.Tags[0] = "mutated"
+	assert.Equalf(t, "a", snapshot["one"].Tags[0], "snapshot is deep copied")
+	m.MustDelete(ctx, "two")
+	m.MustSet(ctx, "three", &cloneableConfig{})
+
+	err := m.Restore(ctx, snapshot)
+	assert.Nilf(t, err, "restore")
+	assert.ElementsMatchf(t, []string{"one", "two"}, m.Keys(ctx), "restored keys")
+	one, _ = m.Get(ctx, "one")
+	assert.Equalf(t, []string{"a"}, one.Tags, "restored value")
+}
+
+func TestMapWithCopyOnRead(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithCopyOnRead[string, *cloneableConfig](nil))
+	m.MustSet(ctx, "one", &cloneableConfig{Tags: []string{"a"}})
+	one, _ := m.Get(ctx, "one")
+	one.Tags[0] = "mutated"
+	m.GetMany(ctx, []string{"one"})["one"].Tags[0] = "mutated"
+	m.Values(ctx)[0].Tags[0] = "mutated"
+	m.Map(ctx)["one"].Tags[0] = "mutated"
+	one, _, _ = m.GetDefault(ctx, "one")
+	one.Tags[0] = "mutated"
+	one, _ = m.GetOrSet(ctx, "one", nil)
+	assert.Equalf(t, []string{"a"}, one.Tags, "registered instance is never aliased")
+
+	type config struct{ Port int }
+	var copies int
+	plain := inithook.NewMap(inithook.WithCopyOnRead[string, *config](func(c *config) *config {
+		copies++
+		copied := *c
+		return &copied
+	}))
+	plain.MustSet(ctx, "api", &config{Port: 80})
+	api, _ := plain.Get(ctx, "api")
+	api.Port = 443
+	api, _ = plain.GetOrCompute(ctx, "api", func(ctx context.Context) (*config, error) { return nil, errors.New("unused") })
+	assert.Equalf(t, 80, api.Port, "copied by fn")
+	assert.Equalf(t, 2, copies, "copies")
+}
+
+func TestMapWithImmutabilityCheck(t *testing.T) {
+	ctx := context.Background()
+	type config struct {
+		Tags   []string
+		Labels map[string]string
+		Next   *config
+	}
+	m := inithook.NewMap(inithook.WithImmutabilityCheck[string, *config](nil))
+	c := &config{Tags: []string{"a"}, Labels: map[string]string{"env": "dev", "zone": "a"}}
+	c.Next = c // cycle
+	m.MustRegister(ctx, "c", c)
+	assert.NotPanicsf(t, func() { m.MustGet(ctx, "c") }, "not mutated")
+	c.Labels["env"] = "prod"
+	assert.PanicsWithValuef(t, "inithook: type *inithook_test.config instance c is mutated in place after stored", func() { m.Get(ctx, "c") }, "mutated")
+	m.MustSet(ctx, "c", c)
+	assert.NotPanicsf(t, func() { m.GetDefault(ctx, "c") }, "set again rehashes")
+	c.Tags[0] = "b"
+	assert.Panicsf(t, func() { m.GetDefault(ctx, "c") }, "mutated slice")
+	assert.Nilf(t, m.Delete(ctx, "c"), "lock released after panic")
+
+	assert.Equalf(t, inithook.DeepHash(map[string]int{"a": 1, "b": 2}), inithook.DeepHash(map[string]int{"b": 2, "a": 1}), "map order")
+	assert.NotEqualf(t, inithook.DeepHash([]int{1, 2}), inithook.DeepHash([]int{2, 1}), "slice order")
+	shared := &config{Tags: []string{"shared"}}
+	nodes := map[string]*config{"a": shared, "b": shared, "c": shared, "d": shared}
+	sum := inithook.DeepHash(nodes)
+	for i := 0; i < 100; i++ {
+		assert.Equalf(t, sum, inithook.DeepHash(nodes), "shared pointers of map entries")
+	}
+	graphs := inithook.NewMap(inithook.WithImmutabilityCheck[string, map[string]*config](nil))
+	graphs.MustSet(ctx, "nodes", nodes)
+	assert.NotPanicsf(t, func() {
+		for i := 0; i < 100; i++ {
+			graphs.MustGet(ctx, "nodes")
+		}
+	}, "shared pointers not mutated")
+	var calls int
+	hashed := inithook.NewMap(inithook.WithImmutabilityCheck[string, int](func(v int) uint64 {
+		calls++
+		return uint64(v)
+	}))
+	hashed.MustSet(ctx, "a", 1)
+	hashed.MustGet(ctx, "a")
+	assert.Equalf(t, 2, calls, "user hasher")
+}
+
+func TestMapWithHistory(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithHistory[string, int](2))
+	m.MustRegister(ctx, "a", 1)
+	assert.Emptyf(t, m.History(ctx, "a"), "no history")
+	err := m.Rollback(ctx, "a")
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "rollback without history: %v", err)
+	m.MustSet(ctx, "a", 2)
+	m.MustSet(ctx, "a", 3)
+	m.MustSet(ctx, "a", 4)
+	assert.Equalf(t, []int{2, 3}, m.History(ctx, "a"), "last 2 kept")
+
+	assert.Nilf(t, m.Rollback(ctx, "a"), "rollback")
+	assert.Equalf(t, 3, m.MustGet(ctx, "a"), "rollback to 3")
+	assert.Equalf(t, []int{2}, m.History(ctx, "a"), "replaced one not recorded")
+	assert.Nilf(t, m.Rollback(ctx, "a"), "rollback")
+	assert.Equalf(t, 2, m.MustGet(ctx, "a"), "rollback to 2")
+	assert.Truef(t, errors.Is(m.Rollback(ctx, "a"), inithook.ErrNotFound), "history exhausted")
+
+	m.MustSet(ctx, "a", 5)
+	assert.Nilf(t, m.Delete(ctx, "a"), "delete")
+	assert.Emptyf(t, m.History(ctx, "a"), "history dropped by delete")
+	m.MustSet(ctx, "b", 1)
+	m.Seal()
+	assert.Truef(t, errors.Is(m.Rollback(ctx, "b"), inithook.ErrSealed), "sealed")
+
+	plain := inithook.NewMap[string, int]()
+	plain.MustSet(ctx, "a", 1)
+	plain.MustSet(ctx, "a", 2)
+	assert.Emptyf(t, plain.History(ctx, "a"), "not kept without option")
+}
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	a := inithook.NewMap[string, []int]()
+	a.MustSet(ctx, "kept", []int{1})
+	a.MustSet(ctx, "changed", []int{1})
+	a.MustSet(ctx, "removed", []int{1})
+	b, _ := a.Clone(ctx)
+	b.MustSet(ctx, "changed", []int{2})
+	b.MustDelete(ctx, "removed")
+	b.MustSet(ctx, "added", []int{3})
+
+	d := inithook.Diff(ctx, a, b, nil)
+	assert.Equalf(t, map[string][]int{"added": {3}}, d.Added, "added")
+	assert.Equalf(t, map[string][]int{"removed": {1}}, d.Removed, "removed")
+	assert.Equalf(t, map[string]inithook.Change[[]int]{"changed": {Old: []int{1}, New: []int{2}}}, d.Changed, "changed")
+	assert.Falsef(t, d.IsEmpty(), "not empty")
+	events := d.Events()
+	assert.Lenf(t, events, 3, "events")
+	replayed, _ := a.Clone(ctx)
+	for _, ev := range events {
+		if ev.Type == inithook.EventDelete {
+			replayed.MustDelete(ctx, ev.Key)
+		} else {
+			replayed.MustSet(ctx, ev.Key, ev.NewValue)
+		}
+	}
+	assert.Truef(t, inithook.Diff(ctx, replayed, b, nil).IsEmpty(), "events replayed")
+
+	byLen := func(x, y []int) bool { return len(x) == len(y) }
+	assert.Emptyf(t, inithook.DiffSnapshots(a.Snapshot(ctx), b.Snapshot(ctx), byLen).Changed, "custom eq")
+}
+
+func TestMapCloneMerge(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustSet(ctx, "one", 1)
+	m.MustSet(ctx, "two", 2)
+	clone, err := m.Clone(ctx)
+	assert.Nilf(t, err, "clone")
+	clone.MustSet(ctx, "one", 11)
+	v, _ := m.Get(ctx, "one")
+	assert.Equalf(t, 1, v, "clone is independent")
+
+	other := inithook.NewMap[string, int]()
+	other.MustSet(ctx, "two", 22)
+	other.MustSet(ctx, "three", 3)
+
+	skip, _ := m.Clone(ctx)
+	assert.Nilf(t, skip.Merge(ctx, other, inithook.MergeSkipExisting), "merge skip existing")
+	assert.Equalf(t, map[string]int{"one": 1, "two": 2, "three": 3}, skip.Map(ctx), "skip existing")
+
+	overwrite, _ := m.Clone(ctx)
+	assert.Nilf(t, overwrite.Merge(ctx, other, inithook.MergeOverwrite), "merge overwrite")
+	assert.Equalf(t, map[string]int{"one": 1, "two": 22, "three": 3}, overwrite.Map(ctx), "overwrite")
+
+	conflict, _ := m.Clone(ctx)
+	err = conflict.Merge(ctx, other, inithook.MergeErrorOnConflict)
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "merge error on conflict")
+	assert.Equalf(t, m.Map(ctx), conflict.Map(ctx), "nothing merged on conflict")
+
+	m.MustSetWithTTL(ctx, "expiring", 4, time.Hour)
+	m.MustRegisterProvider(ctx, "provided", func(ctx context.Context) (int, error) { return 5, nil })
+	m.MustRegister(ctx, "described", 6, inithook.WithDescription("the sixth"))
+	m.Alias(ctx, "uno", "one")
+	clone, err = m.Clone(ctx)
+	assert.Nilf(t, err, "clone state")
+	ttl, ok := clone.TTL(ctx, "expiring")
+	assert.Truef(t, ok && ttl > 0, "clone keeps ttl")
+	assert.Equalf(t, 5, clone.MustGet(ctx, "provided"), "clone keeps provider")
+	meta, err := clone.Describe(ctx, "described")
+	assert.Nilf(t, err, "describe clone")
+	assert.Equalf(t, "the sixth", meta.Description, "clone keeps metadata")
+	assert.Equalf(t, 1, clone.MustGet(ctx, "uno"), "clone keeps alias")
+
+	var frozen bool
+	strict := inithook.NewMap(inithook.WithValidator(func(ctx context.Context, key string, value int) error {
+		if frozen {
+			return errors.New("frozen")
+		}
+		return nil
+	}))
+	strict.MustSet(ctx, "one", 1)
+	frozen = true
+	_, err = strict.Clone(ctx)
+	assert.ErrorContainsf(t, err, "frozen", "clone rejected by validators")
+	_, err = strict.Filter(ctx, func(key string, value int) bool { return true })
+	assert.ErrorContainsf(t, err, "frozen", "filter rejected by validators")
+}
+
+func TestMapFunctional(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.SetMany(ctx, map[string]int{"one": 1, "two": 2, "three": 3})
+	odd, err := m.Filter(ctx, func(key string, value int) bool { return value%2 == 1 })
+	assert.Nilf(t, err, "filter")
+	assert.Equalf(t, map[string]int{"one": 1, "three": 3}, odd.Map(ctx), "filter")
+	strs := inithook.MapValues(ctx, m, func(key string, value int) string { return key + "=" + strconv.Itoa(value) })
+	assert.Equalf(t, map[string]string{"one": "one=1", "two": "two=2", "three": "three=3"}, strs.Map(ctx), "map values")
+	sum := inithook.Reduce(ctx, m, 0, func(acc int, key string, value int) int { return acc + value })
+	assert.Equalf(t, 6, sum, "reduce")
+}
+
+func TestMapRangeTyped(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.SetMany(ctx, map[string]int{"one": 1, "two": 2, "three": 3})
+	sum := 0
+	m.RangeTyped(ctx, func(key string, value int) bool {
+		sum += value
+		return true
+	})
+	assert.Equalf(t, 6, sum, "range typed")
+	count := 0
+	m.RangeTyped(ctx, func(key string, value int) bool {
+		count++
+		return false
+	})
+	assert.Equalf(t, 1, count, "range typed stops")
+}
+
+func TestMapRangeSnapshot(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.SetMany(ctx, map[string]int{"one": 1, "two": 2, "three": 3})
+	visited := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.RangeSnapshot(ctx, func(key string, value int) bool {
+			visited++
+			m.MustDelete(ctx, key)
+			m.MustSet(ctx, key+"-copy", value)
+			return true
+		})
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("range snapshot should not hold the lock while calling back")
+	}
+	assert.Equalf(t, 3, visited, "mutations not visited")
+	assert.ElementsMatchf(t, []string{"one-copy", "two-copy", "three-copy"}, m.Keys(ctx), "mutated in range")
+	count := 0
+	m.RangeSnapshot(ctx, func(key string, value int) bool {
+		count++
+		return false
+	})
+	assert.Equalf(t, 1, count, "range snapshot stops")
+}
+
+func TestMapWithReentrancyCheck(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithReentrancyCheck[string, int]())
+	m.MustSet(ctx, "a", 1)
+	defer func() {
+		msg := fmt.Sprint(recover())
+		assert.Regexpf(t, `^inithook: reentrant write lock of the map of type int by Set at .*map_test.go:\d+ while locked by RangeTyped at .*map_test.go:\d+, which deadlocks$`, msg, "panic message")
+		assert.Nilf(t, m.Set(ctx, "b", 2), "lock released after panic")
+		m.RangeSnapshot(ctx, func(key string, value int) bool {
+			assert.Equalf(t, value, m.MustGet(ctx, key), "no reentrancy outside the lock")
+			return true
+		})
+	}()
+	m.RangeTyped(ctx, func(key string, value int) bool {
+		m.Set(ctx, key, value+1)
+		return true
+	})
+	t.Fatal("should panic")
+}
+
+func TestOrderedMap(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewOrderedMap[string, int]()
+	for i, key := range []string{"logger", "config", "db", "http"} {
+		m.MustRegister(ctx, key, i)
+	}
+	m.MustSet(ctx, "config", 10)
+	m.MustDelete(ctx, "db")
+	m.MustSet(ctx, "db", 20)
+	assert.Equalf(t, []string{"logger", "config", "http", "db"}, m.Keys(ctx), "keys in insertion order")
+	assert.Equalf(t, []int{0, 10, 3, 20}, m.Values(ctx), "values in insertion order")
+	var keys []string
+	m.RangeTyped(ctx, func(key string, value int) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.Equalf(t, m.Keys(ctx), keys, "range in insertion order")
+}
+
+func TestMapSorted(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.SetMany(ctx, map[string]int{"b": 2, "c": 1, "a": 3})
+	assert.Equalf(t, []string{"a", "b", "c"}, m.SortedKeys(ctx, func(a, b string) bool { return a < b }), "lexical")
+	var values []int
+	m.RangeSorted(ctx, func(a, b string) bool { return a > b }, func(key string, value int) bool {
+		values = append(values, value)
+		return len(values) < 2
+	})
+	assert.Equalf(t, []int{1, 2}, values, "range sorted stops")
+}
+
+func TestMapPopSwap(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	old, loaded := m.Swap(ctx, "job", 1)
+	assert.Falsef(t, loaded, "swap new")
+	assert.Equalf(t, 0, old, "swap new old")
+	old, loaded = m.Swap(ctx, "job", 2)
+	assert.Truef(t, loaded, "swap existing")
+	assert.Equalf(t, 1, old, "swap existing old")
+
+	var wg sync.WaitGroup
+	var claimed int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Pop(ctx, "job"); err == nil {
+				atomic.AddInt32(&claimed, 1)
+			} else {
+				assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "pop not found")
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equalf(t, int32(1), claimed, "claimed once")
+	assert.Falsef(t, m.Has(ctx, "job"), "popped")
+}
+
+func TestMapSeal(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustRegister(ctx, "a", 1)
+	assert.Nilf(t, m.RegisterProvider(ctx, "lazy", func(ctx context.Context) (int, error) { return 2, nil }), "provider")
+	assert.Falsef(t, m.Sealed(), "not sealed")
+	m.Seal()
+	assert.Truef(t, m.Sealed(), "sealed")
+	for name, err := range map[string]error{
+		"register": m.Register(ctx, "b", 2),
+		"set":      m.Set(ctx, "a", 2),
+		"delete":   m.Delete(ctx, "a"),
+		"update":   m.Update(ctx, "a", func(old int) (int, error) { return old + 1, nil }),
+		"set many": m.SetMany(ctx, map[string]int{"a": 2}),
+		"clear":    m.Clear(ctx),
+		"tx":       m.Tx(ctx, func(tx inithook.Txn[string, int]) error { return nil }),
+	} {
+		assert.Truef(t, errors.Is(err, inithook.ErrSealed), "%s should be sealed: %v", name, err)
+	}
+	_, err := m.GetOrSet(ctx, "b", 2)
+	assert.Truef(t, errors.Is(err, inithook.ErrSealed), "get or set missing")
+	v, err := m.GetOrSet(ctx, "a", 2)
+	assert.Nilf(t, err, "get or set existing")
+	assert.Equalf(t, 1, v, "get or set existing")
+	assert.Falsef(t, m.CompareAndSwap(ctx, "a", 1, 2), "compare and swap")
+	assert.Panicsf(t, func() { m.Swap(ctx, "a", 2) }, "swap")
+	v, err = m.Get(ctx, "lazy")
+	assert.Nilf(t, err, "providers are resolved")
+	assert.Equalf(t, 2, v, "providers are resolved")
+	assert.Equalf(t, map[string]int{"a": 1, "lazy": 2}, m.Map(ctx), "unchanged")
+	assert.Equalf(t, "type int instance b: sealed", m.Register(ctx, "b", 2).Error(), "message")
+}
+
+func TestMapWithKeyValidator(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithKeyValidator[string, int](func(key string) error {
+		if key == "" {
+			return errors.New("empty key")
+		}
+		return nil
+	}))
+	err := m.Register(ctx, "", 1)
+	assert.Truef(t, errors.Is(err, inithook.ErrInvalidKey), "invalid key: %v", err)
+	assert.Equalf(t, "type int instance : empty key: invalid key", err.Error(), "message")
+	assert.Truef(t, errors.Is(m.SetMany(ctx, map[string]int{"a": 1, "": 2}), inithook.ErrInvalidKey), "set many")
+	assert.Nilf(t, m.Register(ctx, "a", 1), "valid key")
+
+	ids := inithook.NewMap(inithook.WithKeyValidator[string, int](inithook.NonEmptyKey), inithook.WithKeyValidator[string, int](inithook.KeyPattern(`[a-z0-9_]+`)))
+	assert.Nilf(t, ids.Register(ctx, "http_2", 1), "valid id")
+	err = ids.Set(ctx, "HTTP-2", 1)
+	assert.Truef(t, errors.Is(err, inithook.ErrInvalidKey), "invalid id: %v", err)
+	assert.Equalf(t, "type int instance HTTP-2: not match [a-z0-9_]+: invalid key", err.Error(), "message")
+	assert.Truef(t, errors.Is(ids.Set(ctx, "", 1), inithook.ErrInvalidKey), "empty id")
+	err = ids.RegisterProvider(ctx, "Lazy", func(ctx context.Context) (int, error) { return 1, nil })
+	assert.Truef(t, errors.Is(err, inithook.ErrInvalidKey), "provider key")
+	assert.Truef(t, errors.Is(ids.Alias(ctx, "HTTP", "http_2"), inithook.ErrInvalidKey), "alias key")
+	assert.Equalf(t, []string{"http_2"}, ids.Keys(ctx), "nothing registered")
+}
+
+func TestMapWithKeyNormalizer(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int](inithook.WithKeyNormalizer[string, int](func(key string) string {
+		return strings.ToLower(strings.TrimSpace(key))
+	}))
+	m.MustRegister(ctx, "HTTP", 1)
+	err := m.Register(ctx, " http ", 2)
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "normalized keys conflict")
+	v, err := m.Get(ctx, "Http")
+	assert.Nilf(t, err, "get normalized")
+	assert.Equalf(t, 1, v, "get normalized")
+	assert.Equalf(t, []string{"http"}, m.Keys(ctx), "stored normalized")
+	m.SetMany(ctx, map[string]int{"GRPC": 3})
+	assert.Equalf(t, map[string]int{"grpc": 3}, m.GetMany(ctx, []string{"Grpc"}), "batch normalized")
+	m.MustDelete(ctx, "HTTP ")
+	assert.Falsef(t, m.Has(ctx, "http"), "delete normalized")
+}
+
+func TestMapRegisterOptions(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustRegister(ctx, "codec", 1, inithook.WithDescription("json codec"), inithook.WithTags("experimental", "json"))
+	meta, err := m.Describe(ctx, "codec")
+	assert.Nilf(t, err, "describe")
+	assert.Equalf(t, inithook.Metadata{Description: "json codec", Tags: []string{"experimental", "json"}}, meta, "metadata")
+
+	err = m.Register(ctx, "codec", 2)
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "register twice")
+	err = m.Register(ctx, "codec", 2, inithook.WithOverwrite(), inithook.WithDescription("json codec v2"))
+	assert.Nilf(t, err, "register with overwrite")
+	v, _ := m.Get(ctx, "codec")
+	assert.Equalf(t, 2, v, "overwritten")
+	meta, _ = m.Describe(ctx, "codec")
+	assert.Equalf(t, "json codec v2", meta.Description, "metadata overwritten")
+
+	m.MustDelete(ctx, "codec")
+	_, err = m.Describe(ctx, "codec")
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "describe deleted")
+}
+
+func TestMapRegistrationInfo(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithRegistrationInfo[string, int]())
+	before := time.Now()
+	m.MustRegister(ctx, "one", 1)
+	info, ok := m.Info(ctx, "one")
+	assert.Truef(t, ok, "info recorded")
+	assert.Falsef(t, info.RegisteredAt.Before(before), "registered at")
+	assert.Equalf(t, "github.com/ccmonky/inithook_test", info.Caller.Package, "caller package")
+	assert.Truef(t, strings.HasSuffix(info.Caller.File, "map_test.go"), "caller file %s", info.Caller.File)
+	assert.Truef(t, strings.HasSuffix(info.Caller.Function, "TestMapRegistrationInfo"), "caller function %s", info.Caller.Function)
+
+	inithook.NewNamespace(m, "ns").MustRegister(ctx, "two", 2)
+	info, _ = m.Info(ctx, "ns/two")
+	assert.Truef(t, strings.HasSuffix(info.Caller.File, "map_test.go"), "caller through namespace %s", info.Caller.File)
+
+	_, ok = inithook.NewMap[string, int]().Info(ctx, "one")
+	assert.Falsef(t, ok, "info not recorded by default")
+}
+
+func TestMapDuplicateRegistrationDiagnostics(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithRegistrationInfo[string, int]())
+	m.MustRegister(ctx, "dup", 1)
+	_, _, line, _ := runtime.Caller(0)
+	err := m.Register(ctx, "dup", 2)
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "already exists")
+	assert.Containsf(t, err.Error(), "map_test.go:"+strconv.Itoa(line-1), "original location in %v", err)
+	assert.Containsf(t, err.Error(), "map_test.go:"+strconv.Itoa(line+1), "conflict location in %v", err)
+}
+
+func TestMapAlias(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustRegister(ctx, "render", 1)
+	assert.Nilf(t, m.Alias(ctx, "renderer", "render"), "alias")
+	assert.Nilf(t, m.Alias(ctx, "legacy-renderer", "renderer"), "alias of alias")
+	v, err := m.Get(ctx, "legacy-renderer")
+	assert.Nilf(t, err, "get by alias")
+	assert.Equalf(t, 1, v, "get by alias")
+	m.MustSet(ctx, "renderer", 2)
+	v, _ = m.Get(ctx, "render")
+	assert.Equalf(t, 2, v, "set by alias")
+	assert.ElementsMatchf(t, []string{"renderer", "legacy-renderer"}, m.Aliases(ctx, "render"), "aliases")
+	assert.Equalf(t, []string{"render"}, m.Keys(ctx), "aliases are not keys")
+
+	assert.Truef(t, errors.Is(m.Alias(ctx, "render", "other"), inithook.ErrAlreadyExists), "alias existing key")
+	assert.Truef(t, errors.Is(m.Alias(ctx, "renderer", "other"), inithook.ErrAlreadyExists), "alias existing alias")
+	assert.Nilf(t, m.Alias(ctx, "a", "b"), "alias to missing key is allowed")
+	assert.Nilf(t, m.Alias(ctx, "b", "c"), "chain")
+	err = m.Alias(ctx, "c", "a")
+	assert.Truef(t, errors.Is(err, inithook.ErrCycle), "cycle detected: %v", err)
+
+	assert.Nilf(t, m.Unalias(ctx, "legacy-renderer"), "unalias")
+	assert.Falsef(t, m.Has(ctx, "legacy-renderer"), "unaliased")
+}
+
+func TestMapDeprecate(t *testing.T) {
+	ctx := context.Background()
+	warnings := map[string]string{}
+	var warned int
+	m := inithook.NewMap(inithook.WithDeprecationHandler[string, int](func(ctx context.Context, key string, message string) {
+		warnings[key] = message
+		warned++
+	}))
+	m.MustRegister(ctx, "render", 1)
+	m.Alias(ctx, "renderer", "render")
+	assert.Nilf(t, m.Deprecate(ctx, "renderer", "use render instead"), "deprecate alias")
+	assert.Truef(t, errors.Is(m.Deprecate(ctx, "missing", ""), inithook.ErrNotFound), "deprecate missing")
+
+	v, err := m.Get(ctx, "render")
+	assert.Nilf(t, err, "get")
+	assert.Equalf(t, 1, v, "get")
+	assert.Emptyf(t, warnings, "new name is not deprecated")
+	v, err = m.Get(ctx, "renderer")
+	assert.Nilf(t, err, "get deprecated")
+	assert.Equalf(t, 1, v, "get deprecated")
+	assert.Equalf(t, map[string]string{"renderer": "use render instead"}, warnings, "warned")
+	assert.Equalf(t, map[string]string{"renderer": "use render instead"}, m.Deprecated(ctx), "deprecated")
+	warned = 0
+	v, ok := m.GetOK(ctx, "renderer")
+	assert.Truef(t, ok, "get ok deprecated")
+	assert.Equalf(t, 1, v, "get ok deprecated")
+	assert.Equalf(t, 1, warned, "get ok warns once")
+
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+	writers := inithook.NewMap[string, io.Writer]()
+	writers.MustRegister(ctx, "discard", io.Discard)
+	writers.Alias(ctx, "null", "discard")
+	writers.Deprecate(ctx, "null", "use discard instead")
+	writers.Get(ctx, "null")
+	assert.Equalf(t, "inithook: type io.Writer instance null is deprecated: use discard instead\n", buf.String(), "default handler logs the type")
+}
+
+func TestChain(t *testing.T) {
+	ctx := context.Background()
+	tenant := inithook.NewMap[string, string]()
+	global := inithook.NewMap[string, string]()
+	global.MustRegister(ctx, "theme", "light")
+	global.MustRegister(ctx, "lang", "en")
+	tenant.MustRegister(ctx, "theme", "dark")
+	chain := inithook.NewChain(tenant, global)
+
+	v, err := chain.Get(ctx, "theme")
+	assert.Nilf(t, err, "get theme")
+	assert.Equalf(t, "dark", v, "tenant overrides global")
+	v, err = chain.Get(ctx, "lang")
+	assert.Nilf(t, err, "get lang")
+	assert.Equalf(t, "en", v, "fallback to global")
+	_, err = chain.Get(ctx, "missing")
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "missing")
+	assert.Truef(t, chain.Has(ctx, "lang"), "has lang")
+	assert.Falsef(t, chain.Has(ctx, "missing"), "has missing")
+}
+
+func TestMapJSON(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustRegister(ctx, "a", 1)
+	m.MustRegister(ctx, "b", 2)
+	data, err := json.Marshal(m)
+	assert.Nilf(t, err, "marshal")
+	assert.JSONEqf(t, `{"a":1,"b":2}`, string(data), "marshal")
+
+	loaded := inithook.NewMap[string, int]()
+	assert.Nilf(t, json.Unmarshal(data, loaded), "unmarshal")
+	assert.Equalf(t, m.Map(ctx), loaded.Map(ctx), "unmarshal")
+
+	var holder struct {
+		Registry inithook.Map[string, int] `json:"registry"`
+	}
+	assert.Nilf(t, json.Unmarshal([]byte(`{"registry":{"c":3}}`), &holder), "unmarshal zero map")
+	assert.Equalf(t, map[string]int{"c": 3}, holder.Registry.Map(ctx), "unmarshal zero map")
+}
+
+func TestMapJSONKeyCodec(t *testing.T) {
+	type point struct{ X, Y int }
+	ctx := context.Background()
+	codec := inithook.WithKeyCodec[point, string](func(key point) (string, error) {
+		return fmt.Sprintf("%d,%d", key.X, key.Y), nil
+	}, func(s string) (point, error) {
+		var p point
+		_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+		return p, err
+	})
+	m := inithook.NewMap(codec)
+	m.MustRegister(ctx, point{1, 2}, "a")
+	data, err := json.Marshal(m)
+	assert.Nilf(t, err, "marshal")
+	assert.JSONEqf(t, `{"1,2":"a"}`, string(data), "marshal")
+
+	loaded := inithook.NewMap(codec)
+	assert.Nilf(t, json.Unmarshal(data, loaded), "unmarshal")
+	assert.Equalf(t, map[point]string{{1, 2}: "a"}, loaded.Map(ctx), "unmarshal")
+	err = json.Unmarshal([]byte(`{"x":"b"}`), loaded)
+	assert.Truef(t, errors.Is(err, inithook.ErrInvalidKey), "bad key: %v", err)
+}
+
+func TestKey2Key3(t *testing.T) {
+	ctx := context.Background()
+	codecs := inithook.NewMap[inithook.Key2[string, int], string]()
+	codecs.MustRegister(ctx, inithook.NewKey2("json", 1), "v1")
+	codecs.MustRegister(ctx, inithook.NewKey2("json", 2), "v2")
+	v, err := codecs.Get(ctx, inithook.Key2[string, int]{First: "json", Second: 2})
+	assert.Nilf(t, err, "get by tuple")
+	assert.Equalf(t, "v2", v, "get by tuple")
+	assert.Equalf(t, "(json, 1)", inithook.NewKey2("json", 1).String(), "string")
+
+	data, err := json.Marshal(codecs)
+	assert.Nilf(t, err, "marshal map")
+	loaded := inithook.NewMap[inithook.Key2[string, int], string]()
+	assert.Nilf(t, json.Unmarshal(data, loaded), "unmarshal map")
+	assert.Equalf(t, codecs.Map(ctx), loaded.Map(ctx), "roundtrip")
+
+	routes := inithook.NewKey3("GET", "/users", 2)
+	data, err = json.Marshal(routes)
+	assert.Nilf(t, err, "marshal")
+	assert.Equalf(t, `["GET","/users",2]`, string(data), "json array")
+	var decoded inithook.Key3[string, string, int]
+	assert.Nilf(t, json.Unmarshal(data, &decoded), "unmarshal")
+	assert.Equalf(t, routes, decoded, "roundtrip")
+	assert.Equalf(t, "(GET, /users, 2)", decoded.String(), "string")
+	assert.NotNilf(t, json.Unmarshal([]byte(`["GET","/users"]`), &decoded), "length mismatch")
+	assert.NotNilf(t, json.Unmarshal([]byte(`["GET","/users","2"]`), &decoded), "type mismatch")
+}
+
+func TestMapStageJSON(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithValidator(func(ctx context.Context, key string, value int) error {
+		if value < 0 {
+			return errors.New("negative")
+		}
+		return nil
+	}))
+	m.MustRegister(ctx, "builtin", 0)
+	swap, err := m.StageJSON(ctx, nil, []byte(`{"a":1,"b":2}`))
+	assert.Nilf(t, err, "stage")
+	assert.Equalf(t, map[string]int{"builtin": 0}, m.Map(ctx), "nothing changed before swap")
+	assert.Nilf(t, swap(ctx), "swap")
+	assert.Equalf(t, map[string]int{"builtin": 0, "a": 1, "b": 2}, m.Map(ctx), "swapped")
+
+	swap, err = m.StageJSON(ctx, []byte(`{"a":1,"b":2}`), []byte(`{"b":3}`))
+	assert.Nilf(t, err, "stage")
+	assert.Nilf(t, swap(ctx), "swap")
+	assert.Equalf(t, map[string]int{"builtin": 0, "b": 3}, m.Map(ctx), "stale deleted")
+
+	_, err = m.StageJSON(ctx, nil, []byte(`{"c":-1}`))
+	assert.NotNilf(t, err, "invalid")
+	_, err = m.StageJSON(ctx, nil, []byte(`{"c":"x"}`))
+	assert.NotNilf(t, err, "bad json")
+	assert.Equalf(t, map[string]int{"builtin": 0, "b": 3}, m.Map(ctx), "nothing changed")
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Encode(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) }
+func (jsonCodec) Decode(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) }
+
+func TestMapEncodeDecode(t *testing.T) {
+	ctx := context.Background()
+	for name, opts := range map[string][]inithook.Option[string, []int]{
+		"gob":  nil,
+		"json": {inithook.WithCodec[string, []int](jsonCodec{})},
+	} {
+		m := inithook.NewMap(opts...)
+		m.MustRegister(ctx, "a", []int{1, 2})
+		m.MustRegister(ctx, "b", []int{3})
+		var buf bytes.Buffer
+		assert.Nilf(t, m.Encode(&buf), "%s encode", name)
+
+		loaded := inithook.NewMap(opts...)
+		loaded.MustRegister(ctx, "stale", nil)
+		assert.Nilf(t, loaded.Decode(&buf), "%s decode", name)
+		assert.Equalf(t, m.Map(ctx), loaded.Map(ctx), "%s decode replaces contents", name)
+		assert.NotNilf(t, loaded.Decode(strings.NewReader("garbage")), "%s decode garbage", name)
+	}
+}
+
+func TestMapPublishExpvar(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustRegister(ctx, "a", 1)
+	m.Get(ctx, "a")
+	m.Get(ctx, "a")
+	m.Get(ctx, "missing")
+	assert.Nilf(t, m.PublishExpvar("inithook_test_map"), "publish")
+	assert.Truef(t, errors.Is(m.PublishExpvar("inithook_test_map"), inithook.ErrAlreadyExists), "publish twice")
+
+	var vars struct {
+		Keys   []string `json:"keys"`
+		Len    int      `json:"len"`
+		Hits   uint64   `json:"hits"`
+		Misses uint64   `json:"misses"`
+	}
+	assert.Nilf(t, json.Unmarshal([]byte(expvar.Get("inithook_test_map").String()), &vars), "unmarshal vars")
+	assert.Equalf(t, []string{"a"}, vars.Keys, "keys")
+	assert.Equalf(t, 1, vars.Len, "len")
+	assert.Equalf(t, uint64(2), vars.Hits, "hits")
+	assert.Equalf(t, uint64(1), vars.Misses, "misses")
+}
+
+// loadedDefault counts the default loadings
+type loadedDefault struct {
+	Key string
+}
+
+var defaultLoads atomic.Int32
+
+func (loadedDefault) LoadDefault(ctx context.Context, key any) (loadedDefault, error) {
+	defaultLoads.Add(1)
+	return loadedDefault{Key: fmt.Sprint(key)}, nil
+}
+
+// typedDefault loads the default by the typed key
+type typedDefault struct {
+	Port int
+}
+
+func (typedDefault) LoadDefault(ctx context.Context, port int) (typedDefault, error) {
+	return typedDefault{Port: port}, nil
+}
+
+func TestZero(t *testing.T) {
+	assert.Equalf(t, 0, inithook.Zero[int](), "int")
+	assert.Equalf(t, "", **inithook.Zero[**string](), "pointers")
+	m := inithook.Zero[map[string]int]()
+	assert.NotNilf(t, m, "map")
+	m["a"] = 1
+	assert.NotNilf(t, inithook.Zero[[]int](), "slice")
+	assert.NotNilf(t, *inithook.Zero[*map[string]int](), "pointer to map")
+	assert.NotNilf(t, inithook.Zero[chan int](), "chan")
+	assert.NotNilf(t, inithook.Zero[<-chan int](), "receive-only chan")
+	assert.Nilf(t, inithook.Zero[io.Reader](), "interface")
+
+	assert.Equalf(t, map[string]int{}, inithook.ZeroOf(reflect.TypeOf(map[string]int(nil))), "zero of map")
+	assert.Equalf(t, 0, inithook.ZeroOf(reflect.TypeOf(0)), "zero of int")
+	assert.Nilf(t, inithook.ZeroOf(reflect.TypeOf((*io.Reader)(nil)).Elem()), "zero of interface")
+	assert.Nilf(t, inithook.ZeroOf(nil), "zero of nil")
+}
+
+func TestMapDefaultLoaderK(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[int, typedDefault]()
+	v, defaulted, err := m.GetDefault(ctx, 8080)
+	assert.Nilf(t, err, "default")
+	assert.Truef(t, defaulted, "defaulted")
+	assert.Equalf(t, 8080, v.Port, "default by typed key")
+	var _ inithook.DefaultLoaderK[int, typedDefault] = typedDefault{}
+
+	loaded, err := inithook.NewMap[int, loadedDefault]().Default(ctx, 1)
+	assert.Nilf(t, err, "untyped loader still works")
+	assert.Equalf(t, "1", loaded.Key, "untyped loader still works")
+}
+
+func TestMapWithDefaultFunc(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithDefaultFunc(func(ctx context.Context, key string) (int, error) {
+		if key == "" {
+			return 0, errors.New("empty key")
+		}
+		return len(key), nil
+	}))
+	m.MustRegister(ctx, "a", 100)
+	v, defaulted, err := m.GetDefault(ctx, "a")
+	assert.Nilf(t, err, "registered")
+	assert.Falsef(t, defaulted, "registered")
+	assert.Equalf(t, 100, v, "registered")
+	v, defaulted, err = m.GetDefault(ctx, "abc")
+	assert.Nilf(t, err, "default func")
+	assert.Truef(t, defaulted, "defaulted")
+	assert.Equalf(t, 3, v, "default func")
+	_, _, err = m.GetDefault(ctx, "")
+	assert.EqualErrorf(t, err, "empty key", "default func error")
+}
+
+func TestMapDefaultCache(t *testing.T) {
+	ctx := context.Background()
+	defaultLoads.Store(0)
+	m := inithook.NewMap(inithook.WithDefaultCache[string, loadedDefault]())
+	m.MustRegister(ctx, "registered", loadedDefault{Key: "registered"})
+	v, defaulted, err := m.GetDefault(ctx, "registered")
+	assert.Nilf(t, err, "registered")
+	assert.Falsef(t, defaulted, "registered is not defaulted")
+	assert.Equalf(t, "registered", v.Key, "registered")
+	for i := 0; i < 3; i++ {
+		v, defaulted, err = m.GetDefault(ctx, "missing")
+		assert.Nilf(t, err, "default")
+		assert.Truef(t, defaulted, "defaulted")
+		assert.Equalf(t, "missing", v.Key, "default of key")
+	}
+	assert.Equalf(t, int32(1), defaultLoads.Load(), "default memoized")
+	assert.Equalf(t, uint64(3), m.Stats(ctx).Defaults, "defaults counted")
+	m.ForgetDefaults(ctx)
+	m.GetDefault(ctx, "missing")
+	assert.Equalf(t, int32(2), defaultLoads.Load(), "loaded again after forget")
+
+	uncached := inithook.NewMap[string, loadedDefault]()
+	uncached.GetDefault(ctx, "missing")
+	uncached.GetDefault(ctx, "missing")
+	assert.Equalf(t, int32(4), defaultLoads.Load(), "not memoized by default")
+}
+
+func TestMapStats(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustRegister(ctx, "a", 1)
+	m.MustRegister(ctx, "b", 2)
+	m.Register(ctx, "a", 3)
+	m.MustSet(ctx, "a", 4)
+	m.Get(ctx, "a")
+	m.Get(ctx, "missing")
+	m.GetDefault(ctx, "missing")
+	m.MustDelete(ctx, "b")
+	assert.Equalf(t, inithook.Stats{
+		Gets:      3,
+		Hits:      1,
+		Misses:    2,
+		Defaults:  1,
+		Registers: 2,
+		Sets:      1,
+		Deletes:   1,
+		Conflicts: 1,
+	}, m.Stats(ctx), "stats")
+}
+
+func TestMapLogger(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	m := inithook.NewMap(inithook.WithName[string, int]("ints"), inithook.WithLogger[string, int](logger))
+	m.MustRegister(ctx, "a", 1)
+	m.Register(ctx, "a", 2)
+	m.MustSet(ctx, "a", 3)
+	m.MustDelete(ctx, "a")
+	assert.Equalf(t, strings.Join([]string{
+		`level=DEBUG msg="inithook: register" registry=ints key=a type=int`,
+		`level=WARN msg="inithook: register conflict" registry=ints key=a type=int error="type int instance a: already exists"`,
+		`level=INFO msg="inithook: override" registry=ints key=a type=int`,
+		`level=DEBUG msg="inithook: delete" registry=ints key=a type=int`,
+	}, "\n")+"\n", buf.String(), "logs")
+
+	buf.Reset()
+	m = inithook.NewMap(inithook.WithLogger[string, int](logger), inithook.WithLogLevels[string, int](inithook.LogLevels{
+		Register: slog.LevelDebug - 1,
+		Override: slog.LevelDebug - 1,
+		Delete:   slog.LevelDebug - 1,
+		Error:    slog.LevelError,
+	}))
+	m.MustRegister(ctx, "a", 1)
+	m.Register(ctx, "a", 2)
+	assert.Equalf(t, `level=ERROR msg="inithook: register conflict" registry="" key=a type=int error="type int instance a: already exists"`+"\n",
+		buf.String(), "levels")
+
+	buf.Reset()
+	writers := inithook.NewMap(inithook.WithLogger[string, io.Writer](logger))
+	writers.MustRegister(ctx, "buf", io.Discard)
+	assert.Equalf(t, `level=DEBUG msg="inithook: register" registry="" key=buf type=io.Writer`+"\n", buf.String(), "interface type")
+}
+
+func TestMapFromContext(t *testing.T) {
+	ctx := context.Background()
+	global := inithook.NewMap[string, int]()
+	_, ok := inithook.MapFromContext[string, int](ctx)
+	assert.Falsef(t, ok, "empty ctx")
+	assert.Samef(t, global, inithook.MapFromContextOr(ctx, global), "fallback to global")
+
+	scoped := inithook.NewMap[string, int]()
+	ctx = inithook.WithMap(ctx, scoped)
+	m, ok := inithook.MapFromContext[string, int](ctx)
+	assert.Truef(t, ok, "scoped ctx")
+	assert.Samef(t, scoped, m, "scoped ctx")
+	assert.Samef(t, scoped, inithook.MapFromContextOr(ctx, global), "scoped shadows global")
+	_, ok = inithook.MapFromContext[string, string](ctx)
+	assert.Falsef(t, ok, "other type")
+}
+
+func TestRegisterMap(t *testing.T) {
+	ctx := context.Background()
+	handlers := inithook.MustRegisterMap[string, func() string]("test_handlers")
+	handlers.MustRegister(ctx, "index", func() string { return "index" })
+	_, err := inithook.RegisterMap[string, func() string]("test_handlers")
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "register twice")
+	ints := inithook.MustRegisterMap[string, int]("test_handlers")
+
+	m, err := inithook.MapOf[string, func() string]("test_handlers")
+	assert.Nilf(t, err, "map of")
+	assert.Samef(t, handlers, m, "map of")
+	m2, err := inithook.MapOf[string, int]("test_handlers")
+	assert.Nilf(t, err, "map of other type")
+	assert.Samef(t, ints, m2, "map of other type")
+	_, err = inithook.MapOf[string, string]("test_handlers")
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "map of missing type")
+
+	var names []string
+	for _, info := range inithook.Registries() {
+		if info.Name == "test_handlers" {
+			names = append(names, info.KeyType.String()+":"+info.ValueType.String())
+		}
+	}
+	assert.Equalf(t, []string{"string:func() string", "string:int"}, names, "registries")
+	for _, info := range inithook.Registries() {
+		reg, err := inithook.Registry(info)
+		assert.Nilf(t, err, "registry %s", info.Name)
+		assert.NotNilf(t, reg, "registry %s", info.Name)
+	}
+	_, err = inithook.Registry(inithook.RegistryInfo{Name: "test_missing", KeyType: reflect.TypeOf(""), ValueType: reflect.TypeOf("")})
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "missing registry")
+}
+
+func TestFor(t *testing.T) {
+	ctx := context.Background()
+	type handler func() string
+	inithook.For[handler]().MustRegister(ctx, "index", func() string { return "index" })
+	h, err := inithook.For[handler]().Get(ctx, "index")
+	assert.Nilf(t, err, "get")
+	assert.Equalf(t, "index", h(), "get")
+	assert.Samef(t, inithook.For[handler](), inithook.For[handler](), "same map")
+	assert.Falsef(t, inithook.For[func() string]().Has(ctx, "index"), "keyed by the exact type")
+}
+
+func BenchmarkMapGet(b *testing.B) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	for i, key := range benchKeys {
+		m.MustSet(ctx, key, i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			m.Get(ctx, benchKeys[i%len(benchKeys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkMapGetMiss(b *testing.B) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			m.Get(ctx, benchKeys[i%len(benchKeys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkMapGetOKMiss(b *testing.B) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			m.GetOK(ctx, benchKeys[i%len(benchKeys)])
+			i++
+		}
+	})
+}
+
+func BenchmarkMapSet(b *testing.B) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			m.Set(ctx, benchKeys[i%len(benchKeys)], i)
+			i++
+		}
+	})
+}
+
+func BenchmarkMapRange(b *testing.B) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	for i, key := range benchKeys {
+		m.MustSet(ctx, key, i)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m.RangeTyped(ctx, func(key string, value int) bool { return true })
+		}
+	})
+}
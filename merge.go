@@ -0,0 +1,100 @@
+package inithook
+
+import (
+	"context"
+	"maps"
+	"time"
+)
+
+// MergeStrategy decides how to handle keys which exist in both maps when merging
+type MergeStrategy int
+
+// merge strategies
+const (
+	// MergeSkipExisting keeps the existing instances
+	MergeSkipExisting MergeStrategy = iota
+	// MergeOverwrite overrides the existing instances
+	MergeOverwrite
+	// MergeErrorOnConflict returns `ErrAlreadyExists` error(use `errors.Is` to assert) and merges nothing
+	MergeErrorOnConflict
+)
+
+// Clone returns a new map with the same options and a copy of all items with their expirations, metadata and providers,
+// and the aliases and deprecations, while the history and the sealed state are not copied, and the clone uses the default `Store`,
+// if an item is rejected by the validators(see `WithValidator`) then return the error
+func (m *Map[K, V]) Clone(ctx context.Context) (*Map[K, V], error) {
+	m.lock.RLock()
+	values := make(map[K]V, m.store.Len())
+	m.each(func(k K, v V) bool {
+		values[k] = v
+		return true
+	})
+	expires := make(map[K]time.Time, len(m.expires))
+	for k, t := range m.expires {
+		if _, ok := values[k]; ok { // not expired
+			expires[k] = t
+		}
+	}
+	meta := maps.Clone(m.meta)
+	providers := maps.Clone(m.providers)
+	m.lock.RUnlock()
+	m.aliasesLock.RLock()
+	aliases := maps.Clone(m.aliases)
+	m.aliasesLock.RUnlock()
+	m.deprecatedLock.RLock()
+	deprecated := maps.Clone(m.deprecated)
+	m.deprecatedLock.RUnlock()
+
+	clone := NewMap(m.opts...)
+	if err := clone.SetMany(ctx, values); err != nil {
+		return nil, err
+	}
+	clone.lock.Lock()
+	if len(expires) > 0 {
+		clone.expires = expires
+	}
+	clone.meta, clone.providers = meta, providers
+	clone.lock.Unlock()
+	clone.aliasesLock.Lock()
+	clone.aliases = aliases
+	clone.hasAliases.Store(len(aliases) > 0)
+	clone.aliasesLock.Unlock()
+	clone.deprecatedLock.Lock()
+	clone.deprecated = deprecated
+	clone.hasDeprecated.Store(len(deprecated) > 0)
+	clone.deprecatedLock.Unlock()
+	return clone, nil
+}
+
+// Merge merges all items of other into m with strategy under a single write lock of m
+func (m *Map[K, V]) Merge(ctx context.Context, other *Map[K, V], strategy MergeStrategy) error {
+	values := m.keys(other.Map(ctx))
+	if err := m.validateMany(ctx, "Merge", values); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("Merge")
+	}
+	if strategy == MergeErrorOnConflict {
+		for key := range values {
+			if m.exists(key) {
+				m.lock.Unlock()
+				return m.errAlreadyExists("Merge", key)
+			}
+		}
+	}
+	events := make([]Event[K, V], 0, len(values))
+	for key, value := range values {
+		old, loaded := m.load(key)
+		if loaded && strategy == MergeSkipExisting {
+			continue
+		}
+		m.put(key, value)
+		events = append(events, Event[K, V]{Type: EventSet, Key: key, OldValue: old, NewValue: value, Loaded: loaded})
+	}
+	m.lock.Unlock()
+	m.notify(events...)
+	return m.release(ctx, events...)
+}
@@ -0,0 +1,173 @@
+package inithook
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metadata describes a registered instance, used for introspection
+type Metadata struct {
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// RegisterOption used to configure a registration
+type RegisterOption func(o *registerOptions)
+
+type registerOptions struct {
+	overwrite bool
+	metadata  Metadata
+	scope     Scope
+}
+
+func newRegisterOptions(opts []RegisterOption) *registerOptions {
+	o := &registerOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithOverwrite overrides the existing instance instead of returning `ErrAlreadyExists` error
+func WithOverwrite() RegisterOption {
+	return func(o *registerOptions) {
+		o.overwrite = true
+	}
+}
+
+// WithDescription attaches a description to the registered instance
+func WithDescription(description string) RegisterOption {
+	return func(o *registerOptions) {
+		o.metadata.Description = description
+	}
+}
+
+// WithTags attaches tags to the registered instance
+func WithTags(tags ...string) RegisterOption {
+	return func(o *registerOptions) {
+		o.metadata.Tags = append(o.metadata.Tags, tags...)
+	}
+}
+
+// Describe returns the metadata attached when key registered, if not found return `NotFound` error(use `errors.Is` to assert)
+func (m *Map[K, V]) Describe(ctx context.Context, key K) (Metadata, error) {
+	key = m.key(key)
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if !m.exists(key) {
+		return Metadata{}, m.newError("Describe", key, ErrNotFound, nil)
+	}
+	if meta := m.meta[key]; meta != nil {
+		return Metadata{
+			Description: meta.metadata.Description,
+			Tags:        append([]string(nil), meta.metadata.Tags...),
+		}, nil
+	}
+	return Metadata{}, nil
+}
+
+// Info describes when and where an instance was registered, which is recorded only if `WithRegistrationInfo` is used
+type Info struct {
+	RegisteredAt time.Time `json:"registered_at"`
+	Caller       Caller    `json:"caller"`
+}
+
+// Caller is the location of the code which registered an instance
+type Caller struct {
+	Package  string `json:"package"`
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+}
+
+// String returns `file:line`
+func (c Caller) String() string {
+	return c.File + ":" + strconv.Itoa(c.Line)
+}
+
+// Info returns when and where key was registered, ok is false if key not found or info not recorded
+func (m *Map[K, V]) Info(ctx context.Context, key K) (info Info, ok bool) {
+	key = m.key(key)
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if !m.exists(key) {
+		return Info{}, false
+	}
+	if meta := m.meta[key]; meta != nil && meta.info != nil {
+		return *meta.info, true
+	}
+	return Info{}, false
+}
+
+type entryMeta struct {
+	metadata Metadata
+	info     *Info
+}
+
+// describe stores the metadata of key, must be called with write lock held
+func (m *Map[K, V]) describe(key K, o *registerOptions, info *Info) {
+	if o.metadata.Description == "" && len(o.metadata.Tags) == 0 && info == nil {
+		if m.meta != nil {
+			delete(m.meta, key)
+		}
+		return
+	}
+	if m.meta == nil {
+		m.meta = make(map[K]*entryMeta)
+	}
+	m.meta[key] = &entryMeta{metadata: o.metadata, info: info}
+}
+
+// errAlreadyExists returns the `ErrAlreadyExists` error of key, which reports where the existing instance was registered
+// and where the conflicting registration comes from if `WithRegistrationInfo` is used, must be called with lock held
+func (m *Map[K, V]) errAlreadyExists(op string, key K) error {
+	m.counters.conflicts.Add(1)
+	err := m.newError(op, key, ErrAlreadyExists, nil)
+	if meta := m.meta[key]; meta != nil && meta.info != nil {
+		err.Origin = meta.info.Caller.String()
+	}
+	m.logError(context.Background(), "inithook: register conflict", key, err)
+	return err
+}
+
+// registrationInfo returns the registration info of the current registration if enabled
+func (m *Map[K, V]) registrationInfo() *Info {
+	if !m.recordInfo {
+		return nil
+	}
+	return &Info{
+		RegisteredAt: time.Now(),
+		Caller:       callerOutside(),
+	}
+}
+
+// callerOutside returns the first caller on the stack outside of this package
+func callerOutside() Caller {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(2, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		pkg := funcPackage(frame.Function)
+		if pkg != thisPackage {
+			return Caller{Package: pkg, Function: frame.Function, File: frame.File, Line: frame.Line}
+		}
+		if !more {
+			return Caller{}
+		}
+	}
+}
+
+const thisPackage = "github.com/ccmonky/inithook"
+
+// funcPackage returns the package path of a fully qualified function name, e.g. `github.com/a/b.(*T).F` => `github.com/a/b`
+func funcPackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
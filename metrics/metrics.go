@@ -0,0 +1,97 @@
+// Package metrics exports inithook registries' metrics as a prometheus.Collector
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ccmonky/inithook"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Registry is the part of `inithook.Map` used by Collector
+type Registry interface {
+	Len(ctx context.Context) int
+	Stats(ctx context.Context) inithook.Stats
+}
+
+var (
+	sizeDesc = prometheus.NewDesc(
+		"inithook_registry_size",
+		"Number of instances in the registry.",
+		[]string{"registry"}, nil,
+	)
+	operationsDesc = prometheus.NewDesc(
+		"inithook_registry_operations_total",
+		"Number of operations on the registry by operation.",
+		[]string{"registry", "operation"}, nil,
+	)
+	errorsDesc = prometheus.NewDesc(
+		"inithook_registry_errors_total",
+		"Number of failed operations on the registry by error.",
+		[]string{"registry", "error"}, nil,
+	)
+)
+
+// NewCollector creates a new Collector, which should be registered by `prometheus.Register`
+func NewCollector() *Collector {
+	return &Collector{
+		registries: make(map[string]Registry),
+	}
+}
+
+// Collector is a prometheus.Collector which exports the size and operation counters of registries labeled by name
+type Collector struct {
+	registries map[string]Registry
+	lock       sync.RWMutex
+}
+
+// Add adds registry labeled by name, if name exists then return `inithook.ErrAlreadyExists` error(use `errors.Is` to assert)
+func (c *Collector) Add(name string, registry Registry) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.registries[name]; ok {
+		return fmt.Errorf("registry %s: %w", name, inithook.ErrAlreadyExists)
+	}
+	c.registries[name] = registry
+	return nil
+}
+
+// Remove removes the registry labeled by name
+func (c *Collector) Remove(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.registries, name)
+}
+
+// Describe implements prometheus.Collector
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- sizeDesc
+	ch <- operationsDesc
+	ch <- errorsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	ctx := context.Background()
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for name, registry := range c.registries {
+		stats := registry.Stats(ctx)
+		ch <- prometheus.MustNewConstMetric(sizeDesc, prometheus.GaugeValue, float64(registry.Len(ctx)), name)
+		for operation, n := range map[string]uint64{
+			"get":      stats.Gets,
+			"hit":      stats.Hits,
+			"miss":     stats.Misses,
+			"default":  stats.Defaults,
+			"register": stats.Registers,
+			"set":      stats.Sets,
+			"delete":   stats.Deletes,
+		} {
+			ch <- prometheus.MustNewConstMetric(operationsDesc, prometheus.CounterValue, float64(n), name, operation)
+		}
+		ch <- prometheus.MustNewConstMetric(errorsDesc, prometheus.CounterValue, float64(stats.Misses), name, "not_found")
+		ch <- prometheus.MustNewConstMetric(errorsDesc, prometheus.CounterValue, float64(stats.Conflicts), name, "already_exists")
+	}
+}
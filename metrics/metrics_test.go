@@ -0,0 +1,54 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/ccmonky/inithook/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustRegister(ctx, "a", 1)
+	m.Register(ctx, "a", 2)
+	m.Get(ctx, "a")
+	m.Get(ctx, "missing")
+
+	c := metrics.NewCollector()
+	assert.Nilf(t, c.Add("ints", m), "add")
+	assert.Truef(t, errors.Is(c.Add("ints", m), inithook.ErrAlreadyExists), "add twice")
+	reg := prometheus.NewPedanticRegistry()
+	assert.Nilf(t, reg.Register(c), "register collector")
+
+	expected := `
+# HELP inithook_registry_errors_total Number of failed operations on the registry by error.
+# TYPE inithook_registry_errors_total counter
+inithook_registry_errors_total{error="already_exists",registry="ints"} 1
+inithook_registry_errors_total{error="not_found",registry="ints"} 1
+# HELP inithook_registry_operations_total Number of operations on the registry by operation.
+# TYPE inithook_registry_operations_total counter
+inithook_registry_operations_total{operation="default",registry="ints"} 0
+inithook_registry_operations_total{operation="delete",registry="ints"} 0
+inithook_registry_operations_total{operation="get",registry="ints"} 2
+inithook_registry_operations_total{operation="hit",registry="ints"} 1
+inithook_registry_operations_total{operation="miss",registry="ints"} 1
+inithook_registry_operations_total{operation="register",registry="ints"} 1
+inithook_registry_operations_total{operation="set",registry="ints"} 0
+# HELP inithook_registry_size Number of instances in the registry.
+# TYPE inithook_registry_size gauge
+inithook_registry_size{registry="ints"} 1
+`
+	assert.Nilf(t, testutil.GatherAndCompare(reg, strings.NewReader(expected)), "gather")
+
+	c.Remove("ints")
+	n, err := testutil.GatherAndCount(reg)
+	assert.Nilf(t, err, "gather after remove")
+	assert.Equalf(t, 0, n, "removed")
+}
@@ -0,0 +1,100 @@
+package inithook
+
+import (
+	"context"
+	"strings"
+)
+
+// NamespaceSeparator separates the namespaces and the key in a composed key, e.g. `grpc/interceptor`
+const NamespaceSeparator = "/"
+
+// Namespace is a view of a string keyed map which stores items under keys composed with its name,
+// all keys accepted and returned by Namespace are relative to it
+type Namespace[K ~string, V any] struct {
+	m      *Map[K, V]
+	prefix string
+}
+
+// NewNamespace creates a namespace view of m with name
+func NewNamespace[K ~string, V any](m *Map[K, V], name string) *Namespace[K, V] {
+	return &Namespace[K, V]{m: m, prefix: name + NamespaceSeparator}
+}
+
+// Namespace creates a child namespace view with name
+func (ns *Namespace[K, V]) Namespace(name string) *Namespace[K, V] {
+	return &Namespace[K, V]{m: ns.m, prefix: ns.prefix + name + NamespaceSeparator}
+}
+
+// Name returns the full name of the namespace
+func (ns *Namespace[K, V]) Name() string {
+	return strings.TrimSuffix(ns.prefix, NamespaceSeparator)
+}
+
+// Key returns the composed key of key in the underlying map
+func (ns *Namespace[K, V]) Key(key K) K {
+	return K(ns.prefix) + key
+}
+
+// MustRegister register a V's instance with key in the namespace, if failed(e.g. already exists) then panic
+func (ns *Namespace[K, V]) MustRegister(ctx context.Context, key K, value V) {
+	ns.m.MustRegister(ctx, ns.Key(key), value)
+}
+
+// Register register a V's instance with key in the namespace, if exists then return `ErrAlreadyExists` error(use `errors.Is` to assert)
+func (ns *Namespace[K, V]) Register(ctx context.Context, key K, value V) error {
+	return ns.m.Register(ctx, ns.Key(key), value)
+}
+
+// MustSet set a V's instance with key in the namespace, if exists then override, if failed then panic
+func (ns *Namespace[K, V]) MustSet(ctx context.Context, key K, value V) {
+	ns.m.MustSet(ctx, ns.Key(key), value)
+}
+
+// Set set a V's instance with key in the namespace, if exists then override
+func (ns *Namespace[K, V]) Set(ctx context.Context, key K, value V) error {
+	return ns.m.Set(ctx, ns.Key(key), value)
+}
+
+// Get get a V's instance by key in the namespace, if not found return `NotFound` error(use `errors.Is` to assert)
+func (ns *Namespace[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return ns.m.Get(ctx, ns.Key(key))
+}
+
+// Has tells if the namespace has key
+func (ns *Namespace[K, V]) Has(ctx context.Context, key K) bool {
+	return ns.m.Has(ctx, ns.Key(key))
+}
+
+// Delete delete a V's instance specified by key in the namespace
+func (ns *Namespace[K, V]) Delete(ctx context.Context, key K) error {
+	return ns.m.Delete(ctx, ns.Key(key))
+}
+
+// Range calls f sequentially for each key and value in the namespace subtree(including child namespaces),
+// key is relative to the namespace. If f returns false or ctx is done, range stops the iteration.
+func (ns *Namespace[K, V]) Range(ctx context.Context, fn func(key K, value V) bool) {
+	for k, v := range ns.Map(ctx) {
+		if ctx.Err() != nil || !fn(k, v) {
+			return
+		}
+	}
+}
+
+// Keys return all relative keys in the namespace subtree
+func (ns *Namespace[K, V]) Keys(ctx context.Context) []K {
+	var keys []K
+	for k := range ns.Map(ctx) {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Map return map with all items in the namespace subtree, keyed by relative keys
+func (ns *Namespace[K, V]) Map(ctx context.Context) map[K]V {
+	values := GetByPrefix(ctx, ns.m, ns.prefix)
+	relative := make(map[K]V, len(values))
+	for k, v := range values {
+		relative[K(strings.TrimPrefix(string(k), ns.prefix))] = v
+	}
+	return relative
+}
@@ -0,0 +1,189 @@
+package inithook
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Option used to configure a Map
+type Option[K comparable, V any] func(m *Map[K, V])
+
+// WithOnEvict sets a callback which is invoked when an instance is evicted(e.g. expired), not when explicitly deleted
+func WithOnEvict[K comparable, V any](fn func(key K, value V)) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.onEvict = fn
+	}
+}
+
+// WithKeyNormalizer sets a func which normalizes every key accepted by the map(e.g. `strings.ToLower`),
+// so keys which normalized to the same one resolve to the same instance, keys are stored normalized
+func WithKeyNormalizer[K comparable, V any](fn func(key K) K) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.normalizer = fn
+	}
+}
+
+// WithRegistrationInfo records the registration time and the caller location of every registration,
+// which can be retrieved by `Info`, and the `ErrAlreadyExists` error reports the location of the original registration
+func WithRegistrationInfo[K comparable, V any]() Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.recordInfo = true
+	}
+}
+
+// WithValidator adds a validator which validates every value before stored,
+// values rejected are reported as `ErrInvalidValue` error(use `errors.Is` to assert)
+func WithValidator[K comparable, V any](fn func(ctx context.Context, key K, value V) error) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.validators = append(m.validators, fn)
+	}
+}
+
+// WithKeyValidator adds a key validator which validates every key before a value, provider or alias registered with it,
+// e.g. `NonEmptyKey`, `KeyPattern`, keys rejected are reported as `ErrInvalidKey` error(use `errors.Is` to assert)
+func WithKeyValidator[K comparable, V any](fn func(key K) error) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.keyValidators = append(m.keyValidators, fn)
+	}
+}
+
+// WithDefaultFunc attaches the defaulting to the map(see `Map.Default`) instead of requiring V to implement `DefaultLoaderK`,
+// `DefaultLoader` or `Default`, e.g. for builtin types like string and int, NOTE: it's named apart from the attr option `WithDefault`
+func WithDefaultFunc[K comparable, V any](fn func(ctx context.Context, key K) (V, error)) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.defaultFunc = fn
+	}
+}
+
+// WithDefaultCache memoizes the default values(see `Map.Default`) per key, loaded once unless failed,
+// e.g. for costly `DefaultLoader`s, use `Map.ForgetDefaults` to drop them
+func WithDefaultCache[K comparable, V any]() Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.cacheDefaults = true
+	}
+}
+
+// WithRejectNil rejects nil values(including nil pointers, maps, funcs, chans and typed-nil interfaces)
+// as `ErrInvalidValue` error(use `errors.Is` to assert)
+func WithRejectNil[K comparable, V any]() Option[K, V] {
+	return WithValidator(func(ctx context.Context, key K, value V) error {
+		if isNil(value) {
+			return errNilValue
+		}
+		return nil
+	})
+}
+
+// WithCopyOnRead makes Get/GetDefault/GetOrSet/GetOrCompute/GetMany/Values/Map return the copies of the instances by fn,
+// so consumers mutating the result never alias the registered instances(e.g. shared config structs),
+// if fn is nil then the values implementing `Cloner` are deep copied by `Clone` and the others returned as is
+func WithCopyOnRead[K comparable, V any](fn func(v V) V) Option[K, V] {
+	return func(m *Map[K, V]) {
+		if fn == nil {
+			fn = clone[V]
+		}
+		m.copyOnRead = fn
+	}
+}
+
+// WithImmutabilityCheck hashes every value when stored and re-verifies it on Get/GetDefault, panics if an instance
+// is mutated in place after stored, a debug mode to catch illegal post-init mutations in tests since hashing is costly,
+// if hasher is nil then `DeepHash` is used
+func WithImmutabilityCheck[K comparable, V any](hasher func(v V) uint64) Option[K, V] {
+	return func(m *Map[K, V]) {
+		if hasher == nil {
+			hasher = DeepHash[V]
+		}
+		m.hasher = hasher
+	}
+}
+
+// WithReentrancyCheck panics with the methods and call sites of both lockings when a goroutine holding the lock of the map
+// locks it again, e.g. calls Set in the callback of Range, instead of silently deadlocking, a debug mode since it's costly
+func WithReentrancyCheck[K comparable, V any]() Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.lock.check = true
+		m.lock.typeName = typeName[V]()
+	}
+}
+
+// WithWeigher bounds the total weight of the instances memoized by providers(see `RegisterProvider`) to limit,
+// when exceeded the earliest memoized ones are dropped and constructed again by their providers on the next Get,
+// the dropped instances are reported to the `WithOnEvict` callback and watchers as `EventReclaim`,
+// e.g. weigh the regenerable caches by their size in bytes, see also `Map.Reclaim` and `Map.ReclaimOnGC`
+func WithWeigher[K comparable, V any](limit int64, weigher func(key K, value V) int64) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.weightLimit = limit
+		m.weigher = weigher
+	}
+}
+
+// WithHistory keeps the last n replaced V's instances per key, so that a misbehaving new one can be reverted by `Map.Rollback`,
+// the history of a key is dropped when it's deleted, if `WithAutoClose` is used the replaced ones are closed only when
+// they are dropped from the history, i.e. beyond the limit, rolled back over or the key deleted
+func WithHistory[K comparable, V any](n int) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.historySize = n
+	}
+}
+
+// WithAutoClose closes the values implementing `Shutdowner` or `io.Closer` when they are deleted, cleared or replaced
+// by Delete/DeleteMany/Clear/Set/SetMany/SetWithTTL/Register/Update/CompareAndSwap/Merge/Restore/Rollback/Tx,
+// the close errors are aggregated and returned(ignored by CompareAndSwap),
+// NOTE: values returned to the caller(e.g. by Pop, Swap) and values evicted are not closed
+func WithAutoClose[K comparable, V any]() Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.autoClose = true
+	}
+}
+
+// WithDeprecationHandler sets the handler which is invoked when a deprecated key is got, default to log the message
+func WithDeprecationHandler[K comparable, V any](fn func(ctx context.Context, key K, message string)) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.deprecationHandler = fn
+	}
+}
+
+// WithKeyCodec sets the funcs which encode keys to and decode keys from strings, used by `MarshalJSON` and `UnmarshalJSON`
+func WithKeyCodec[K comparable, V any](encode func(key K) (string, error), decode func(s string) (K, error)) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.keyEncoder = encode
+		m.keyDecoder = decode
+	}
+}
+
+// WithCodec sets the codec used by `Encode` and `Decode`, default to `GobCodec`
+func WithCodec[K comparable, V any](codec Codec) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.valueCodec = codec
+	}
+}
+
+// WithName sets the name of the map, which is used to identify the registry, e.g. as the tracing attribute
+func WithName[K comparable, V any](name string) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.name = name
+	}
+}
+
+// WithTracer sets the tracer which traces provider construction and default loading, default to the global one(see `SetTracer`)
+func WithTracer[K comparable, V any](t Tracer) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.tracer = t
+	}
+}
+
+// WithLogger logs registrations, overrides, deletions and errors by logger with the registry name, key and type attributes,
+// the levels default to `DefaultLogLevels`, see `WithLogLevels`
+func WithLogger[K comparable, V any](logger *slog.Logger) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.logger = logger
+	}
+}
+
+// WithLogLevels sets the levels of the lines logged by the logger of `WithLogger`
+func WithLogLevels[K comparable, V any](levels LogLevels) Option[K, V] {
+	return func(m *Map[K, V]) {
+		m.logLevels = levels
+	}
+}
@@ -0,0 +1,101 @@
+package inithook
+
+import (
+	"container/list"
+	"context"
+	"sort"
+)
+
+// NewOrderedMap creates a new map which preserves insertion order during Range/Keys/Values,
+// setting an existing key keeps its original position
+func NewOrderedMap[K comparable, V any](opts ...Option[K, V]) *Map[K, V] {
+	return NewMapWithStore(NewOrderedStore[K, V](), opts...)
+}
+
+// SortedKeys return all keys sorted by less
+func (m *Map[K, V]) SortedKeys(ctx context.Context, less func(a, b K) bool) []K {
+	keys := m.Keys(ctx)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+	return keys
+}
+
+// RangeSorted calls f sequentially for each key and value present in the map in the order sorted by less.
+// If f returns false or ctx is done, range stops the iteration.
+func (m *Map[K, V]) RangeSorted(ctx context.Context, less func(a, b K) bool, fn func(key K, value V) bool) {
+	type item struct {
+		key   K
+		value V
+	}
+	m.lock.RLock()
+	items := make([]item, 0, m.store.Len())
+	m.each(func(k K, v V) bool {
+		items = append(items, item{key: k, value: v})
+		return true
+	})
+	m.lock.RUnlock()
+	sort.SliceStable(items, func(i, j int) bool {
+		return less(items[i].key, items[j].key)
+	})
+	for _, item := range items {
+		if ctx.Err() != nil || !fn(item.key, item.value) {
+			return
+		}
+	}
+}
+
+// NewOrderedStore creates a new Store which ranges in insertion order
+func NewOrderedStore[K comparable, V any]() Store[K, V] {
+	return &orderedStore[K, V]{items: map[K]*list.Element{}, order: list.New()}
+}
+
+type orderedStore[K comparable, V any] struct {
+	items map[K]*list.Element
+	order *list.List
+}
+
+type orderedItem[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+func (s *orderedStore[K, V]) Load(key K) (V, bool) {
+	if e, ok := s.items[key]; ok {
+		return e.Value.(*orderedItem[K, V]).value, true
+	}
+	return *new(V), false
+}
+
+func (s *orderedStore[K, V]) Store(key K, value V) {
+	if e, ok := s.items[key]; ok {
+		e.Value.(*orderedItem[K, V]).value = value
+		return
+	}
+	s.items[key] = s.order.PushBack(&orderedItem[K, V]{key: key, value: value})
+}
+
+func (s *orderedStore[K, V]) Delete(key K) {
+	if e, ok := s.items[key]; ok {
+		s.order.Remove(e)
+		delete(s.items, key)
+	}
+}
+
+func (s *orderedStore[K, V]) Clear() {
+	s.items = map[K]*list.Element{}
+	s.order.Init()
+}
+
+func (s *orderedStore[K, V]) Len() int {
+	return len(s.items)
+}
+
+func (s *orderedStore[K, V]) Range(fn func(key K, value V) bool) {
+	for e := s.order.Front(); e != nil; e = e.Next() {
+		item := e.Value.(*orderedItem[K, V])
+		if !fn(item.key, item.value) {
+			return
+		}
+	}
+}
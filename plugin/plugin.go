@@ -0,0 +1,418 @@
+// Package plugin loads external plugins which register hooks and instances into the host registries through a handshake.
+//
+// An exec plugin is an executable which calls `Serve`, when launched by `Load` it writes the handshake to stdout,
+// i.e. the protocol version, the instances to register into the named registries(see `inithook.RegisterMap`)
+// and the hooks to register into the host `inithook.Hooks`, then serves the calls of the hooks over stdin and stdout
+// until the host closes it. The instances are encoded as json, so the value type of the registry should be decodable.
+//
+// A shared plugin is a Go plugin(.so built by `go build -buildmode=plugin`) which exports the symbol named `Symbol`
+// of type `func(ctx context.Context, hooks *inithook.Hooks) error`, it runs in the host process and shares the registries,
+// it must be built with the same versions of the host and its dependencies
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ccmonky/inithook"
+)
+
+// ProtocolVersion is the version of the handshake and call protocol, a plugin of another version is rejected
+const ProtocolVersion = 1
+
+// MagicCookieKey and MagicCookieValue are the env set by the host to launch an exec plugin,
+// they are not a security measure but tell `Serve` it's launched as a plugin
+const (
+	MagicCookieKey   = "INITHOOK_PLUGIN_MAGIC_COOKIE"
+	MagicCookieValue = "7d5c0b1e4f1a4d2b9c3e8a6f0b2d4e6c"
+)
+
+// Symbol is the name of the symbol exported by a shared plugin
+const Symbol = "InithookPlugin"
+
+var (
+	// ErrNotPlugin defines the error of `Serve` not launched by a host
+	ErrNotPlugin = errors.New("not launched as a plugin")
+
+	// ErrHandshake defines the error of a plugin failing the handshake
+	ErrHandshake = errors.New("plugin handshake failed")
+
+	// ErrClosed defines the error of calling the hooks of a closed or exited plugin
+	ErrClosed = errors.New("plugin closed")
+
+	// ErrUnsupported defines the error of loading a shared plugin on the platforms or builds without Go plugin support
+	ErrUnsupported = errors.New("shared plugin unsupported")
+)
+
+// Registration is an instance to register into the named registry of the host
+type Registration struct {
+	Registry string          `json:"registry"`
+	Key      string          `json:"key"`
+	Value    json.RawMessage `json:"value"`
+}
+
+// Hook is a hook of a plugin, the hook registered into the host calls the plugin
+type Hook struct {
+	Phase    string   `json:"phase"` // init, start, shutdown or reload
+	Name     string   `json:"name"`
+	Priority int      `json:"priority,omitempty"`
+	Requires []string `json:"requires,omitempty"`
+
+	Func inithook.HookFunc `json:"-"` // executed in the plugin process
+}
+
+// handshake is written by the plugin once launched
+type handshake struct {
+	Protocol      int            `json:"protocol"`
+	Name          string         `json:"name"`
+	Registrations []Registration `json:"registrations,omitempty"`
+	Hooks         []Hook         `json:"hooks,omitempty"`
+}
+
+// request is a call of a hook, or the cancellation of the call of ID
+type request struct {
+	ID     uint64 `json:"id"`
+	Phase  string `json:"phase,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Cancel bool   `json:"cancel,omitempty"`
+}
+
+// response is the result of the call of ID
+type response struct {
+	ID    uint64 `json:"id"`
+	Error string `json:"error,omitempty"`
+}
+
+// Option used to configure the loading
+type Option func(o *options)
+
+type options struct {
+	hooks            *inithook.Hooks
+	args             []string
+	env              []string
+	stderr           io.Writer
+	handshakeTimeout time.Duration
+}
+
+// WithHooks sets the hooks the plugin hooks registered into, default to `inithook.DefaultHooks`
+func WithHooks(hooks *inithook.Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// WithArgs sets the args of an exec plugin
+func WithArgs(args ...string) Option {
+	return func(o *options) {
+		o.args = args
+	}
+}
+
+// WithEnv appends the env(`key=value`) of an exec plugin, which inherits the env of the host
+func WithEnv(env ...string) Option {
+	return func(o *options) {
+		o.env = append(o.env, env...)
+	}
+}
+
+// WithStderr sets the writer of the stderr of an exec plugin, default to `os.Stderr`
+func WithStderr(w io.Writer) Option {
+	return func(o *options) {
+		o.stderr = w
+	}
+}
+
+// WithHandshakeTimeout sets the timeout of the handshake of an exec plugin, default to 10s
+func WithHandshakeTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.handshakeTimeout = timeout
+	}
+}
+
+// Plugin is a loaded plugin
+type Plugin struct {
+	Name string
+
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	enc    *json.Encoder
+	encMu  sync.Mutex
+	lock   sync.Mutex
+	calls  map[uint64]chan response
+	nextID uint64
+	closed bool
+	exited chan struct{} // closed when the plugin exited
+}
+
+// Load loads the plugin of path, a shared plugin if path ends with .so otherwise an exec plugin, and registers
+// its instances and hooks, all instances are decoded and validated ahead(see `inithook.Map.StageJSON`) and registered last,
+// if anything fails then the exec plugin is killed, no instances are registered but the hooks registered ahead are kept.
+// If a registry is not found then return `inithook.ErrNotFound` error, and if the handshake failed then `ErrHandshake`
+func Load(ctx context.Context, path string, opts ...Option) (*Plugin, error) {
+	o := &options{hooks: inithook.DefaultHooks, stderr: os.Stderr, handshakeTimeout: 10 * time.Second}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if strings.HasSuffix(path, ".so") {
+		if err := loadShared(ctx, path, o.hooks); err != nil {
+			return nil, fmt.Errorf("plugin: load %s failed: %w", path, err)
+		}
+		return &Plugin{Name: strings.TrimSuffix(filepath.Base(path), ".so")}, nil
+	}
+	p, err := start(ctx, path, o)
+	if err != nil {
+		return nil, fmt.Errorf("plugin: load %s failed: %w", path, err)
+	}
+	return p, nil
+}
+
+// start launches the exec plugin of path and registers its instances and hooks
+func start(ctx context.Context, path string, o *options) (*Plugin, error) {
+	cmd := exec.Command(path, o.args...)
+	cmd.Env = append(append(os.Environ(), o.env...), MagicCookieKey+"="+MagicCookieValue)
+	cmd.Stderr = o.stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &Plugin{
+		cmd:    cmd,
+		stdin:  stdin,
+		enc:    json.NewEncoder(stdin),
+		calls:  make(map[uint64]chan response),
+		exited: make(chan struct{}),
+	}
+	dec := json.NewDecoder(stdout)
+	hs, err := p.handshake(ctx, dec, o.handshakeTimeout)
+	if err == nil {
+		p.Name = hs.Name
+		err = register(ctx, hs, p, o.hooks)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	go p.receive(dec)
+	return p, nil
+}
+
+// handshake reads the handshake of the plugin
+func (p *Plugin) handshake(ctx context.Context, dec *json.Decoder, timeout time.Duration) (*handshake, error) {
+	result := make(chan error, 1)
+	var hs handshake
+	go func() {
+		result <- dec.Decode(&hs)
+	}()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-result:
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrHandshake, err)
+		}
+	case <-timer.C:
+		return nil, fmt.Errorf("%w: timeout after %s", ErrHandshake, timeout)
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %w", ErrHandshake, ctx.Err())
+	}
+	if hs.Protocol != ProtocolVersion {
+		return nil, fmt.Errorf("%w: protocol version %d, should be %d", ErrHandshake, hs.Protocol, ProtocolVersion)
+	}
+	return &hs, nil
+}
+
+// registry is implemented by `*inithook.Map`
+type registry interface {
+	StageJSON(ctx context.Context, old, new []byte) (func(ctx context.Context) error, error)
+}
+
+// register registers the instances and hooks of hs, the instances are staged before anything registered
+func register(ctx context.Context, hs *handshake, p *Plugin, hooks *inithook.Hooks) error {
+	objects := make(map[string]map[string]json.RawMessage)
+	for _, r := range hs.Registrations {
+		if objects[r.Registry] == nil {
+			objects[r.Registry] = make(map[string]json.RawMessage)
+		}
+		objects[r.Registry][r.Key] = r.Value
+	}
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	swaps := make([]func(ctx context.Context) error, 0, len(names))
+	for _, name := range names {
+		reg, err := lookup(name)
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(objects[name])
+		if err != nil {
+			return fmt.Errorf("registry %s: %w", name, err)
+		}
+		swap, err := reg.StageJSON(ctx, nil, data)
+		if err != nil {
+			return fmt.Errorf("registry %s: %w", name, err)
+		}
+		swaps = append(swaps, swap)
+	}
+	for _, hk := range hs.Hooks {
+		var add func(name string, fn inithook.HookFunc, opts ...inithook.HookOption) error
+		switch hk.Phase {
+		case inithook.PhaseInit.String():
+			add = hooks.OnInit
+		case inithook.PhaseStart.String():
+			add = hooks.OnStart
+		case inithook.PhaseShutdown.String():
+			add = hooks.OnShutdown
+		case inithook.PhaseReload.String():
+			add = hooks.OnReload
+		default:
+			return fmt.Errorf("hook %s: unknown phase %q", hk.Name, hk.Phase)
+		}
+		phase, name := hk.Phase, hk.Name
+		fn := func(ctx context.Context) error {
+			return p.call(ctx, phase, name)
+		}
+		if err := add(name, fn, inithook.WithPriority(hk.Priority), inithook.WithRequires(hk.Requires...)); err != nil {
+			return err
+		}
+	}
+	for i, swap := range swaps {
+		if err := swap(ctx); err != nil {
+			return fmt.Errorf("registry %s: %w", names[i], err)
+		}
+	}
+	return nil
+}
+
+// lookup looks up the named registry
+func lookup(name string) (registry, error) {
+	var infos []inithook.RegistryInfo
+	for _, info := range inithook.Registries() {
+		if info.Name == name {
+			infos = append(infos, info)
+		}
+	}
+	switch len(infos) {
+	case 0:
+		return nil, fmt.Errorf("registry %s: %w", name, inithook.ErrNotFound)
+	case 1:
+	default:
+		return nil, fmt.Errorf("registry %s: ambiguous, %d registries of different types", name, len(infos))
+	}
+	reg, err := inithook.Registry(infos[0])
+	if err != nil {
+		return nil, err
+	}
+	r, ok := reg.(registry)
+	if !ok {
+		return nil, fmt.Errorf("registry %s: %T should be an *inithook.Map", name, reg)
+	}
+	return r, nil
+}
+
+// receive receives the responses until the plugin exited
+func (p *Plugin) receive(dec *json.Decoder) {
+	for {
+		var resp response
+		if err := dec.Decode(&resp); err != nil {
+			break
+		}
+		p.lock.Lock()
+		if ch, ok := p.calls[resp.ID]; ok {
+			delete(p.calls, resp.ID)
+			ch <- resp
+		}
+		p.lock.Unlock()
+	}
+	p.cmd.Wait()
+	close(p.exited)
+}
+
+// call calls the hook of the plugin, and cancels the call if ctx is done
+func (p *Plugin) call(ctx context.Context, phase, name string) error {
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return fmt.Errorf("plugin %s: %w", p.Name, ErrClosed)
+	}
+	p.nextID++
+	id := p.nextID
+	ch := make(chan response, 1)
+	p.calls[id] = ch
+	p.lock.Unlock()
+	if err := p.send(request{ID: id, Phase: phase, Name: name}); err != nil {
+		p.forget(id)
+		return fmt.Errorf("plugin %s: %w: %w", p.Name, ErrClosed, err)
+	}
+	select {
+	case resp := <-ch:
+		if resp.Error != "" {
+			return fmt.Errorf("plugin %s: %s", p.Name, resp.Error)
+		}
+		return nil
+	case <-ctx.Done():
+		p.forget(id)
+		p.send(request{ID: id, Cancel: true})
+		return ctx.Err()
+	case <-p.exited:
+		return fmt.Errorf("plugin %s: %w", p.Name, ErrClosed)
+	}
+}
+
+func (p *Plugin) send(req request) error {
+	p.encMu.Lock()
+	defer p.encMu.Unlock()
+	return p.enc.Encode(req)
+}
+
+func (p *Plugin) forget(id uint64) {
+	p.lock.Lock()
+	delete(p.calls, id)
+	p.lock.Unlock()
+}
+
+// Close closes the exec plugin and waits for its exit, it's killed if not exited in timeout, calling its hooks then
+// return `ErrClosed` error, note the hooks are still registered. Closing a shared plugin does nothing
+func (p *Plugin) Close(timeout time.Duration) error {
+	if p.cmd == nil {
+		return nil
+	}
+	p.lock.Lock()
+	if p.closed {
+		p.lock.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.lock.Unlock()
+	p.stdin.Close()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-p.exited:
+		return nil
+	case <-timer.C:
+		p.cmd.Process.Kill()
+		<-p.exited
+		return fmt.Errorf("plugin %s: killed after %s", p.Name, timeout)
+	}
+}
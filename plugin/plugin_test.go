@@ -0,0 +1,104 @@
+package plugin_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/ccmonky/inithook"
+	"github.com/ccmonky/inithook/plugin"
+	"github.com/stretchr/testify/assert"
+)
+
+var ports = inithook.MustRegisterMap[string, int]("plugin_test_ports")
+
+// TestMain serves the test binary as an exec plugin if launched by a host
+func TestMain(m *testing.M) {
+	if os.Getenv(plugin.MagicCookieKey) == plugin.MagicCookieValue {
+		os.Exit(servePlugin())
+	}
+	os.Exit(m.Run())
+}
+
+func servePlugin() int {
+	s := &plugin.Server{Name: "test"}
+	if err := s.Register("plugin_test_ports", "http", 80); err != nil {
+		return 1
+	}
+	if os.Getenv("PLUGIN_TEST_REGISTRY") != "" {
+		s.Register(os.Getenv("PLUGIN_TEST_REGISTRY"), "https", 443)
+	}
+	s.Hooks = []plugin.Hook{
+		{Phase: "init", Name: "plugin.ok", Func: func(ctx context.Context) error {
+			return nil
+		}},
+		{Phase: "init", Name: "plugin.fail", Priority: 1, Requires: []string{"plugin.ok"}, Func: func(ctx context.Context) error {
+			return errors.New("boom")
+		}},
+		{Phase: "start", Name: "plugin.block", Func: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	}
+	if err := plugin.Serve(context.Background(), s); err != nil {
+		return 1
+	}
+	return 0
+}
+
+func TestExecPlugin(t *testing.T) {
+	ctx := context.Background()
+	hooks := inithook.NewHooks()
+	p, err := plugin.Load(ctx, os.Args[0], plugin.WithHooks(hooks))
+	assert.Nilf(t, err, "load")
+	assert.Equalf(t, "test", p.Name, "name")
+	port, err := ports.Get(ctx, "http")
+	assert.Nilf(t, err, "get registered")
+	assert.Equalf(t, 80, port, "port")
+
+	err = hooks.RunPhase(ctx, inithook.PhaseInit)
+	var hookErr *inithook.HookError
+	assert.Truef(t, errors.As(err, &hookErr), "should be hook error: %v", err)
+	assert.Equalf(t, "plugin.fail", hookErr.Name, "failed hook")
+	assert.Containsf(t, err.Error(), "plugin test: boom", "error of plugin")
+
+	err = hooks.RunPhase(ctx, inithook.PhaseStart, inithook.WithRunTimeout(50*time.Millisecond))
+	assert.Truef(t, errors.Is(err, inithook.ErrHookTimeout), "canceled call should time out: %v", err)
+
+	assert.Nilf(t, p.Close(5*time.Second), "close")
+	assert.Nilf(t, p.Close(5*time.Second), "close twice")
+	hooks = inithook.NewHooks()
+	err = hooks.OnInit("plugin.ok", func(ctx context.Context) error { return nil })
+	assert.Nilf(t, err, "register conflicting hook")
+	_, err = plugin.Load(ctx, os.Args[0], plugin.WithHooks(hooks))
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "conflicting hook: %v", err)
+}
+
+func TestExecPluginRegistryNotFound(t *testing.T) {
+	ctx := context.Background()
+	ports.Delete(ctx, "http")
+	_, err := plugin.Load(ctx, os.Args[0], plugin.WithHooks(inithook.NewHooks()), plugin.WithEnv("PLUGIN_TEST_REGISTRY=plugin_test_missing"))
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "missing registry: %v", err)
+	assert.Falsef(t, ports.Has(ctx, "http"), "nothing should be registered")
+}
+
+func TestExecPluginHandshake(t *testing.T) {
+	path, err := exec.LookPath("true")
+	if err != nil {
+		t.Skip("true not found")
+	}
+	_, err = plugin.Load(context.Background(), path, plugin.WithHooks(inithook.NewHooks()))
+	assert.Truef(t, errors.Is(err, plugin.ErrHandshake), "not a plugin: %v", err)
+}
+
+func TestServe(t *testing.T) {
+	err := plugin.Serve(context.Background(), &plugin.Server{Name: "test"})
+	assert.Truef(t, errors.Is(err, plugin.ErrNotPlugin), "not launched by a host: %v", err)
+	var stderr bytes.Buffer
+	_, err = plugin.Load(context.Background(), "testdata/missing", plugin.WithStderr(&stderr))
+	assert.NotNilf(t, err, "missing plugin")
+}
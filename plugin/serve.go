@@ -0,0 +1,110 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Server is the plugin side of an exec plugin, see `Serve`
+type Server struct {
+	Name          string
+	Registrations []Registration
+	Hooks         []Hook
+}
+
+// Register appends the registration of value encoded as json into the named registry of the host
+func (s *Server) Register(registry, key string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("plugin: register %s of %s failed: %w", key, registry, err)
+	}
+	s.Registrations = append(s.Registrations, Registration{Registry: registry, Key: key, Value: data})
+	return nil
+}
+
+// Serve writes the handshake of s to stdout and serves the calls of its hooks until stdin closed by the host,
+// so the plugin should write nothing else to stdout(use stderr instead), a hook call is canceled if the host call canceled.
+// If not launched by a host(see `MagicCookieKey`) then return `ErrNotPlugin` error, the plugin can run standalone then
+func Serve(ctx context.Context, s *Server) error {
+	if os.Getenv(MagicCookieKey) != MagicCookieValue {
+		return ErrNotPlugin
+	}
+	return serve(ctx, s, os.Stdin, os.Stdout)
+}
+
+func serve(ctx context.Context, s *Server, r io.Reader, w io.Writer) error {
+	hooks := make(map[string]Hook, len(s.Hooks))
+	for _, hk := range s.Hooks {
+		hooks[hk.Phase+"/"+hk.Name] = hk
+	}
+	enc := json.NewEncoder(w)
+	var encMu sync.Mutex
+	err := enc.Encode(handshake{Protocol: ProtocolVersion, Name: s.Name, Registrations: s.Registrations, Hooks: s.Hooks})
+	if err != nil {
+		return fmt.Errorf("plugin: handshake failed: %w", err)
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // cancels the running calls before waiting for them
+	var lock sync.Mutex
+	cancels := make(map[uint64]context.CancelFunc)
+	dec := json.NewDecoder(r)
+	for {
+		var req request
+		if err := dec.Decode(&req); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("plugin: receive failed: %w", err)
+		}
+		if req.Cancel {
+			lock.Lock()
+			if cancel, ok := cancels[req.ID]; ok {
+				cancel()
+			}
+			lock.Unlock()
+			continue
+		}
+		hk, ok := hooks[req.Phase+"/"+req.Name]
+		callCtx, callCancel := context.WithCancel(ctx)
+		lock.Lock()
+		cancels[req.ID] = callCancel
+		lock.Unlock()
+		wg.Add(1)
+		go func(req request) {
+			defer wg.Done()
+			var err error
+			if !ok || hk.Func == nil {
+				err = fmt.Errorf("hook %s of phase %s not found", req.Name, req.Phase)
+			} else {
+				err = invoke(callCtx, hk.Func)
+			}
+			lock.Lock()
+			delete(cancels, req.ID)
+			lock.Unlock()
+			callCancel()
+			resp := response{ID: req.ID}
+			if err != nil {
+				resp.Error = err.Error()
+			}
+			encMu.Lock()
+			enc.Encode(resp)
+			encMu.Unlock()
+		}(req)
+	}
+}
+
+// invoke invokes fn, and converts a panic to error
+func invoke(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("hook panic: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
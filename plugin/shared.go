@@ -0,0 +1,28 @@
+//go:build (linux || darwin || freebsd) && cgo
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+	goplugin "plugin"
+
+	"github.com/ccmonky/inithook"
+)
+
+// loadShared opens the Go plugin of path and invokes its `Symbol`
+func loadShared(ctx context.Context, path string, hooks *inithook.Hooks) error {
+	p, err := goplugin.Open(path)
+	if err != nil {
+		return err
+	}
+	sym, err := p.Lookup(Symbol)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrHandshake, err)
+	}
+	fn, ok := sym.(func(ctx context.Context, hooks *inithook.Hooks) error)
+	if !ok {
+		return fmt.Errorf("%w: symbol %s is %T, should be func(context.Context, *inithook.Hooks) error", ErrHandshake, Symbol, sym)
+	}
+	return fn(ctx, hooks)
+}
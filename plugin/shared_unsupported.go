@@ -0,0 +1,14 @@
+//go:build !((linux || darwin || freebsd) && cgo)
+
+package plugin
+
+import (
+	"context"
+
+	"github.com/ccmonky/inithook"
+)
+
+// loadShared returns `ErrUnsupported` error without Go plugin support
+func loadShared(ctx context.Context, path string, hooks *inithook.Hooks) error {
+	return ErrUnsupported
+}
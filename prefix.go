@@ -0,0 +1,139 @@
+package inithook
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+)
+
+// GetByPrefix returns all items of a string keyed map whose key has prefix, it scans all keys,
+// use `PrefixIndex` for large maps with frequent prefix lookups
+func GetByPrefix[K ~string, V any](ctx context.Context, m *Map[K, V], prefix string) map[K]V {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	values := make(map[K]V)
+	m.each(func(k K, v V) bool {
+		if strings.HasPrefix(string(k), prefix) {
+			values[k] = v
+		}
+		return true
+	})
+	return values
+}
+
+// Match returns all items of a string keyed map whose key matches glob, the glob syntax is the same as `path.Match`,
+// so `*` does not match `/`, returns `path.ErrBadPattern` if glob is malformed
+func Match[K ~string, V any](ctx context.Context, m *Map[K, V], glob string) (map[K]V, error) {
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, err
+	}
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	values := make(map[K]V)
+	m.each(func(k K, v V) bool {
+		if ok, _ := path.Match(glob, string(k)); ok {
+			values[k] = v
+		}
+		return true
+	})
+	return values, nil
+}
+
+// PrefixIndex is a trie index of the keys of a string keyed map, which is kept in sync by watching the map,
+// thus prefix lookups only visit the matched keys
+type PrefixIndex[K ~string, V any] struct {
+	m      *Map[K, V]
+	root   *trieNode
+	lock   sync.RWMutex
+	cancel func()
+}
+
+type trieNode struct {
+	children map[byte]*trieNode
+	terminal bool
+}
+
+// NewPrefixIndex creates a prefix index of m, the index is maintained until ctx done or `Close` called
+func NewPrefixIndex[K ~string, V any](ctx context.Context, m *Map[K, V]) *PrefixIndex[K, V] {
+	idx := &PrefixIndex[K, V]{m: m, root: &trieNode{}}
+	idx.cancel = m.Watch(ctx, func(ev Event[K, V]) {
+		idx.lock.Lock()
+		defer idx.lock.Unlock()
+		switch ev.Type {
+		case EventDelete, EventClear:
+			idx.root.remove(string(ev.Key), 0)
+		case EventReclaim: // the key still exists
+		default:
+			idx.root.insert(string(ev.Key))
+		}
+	})
+	idx.lock.Lock()
+	for _, key := range m.Keys(ctx) {
+		idx.root.insert(string(key))
+	}
+	idx.lock.Unlock()
+	return idx
+}
+
+// Close stops maintaining the index
+func (idx *PrefixIndex[K, V]) Close() {
+	idx.cancel()
+}
+
+// Keys returns all keys with prefix
+func (idx *PrefixIndex[K, V]) Keys(ctx context.Context, prefix string) []K {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	node := idx.root
+	for i := 0; i < len(prefix); i++ {
+		node = node.children[prefix[i]]
+		if node == nil {
+			return nil
+		}
+	}
+	var keys []K
+	node.walk([]byte(prefix), func(key string) {
+		keys = append(keys, K(key))
+	})
+	return keys
+}
+
+// GetByPrefix returns all items whose key has prefix
+func (idx *PrefixIndex[K, V]) GetByPrefix(ctx context.Context, prefix string) map[K]V {
+	return idx.m.GetMany(ctx, idx.Keys(ctx, prefix))
+}
+
+func (n *trieNode) insert(key string) {
+	for i := 0; i < len(key); i++ {
+		if n.children == nil {
+			n.children = map[byte]*trieNode{}
+		}
+		child := n.children[key[i]]
+		if child == nil {
+			child = &trieNode{}
+			n.children[key[i]] = child
+		}
+		n = child
+	}
+	n.terminal = true
+}
+
+// remove removes key from the subtree and tells if n becomes empty
+func (n *trieNode) remove(key string, depth int) bool {
+	if depth == len(key) {
+		n.terminal = false
+	} else if child := n.children[key[depth]]; child != nil && child.remove(key, depth+1) {
+		delete(n.children, key[depth])
+	}
+	return !n.terminal && len(n.children) == 0
+}
+
+func (n *trieNode) walk(prefix []byte, fn func(key string)) {
+	if n.terminal {
+		fn(string(prefix))
+	}
+	for b, child := range n.children {
+		child.walk(append(prefix, b), fn)
+	}
+}
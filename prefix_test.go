@@ -0,0 +1,71 @@
+package inithook_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetByPrefixAndMatch(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.SetMany(ctx, map[string]int{
+		"/api/v1/users":  1,
+		"/api/v1/orders": 2,
+		"/api/v2/users":  3,
+		"/health":        4,
+	})
+	assert.Equalf(t, map[string]int{"/api/v1/users": 1, "/api/v1/orders": 2}, inithook.GetByPrefix(ctx, m, "/api/v1/"), "prefix")
+	matched, err := inithook.Match(ctx, m, "/api/*/users")
+	assert.Nilf(t, err, "match")
+	assert.Equalf(t, map[string]int{"/api/v1/users": 1, "/api/v2/users": 3}, matched, "match")
+	matched, err = inithook.Match(ctx, m, "/*")
+	assert.Nilf(t, err, "match")
+	assert.Equalf(t, map[string]int{"/health": 4}, matched, "star does not match slash")
+	_, err = inithook.Match(ctx, m, "[")
+	assert.NotNilf(t, err, "bad pattern")
+}
+
+func TestPrefixIndex(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	m.MustSet(ctx, "/api/v1/users", 1)
+	idx := inithook.NewPrefixIndex(ctx, m)
+	defer idx.Close()
+	m.MustSet(ctx, "/api/v1/orders", 2)
+	m.MustSet(ctx, "/api/v2/users", 3)
+	m.MustSet(ctx, "/api", 0)
+	assert.ElementsMatchf(t, []string{"/api/v1/users", "/api/v1/orders"}, idx.Keys(ctx, "/api/v1"), "keys")
+	assert.Equalf(t, map[string]int{"/api/v2/users": 3}, idx.GetByPrefix(ctx, "/api/v2"), "get by prefix")
+	m.MustDelete(ctx, "/api/v1/users")
+	assert.Equalf(t, []string{"/api/v1/orders"}, idx.Keys(ctx, "/api/v1"), "deleted")
+	assert.Lenf(t, idx.Keys(ctx, ""), 3, "all keys")
+	m.MustClear(ctx)
+	assert.Emptyf(t, idx.Keys(ctx, ""), "cleared")
+}
+
+func TestNamespace(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, string]()
+	grpc := inithook.NewNamespace(m, "grpc")
+	grpc.MustRegister(ctx, "interceptor", "auth")
+	grpc.Namespace("server").MustSet(ctx, "interceptor", "recovery")
+	inithook.NewNamespace(m, "http").MustSet(ctx, "interceptor", "cors")
+
+	v, err := m.Get(ctx, "grpc/interceptor")
+	assert.Nilf(t, err, "composed key")
+	assert.Equalf(t, "auth", v, "composed key")
+	assert.Equalf(t, "grpc/server", grpc.Namespace("server").Name(), "name")
+	err = grpc.Register(ctx, "interceptor", "other")
+	assert.ErrorIsf(t, err, inithook.ErrAlreadyExists, "register twice")
+	assert.Equalf(t, map[string]string{
+		"interceptor":        "auth",
+		"server/interceptor": "recovery",
+	}, grpc.Map(ctx), "subtree")
+	assert.ElementsMatchf(t, []string{"interceptor"}, grpc.Namespace("server").Keys(ctx), "child keys")
+	assert.Nilf(t, grpc.Delete(ctx, "interceptor"), "delete")
+	assert.Falsef(t, grpc.Has(ctx, "interceptor"), "deleted")
+	assert.Truef(t, m.Has(ctx, "http/interceptor"), "other namespace untouched")
+}
@@ -0,0 +1,164 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+)
+
+// Provider constructs a V's instance lazily
+type Provider[V any] func(ctx context.Context) (V, error)
+
+type provider[V any] struct {
+	fn    Provider[V]
+	scope Scope
+}
+
+// Scope decides how the instances constructed by a provider are shared
+type Scope int
+
+// provider scopes
+const (
+	// ScopeSingleton constructs the instance once and memoizes it, which is the default scope
+	ScopeSingleton Scope = iota
+	// ScopePrototype constructs a fresh instance on every Get, nothing memoized
+	ScopePrototype
+)
+
+// WithScope sets the scope of a provider registered by `RegisterProvider`
+func WithScope(scope Scope) RegisterOption {
+	return func(o *registerOptions) {
+		o.scope = scope
+	}
+}
+
+// MustRegisterProvider register a provider with key, if failed(e.g. already exists) then panic
+func (m *Map[K, V]) MustRegisterProvider(ctx context.Context, key K, fn Provider[V], opts ...RegisterOption) {
+	err := m.RegisterProvider(ctx, key, fn, opts...)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// RegisterProvider register a provider with key instead of an instance, the provider is invoked on the first Get of key
+// and the result is memoized, the provider is invoked at most once at a time for a key even under heavy concurrency,
+// while the providers of different keys are invoked concurrently without holding the map lock,
+// if the provider returns an error, nothing memoized and the next Get will invoke it again.
+// Use `WithScope(ScopePrototype)` to construct a fresh instance on every Get instead.
+// If key exists then return `ErrAlreadyExists` error(use `errors.Is` to assert), unless `WithOverwrite` is used
+func (m *Map[K, V]) RegisterProvider(ctx context.Context, key K, fn Provider[V], opts ...RegisterOption) error {
+	key = m.key(key)
+	if err := m.validateKey(ctx, "RegisterProvider", key); err != nil {
+		return err
+	}
+	o := newRegisterOptions(opts)
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("RegisterProvider", key)
+	}
+	if m.exists(key) && !o.overwrite {
+		m.lock.Unlock()
+		return m.errAlreadyExists("RegisterProvider", key)
+	}
+	old, loaded := m.load(key)
+	m.remove(key)
+	if m.providers == nil {
+		m.providers = make(map[K]*provider[V])
+	}
+	m.providers[key] = &provider[V]{fn: fn, scope: o.scope}
+	m.describe(key, o, m.registrationInfo())
+	m.lock.Unlock()
+	if !loaded {
+		return nil
+	}
+	ev := Event[K, V]{Type: EventDelete, Key: key, OldValue: old, Loaded: true}
+	m.notify(ev)
+	return m.release(ctx, ev)
+}
+
+// provide resolves key by provider p and memoizes the result,
+// returns `errProviderChanged` if p is deleted or replaced meanwhile, then the caller should look up key again
+func (m *Map[K, V]) provide(ctx context.Context, key K, p *provider[V]) (V, error) {
+	if p.scope == ScopePrototype {
+		value, err := m.construct(ctx, key, p)
+		if err != nil {
+			return value, m.errTimeout("Get", key, err)
+		}
+		return value, m.validate(ctx, "Get", key, value)
+	}
+	v, err := m.flight(ctx, key, func(ctx context.Context) (V, error) {
+		m.lock.RLock()
+		v, ok := m.load(key)
+		current := m.providers[key]
+		m.lock.RUnlock()
+		if ok {
+			return v, nil
+		}
+		if current != p {
+			return v, errProviderChanged
+		}
+		value, err := m.construct(ctx, key, p)
+		if err != nil {
+			return value, err
+		}
+		if err := m.validate(ctx, "Get", key, value); err != nil {
+			return value, err
+		}
+		m.lock.Lock()
+		if v, ok := m.load(key); ok {
+			m.lock.Unlock()
+			return v, nil
+		}
+		if m.providers[key] != p {
+			m.lock.Unlock()
+			return v, errProviderChanged
+		}
+		m.store.Store(key, value)
+		m.checksum(key, value)
+		dropped := m.memoize(key, value)
+		m.lock.Unlock()
+		m.notify(Event[K, V]{Type: EventSet, Key: key, NewValue: value})
+		if len(dropped) > 0 {
+			m.evicted(dropped...)
+		}
+		return value, nil
+	})
+	if err == errProviderChanged {
+		return v, err
+	}
+	return v, m.errTimeout("Get", key, err)
+}
+
+// construct invokes provider p of key
+func (m *Map[K, V]) construct(ctx context.Context, key K, p *provider[V]) (V, error) {
+	if err := ctx.Err(); err != nil {
+		return *new(V), err
+	}
+	ctx, end := m.trace(ctx, SpanProvide, key)
+	value, err := p.fn(ctx)
+	end(err)
+	if err != nil {
+		m.logError(ctx, "inithook: provider failed", key, err)
+	}
+	return value, err
+}
+
+// errTimeout wraps err of op with `ErrTimeout` if it's caused by the ctx deadline exceeded
+func (m *Map[K, V]) errTimeout(op string, key K, err error) error {
+	if errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrTimeout) {
+		return m.newError(op, key, ErrTimeout, err)
+	}
+	return err
+}
+
+// errProviderChanged reports the provider has been deleted or replaced during resolving
+var errProviderChanged = errors.New("provider changed")
+
+// exists tells if key has an instance or a provider, must be called with lock held
+func (m *Map[K, V]) exists(key K) bool {
+	if _, ok := m.load(key); ok {
+		return true
+	}
+	_, ok := m.providers[key]
+	return ok
+}
@@ -0,0 +1,364 @@
+package inithook_test
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapRegisterProvider(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, *pool]()
+	var calls int32
+	err := m.RegisterProvider(ctx, "db", func(ctx context.Context) (*pool, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return &pool{name: "db"}, nil
+	})
+	assert.Nilf(t, err, "register provider")
+	assert.Truef(t, m.Has(ctx, "db"), "has provider key")
+	assert.Equalf(t, []string{"db"}, m.Keys(ctx), "keys include provider")
+	assert.Emptyf(t, m.Values(ctx), "values exclude unresolved provider")
+	assert.Equalf(t, int32(0), atomic.LoadInt32(&calls), "not constructed until used")
+
+	var wg sync.WaitGroup
+	pools := make([]*pool, 10)
+	for i := range pools {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			p, err := m.Get(ctx, "db")
+			assert.Nilf(t, err, "get provided")
+			pools[i] = p
+		}(i)
+	}
+	wg.Wait()
+	assert.Equalf(t, int32(1), atomic.LoadInt32(&calls), "constructed once")
+	for _, p := range pools {
+		assert.Samef(t, pools[0], p, "memoized")
+	}
+	assert.Lenf(t, m.Values(ctx), 1, "resolved")
+
+	err = m.Register(ctx, "db", &pool{})
+	assert.ErrorIsf(t, err, inithook.ErrAlreadyExists, "register provider key")
+	err = m.RegisterProvider(ctx, "db", nil)
+	assert.ErrorIsf(t, err, inithook.ErrAlreadyExists, "register provider twice")
+}
+
+func TestMapRegisterProviderError(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	var calls int
+	m.MustRegisterProvider(ctx, "flaky", func(ctx context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, errors.New("dial failed")
+		}
+		return calls, nil
+	})
+	_, err := m.Get(ctx, "flaky")
+	assert.NotNilf(t, err, "failed")
+	v, err := m.Get(ctx, "flaky")
+	assert.Nilf(t, err, "retry")
+	assert.Equalf(t, 2, v, "retry value")
+	m.MustDelete(ctx, "flaky")
+	assert.Falsef(t, m.Has(ctx, "flaky"), "provider deleted")
+}
+
+func TestMapProviderScope(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, *pool]()
+	m.MustRegisterProvider(ctx, "shared", func(ctx context.Context) (*pool, error) {
+		return &pool{name: "shared"}, nil
+	}, inithook.WithScope(inithook.ScopeSingleton))
+	m.MustRegisterProvider(ctx, "request", func(ctx context.Context) (*pool, error) {
+		return &pool{name: "request"}, nil
+	}, inithook.WithScope(inithook.ScopePrototype), inithook.WithDescription("per-request pool"))
+
+	a, _ := m.Get(ctx, "shared")
+	b, _ := m.Get(ctx, "shared")
+	assert.Samef(t, a, b, "singleton")
+	c, err := m.Get(ctx, "request")
+	assert.Nilf(t, err, "prototype")
+	d, _ := m.Get(ctx, "request")
+	assert.NotSamef(t, c, d, "prototype constructs fresh instances")
+	assert.Equalf(t, "request", d.name, "prototype value")
+	assert.ElementsMatchf(t, []string{"shared", "request"}, m.Keys(ctx), "keys")
+	assert.Lenf(t, m.Values(ctx), 1, "prototype instances are not memoized")
+	meta, err := m.Describe(ctx, "request")
+	assert.Nilf(t, err, "describe provider")
+	assert.Equalf(t, "per-request pool", meta.Description, "provider metadata")
+}
+
+type span struct {
+	name  string
+	attrs []inithook.Attribute
+	err   error
+}
+
+type recordTracer struct {
+	spans []*span
+}
+
+func (t *recordTracer) Start(ctx context.Context, name string, attrs ...inithook.Attribute) (context.Context, func(err error)) {
+	s := &span{name: name, attrs: attrs}
+	t.spans = append(t.spans, s)
+	return ctx, func(err error) { s.err = err }
+}
+
+func TestMapTracer(t *testing.T) {
+	ctx := context.Background()
+	tracer := &recordTracer{}
+	m := inithook.NewMap(inithook.WithName[string, int]("ints"), inithook.WithTracer[string, int](tracer))
+	errBoom := errors.New("boom")
+	m.MustRegisterProvider(ctx, "ok", func(ctx context.Context) (int, error) { return 1, nil })
+	m.MustRegisterProvider(ctx, "fail", func(ctx context.Context) (int, error) { return 0, errBoom })
+	m.Get(ctx, "ok")
+	m.Get(ctx, "ok")
+	m.Get(ctx, "fail")
+	m.GetDefault(ctx, "missing")
+	assert.Equalf(t, []*span{
+		{name: inithook.SpanProvide, attrs: []inithook.Attribute{{Key: inithook.AttributeRegistry, Value: "ints"}, {Key: inithook.AttributeKey, Value: "ok"}}},
+		{name: inithook.SpanProvide, attrs: []inithook.Attribute{{Key: inithook.AttributeRegistry, Value: "ints"}, {Key: inithook.AttributeKey, Value: "fail"}}, err: errBoom},
+		{name: inithook.SpanDefault, attrs: []inithook.Attribute{{Key: inithook.AttributeRegistry, Value: "ints"}, {Key: inithook.AttributeKey, Value: "missing"}}},
+	}, tracer.spans, "spans")
+}
+
+func TestMapContextCanceled(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	for i := 0; i < 10; i++ {
+		m.MustRegister(context.Background(), strconv.Itoa(i), i)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	var n int
+	m.RangeTyped(ctx, func(key string, value int) bool {
+		n++
+		cancel()
+		return true
+	})
+	assert.Equalf(t, 1, n, "range stops when ctx is canceled")
+
+	var calls int32
+	m.MustRegisterProvider(context.Background(), "lazy", func(ctx context.Context) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 1, nil
+	})
+	_, err := m.Get(ctx, "lazy")
+	assert.Truef(t, errors.Is(err, context.Canceled), "provider not invoked with canceled ctx")
+	assert.Equalf(t, int32(0), atomic.LoadInt32(&calls), "provider not invoked with canceled ctx")
+	_, err = m.Default(ctx, "lazy")
+	assert.Truef(t, errors.Is(err, context.Canceled), "default with canceled ctx")
+
+	v, err := m.Get(context.Background(), "lazy")
+	assert.Nilf(t, err, "get after cancel")
+	assert.Equalf(t, 1, v, "get after cancel")
+}
+
+func TestMapProviderWaiterCanceled(t *testing.T) {
+	m := inithook.NewMap[string, int]()
+	started, release := make(chan struct{}), make(chan struct{})
+	m.MustRegisterProvider(context.Background(), "slow", func(ctx context.Context) (int, error) {
+		close(started)
+		<-release
+		return 1, nil
+	})
+	go m.Get(context.Background(), "slow")
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := m.Get(ctx, "slow")
+	assert.Truef(t, errors.Is(err, context.DeadlineExceeded), "waiter returns when ctx is done")
+	assert.Truef(t, errors.Is(err, inithook.ErrTimeout), "waiter timed out")
+	close(release)
+}
+
+func TestMapWithWeigher(t *testing.T) {
+	ctx := context.Background()
+	var evicted []string
+	m := inithook.NewMap(
+		inithook.WithWeigher(10, func(key string, value []byte) int64 { return int64(len(value)) }),
+		inithook.WithOnEvict(func(key string, value []byte) { evicted = append(evicted, key) }),
+	)
+	calls := map[string]int{}
+	for _, key := range []string{"a", "b", "c"} {
+		key := key
+		m.MustRegisterProvider(ctx, key, func(ctx context.Context) ([]byte, error) {
+			calls[key]++
+			return make([]byte, 4), nil
+		})
+	}
+	m.MustSet(ctx, "set", make([]byte, 100))
+	var reclaimed []string
+	m.Watch(ctx, func(ev inithook.Event[string, []byte]) {
+		if ev.Type == inithook.EventReclaim {
+			reclaimed = append(reclaimed, ev.Key)
+		}
+	})
+	idx := inithook.NewPrefixIndex(ctx, m)
+	defer idx.Close()
+	m.MustGet(ctx, "a")
+	m.MustGet(ctx, "b")
+	assert.Equalf(t, int64(8), m.Weight(ctx), "weight of memoized only")
+	m.MustGet(ctx, "c")
+	assert.Equalf(t, []string{"a"}, evicted, "earliest memoized dropped")
+	assert.Equalf(t, int64(8), m.Weight(ctx), "weight within limit")
+	assert.Truef(t, m.Has(ctx, "a"), "provider kept")
+	assert.Equalf(t, []string{"a"}, reclaimed, "reported as reclaim")
+	assert.Equalf(t, 4, m.Len(ctx), "key still counted")
+	assert.ElementsMatchf(t, m.Keys(ctx), idx.Keys(ctx, ""), "prefix index agrees with keys")
+	assert.Zerof(t, m.Stats(ctx).Deletes, "not counted as deletion")
+	m.MustGet(ctx, "a")
+	assert.Equalf(t, 2, calls["a"], "reconstructed on next get")
+	assert.Equalf(t, []string{"a", "b"}, evicted, "dropped by reconstruction")
+	m.MustGet(ctx, "c")
+	assert.Equalf(t, 1, calls["c"], "still memoized")
+
+	assert.Equalf(t, 2, m.Reclaim(ctx), "reclaim memoized")
+	assert.Equalf(t, int64(0), m.Weight(ctx), "nothing memoized")
+	assert.Lenf(t, m.MustGet(ctx, "set"), 100, "set value kept")
+	m.MustGet(ctx, "c")
+	assert.Equalf(t, 2, calls["c"], "reconstructed after reclaim")
+}
+
+func TestMapReclaimOnGC(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	var calls atomic.Int32
+	m.MustRegisterProvider(ctx, "template", func(ctx context.Context) (int, error) {
+		return int(calls.Add(1)), nil
+	})
+	m.MustGet(ctx, "template")
+	stop := m.ReclaimOnGC(ctx, 0)
+	defer stop()
+	assert.Eventuallyf(t, func() bool {
+		runtime.GC()
+		return m.MustGet(ctx, "template") > 1
+	}, time.Second, 10*time.Millisecond, "reclaimed after gc")
+}
+
+func TestMapProviderPerKeyConstruction(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	var started sync.WaitGroup
+	started.Add(2)
+	for i, key := range []string{"a", "b"} {
+		i := i
+		m.MustRegisterProvider(ctx, key, func(ctx context.Context) (int, error) {
+			started.Done()
+			started.Wait() // blocks forever if constructions of different keys are serialized
+			return i, nil
+		})
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for _, key := range []string{"a", "b"} {
+			wg.Add(1)
+			go func(key string) {
+				defer wg.Done()
+				m.MustGet(ctx, key)
+			}(key)
+		}
+		wg.Wait()
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("constructions of different keys should proceed concurrently")
+	}
+	assert.Equalf(t, 1, m.MustGet(ctx, "b"), "constructed")
+}
+
+func TestMapWarm(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap(inithook.WithDefaultCache[string, int](), inithook.WithDefaultFunc(func(ctx context.Context, key string) (int, error) {
+		if key == "bad" {
+			return 0, errors.New("no default")
+		}
+		return len(key), nil
+	}))
+	var running, peak, calls atomic.Int32
+	for i := 0; i < 8; i++ {
+		i := i
+		m.MustRegisterProvider(ctx, "p"+strconv.Itoa(i), func(ctx context.Context) (int, error) {
+			calls.Add(1)
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			if i == 7 {
+				return 0, errors.New("construct failed")
+			}
+			return i, nil
+		})
+	}
+	m.MustRegisterProvider(ctx, "prototype", func(ctx context.Context) (int, error) {
+		calls.Add(1)
+		return 0, nil
+	}, inithook.WithScope(inithook.ScopePrototype))
+
+	err := m.Warm(ctx, 2, "default", "bad")
+	assert.NotNilf(t, err, "aggregated errors")
+	assert.Containsf(t, err.Error(), "construct failed", "provider error")
+	assert.Containsf(t, err.Error(), "no default", "default error")
+	assert.Equalf(t, int32(8), calls.Load(), "singletons resolved, prototype skipped")
+	assert.LessOrEqualf(t, peak.Load(), int32(2), "bounded parallelism")
+	assert.Equalf(t, 3, m.MustGet(ctx, "p3"), "memoized")
+	assert.Equalf(t, int32(8), calls.Load(), "not constructed again")
+	v, _, _ := m.GetDefault(ctx, "default")
+	assert.Equalf(t, 7, v, "default warmed")
+
+	assert.NotNilf(t, m.Warm(ctx, 0), "only the failed one again")
+	assert.Equalf(t, int32(9), calls.Load(), "resolved ones skipped")
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.Truef(t, errors.Is(m.Warm(canceled, 1), context.Canceled), "canceled")
+}
+
+func TestMapProviderPanic(t *testing.T) {
+	ctx := context.Background()
+	m := inithook.NewMap[string, int]()
+	release := make(chan struct{})
+	m.MustRegisterProvider(ctx, "bad", func(ctx context.Context) (int, error) {
+		<-release
+		panic("boom")
+	})
+	var wg sync.WaitGroup
+	var panics, failures atomic.Int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					panics.Add(1)
+				}
+			}()
+			v, err := m.Get(ctx, "bad")
+			assert.Zerof(t, v, "zero value")
+			if assert.NotNilf(t, err, "waiter should not succeed") {
+				assert.Containsf(t, err.Error(), "panic: boom", "panic error")
+				failures.Add(1)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equalf(t, int32(1), panics.Load(), "original caller panics again")
+	assert.Equalf(t, int32(9), failures.Load(), "waiters receive the panic error")
+	assert.Panicsf(t, func() { m.Get(ctx, "bad") }, "next get invokes the provider again")
+}
@@ -0,0 +1,122 @@
+package inithook
+
+import (
+	"container/list"
+	"context"
+	"runtime"
+	"runtime/metrics"
+	"sync/atomic"
+)
+
+// memoized is an instance memoized by a singleton provider, which can be dropped and reconstructed on the next Get
+type memoized[K comparable] struct {
+	key    K
+	weight int64
+}
+
+// memoize tracks the instance of key memoized by its provider, if the total weight exceeds the limit of `WithWeigher`,
+// the earliest memoized ones except key are dropped and their events returned, must be called with write lock held
+func (m *Map[K, V]) memoize(key K, value V) []Event[K, V] {
+	if m.memoized == nil {
+		m.memoized = make(map[K]*list.Element)
+		m.memoizedOrder = list.New()
+	}
+	var weight int64
+	if m.weigher != nil {
+		weight = m.weigher(key, value)
+	}
+	m.memoized[key] = m.memoizedOrder.PushBack(&memoized[K]{key: key, weight: weight})
+	m.weight += weight
+	var events []Event[K, V]
+	for m.weigher != nil && m.weight > m.weightLimit {
+		front := m.memoizedOrder.Front().Value.(*memoized[K])
+		if front.key == key {
+			break
+		}
+		if ev, ok := m.drop(front.key); ok {
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+// forget stops tracking the memoized instance of key, must be called with write lock held
+func (m *Map[K, V]) forget(key K) {
+	if e, ok := m.memoized[key]; ok {
+		m.weight -= e.Value.(*memoized[K]).weight
+		m.memoizedOrder.Remove(e)
+		delete(m.memoized, key)
+	}
+}
+
+// drop drops the memoized instance of key while keeping its provider and metadata, must be called with write lock held
+func (m *Map[K, V]) drop(key K) (Event[K, V], bool) {
+	v, ok := m.store.Load(key)
+	m.store.Delete(key)
+	delete(m.checksums, key)
+	m.forget(key)
+	return Event[K, V]{Type: EventReclaim, Key: key, OldValue: v, Loaded: true}, ok
+}
+
+// Weight returns the total weight of the instances memoized by providers, see `WithWeigher`
+func (m *Map[K, V]) Weight(ctx context.Context) int64 {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.weight
+}
+
+// Reclaim drops all instances memoized by singleton providers(see `RegisterProvider`), which are constructed again
+// on the next Get, e.g. to release regenerable caches under memory pressure, the dropped instances are reported
+// to the `WithOnEvict` callback and watchers as `EventReclaim`, returns the number of instances dropped
+func (m *Map[K, V]) Reclaim(ctx context.Context) int {
+	m.lock.Lock()
+	var events []Event[K, V]
+	for key := range m.memoized {
+		if ev, ok := m.drop(key); ok {
+			events = append(events, ev)
+		}
+	}
+	m.lock.Unlock()
+	if len(events) > 0 {
+		m.evicted(events...)
+	}
+	return len(events)
+}
+
+// heapMetric is the runtime metric of the memory occupied by live and not yet swept heap objects
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// ReclaimOnGC reclaims(see `Reclaim`) the instances memoized by providers after a garbage collection
+// if the heap objects exceed heapLimit bytes, until ctx is done or stop is called
+func (m *Map[K, V]) ReclaimOnGC(ctx context.Context, heapLimit uint64) (stop func()) {
+	var stopped atomic.Bool
+	OnGC(func() bool {
+		if stopped.Load() || ctx.Err() != nil {
+			return false
+		}
+		sample := []metrics.Sample{{Name: heapMetric}}
+		metrics.Read(sample)
+		if sample[0].Value.Kind() == metrics.KindUint64 && sample[0].Value.Uint64() > heapLimit {
+			go m.Reclaim(ctx)
+		}
+		return true
+	})
+	return func() { stopped.Store(true) }
+}
+
+// OnGC calls fn in the finalizer goroutine after each garbage collection while fn returns true,
+// fn should be quick since it blocks the other finalizers
+func OnGC(fn func() bool) {
+	runtime.SetFinalizer(&gcSentinel{fn: fn}, finalizeSentinel)
+}
+
+// gcSentinel is an unreachable object whose finalizer runs after a garbage collection and re-arms itself
+type gcSentinel struct {
+	fn func() bool
+}
+
+func finalizeSentinel(s *gcSentinel) {
+	if s.fn() {
+		runtime.SetFinalizer(&gcSentinel{fn: s.fn}, finalizeSentinel)
+	}
+}
@@ -0,0 +1,123 @@
+package inithook
+
+import (
+	"bytes"
+	"fmt"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// rwLock is the lock of Map, which detects the reentrant locking of a goroutine if `WithReentrancyCheck` is used
+type rwLock struct {
+	sync.RWMutex
+
+	check    bool
+	typeName string
+	holders  map[uint64]lockSite // goroutine id => where it's locked
+	lock     sync.Mutex
+}
+
+// lockSite is the exported method of Map acquiring the lock and its caller outside this package
+type lockSite struct {
+	method string
+	caller Caller
+}
+
+func (s lockSite) String() string {
+	return s.method + " at " + s.caller.String()
+}
+
+func (l *rwLock) Lock() {
+	if !l.check {
+		l.RWMutex.Lock()
+		return
+	}
+	id, site := l.enter("write lock")
+	l.RWMutex.Lock()
+	l.hold(id, site)
+}
+
+func (l *rwLock) Unlock() {
+	if l.check {
+		l.leave()
+	}
+	l.RWMutex.Unlock()
+}
+
+func (l *rwLock) RLock() {
+	if !l.check {
+		l.RWMutex.RLock()
+		return
+	}
+	id, site := l.enter("read lock")
+	l.RWMutex.RLock()
+	l.hold(id, site)
+}
+
+func (l *rwLock) RUnlock() {
+	if l.check {
+		l.leave()
+	}
+	l.RWMutex.RUnlock()
+}
+
+// enter panics if the current goroutine already holds the lock, which deadlocks since the lock is not reentrant,
+// even a reentrant read lock deadlocks once a writer is waiting
+func (l *rwLock) enter(kind string) (uint64, lockSite) {
+	id, site := goroutineID(), currentLockSite()
+	l.lock.Lock()
+	held, ok := l.holders[id]
+	l.lock.Unlock()
+	if ok {
+		panic(fmt.Sprintf("inithook: reentrant %s of the map of type %s by %s while locked by %s, which deadlocks", kind, l.typeName, site, held))
+	}
+	return id, site
+}
+
+func (l *rwLock) hold(id uint64, site lockSite) {
+	l.lock.Lock()
+	if l.holders == nil {
+		l.holders = make(map[uint64]lockSite)
+	}
+	l.holders[id] = site
+	l.lock.Unlock()
+}
+
+func (l *rwLock) leave() {
+	id := goroutineID()
+	l.lock.Lock()
+	delete(l.holders, id)
+	l.lock.Unlock()
+}
+
+// currentLockSite returns the outermost method of this package in the stack and its caller outside this package
+func currentLockSite() lockSite {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	var site lockSite
+	for {
+		frame, more := frames.Next()
+		if pkg := funcPackage(frame.Function); pkg != thisPackage {
+			site.caller = Caller{Package: pkg, Function: frame.Function, File: frame.File, Line: frame.Line}
+			return site
+		}
+		site.method = frame.Function[strings.LastIndex(frame.Function, ".")+1:]
+		if !more {
+			return site
+		}
+	}
+}
+
+// goroutineID parses the id of the current goroutine from its stack header `goroutine 123 [running]:`
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := bytes.TrimPrefix(buf[:runtime.Stack(buf[:], false)], []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i >= 0 {
+		b = b[:i]
+	}
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
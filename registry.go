@@ -0,0 +1,102 @@
+package inithook
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// RegistryInfo describes a Map registered by `RegisterMap`
+type RegistryInfo struct {
+	Name      string
+	KeyType   reflect.Type
+	ValueType reflect.Type
+}
+
+// MustRegisterMap register a Map[K, V] named name, if failed(e.g. already exists) then panic
+func MustRegisterMap[K comparable, V any](name string, opts ...Option[K, V]) *Map[K, V] {
+	m, err := RegisterMap(name, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// RegisterMap creates a Map[K, V] named name(see `WithName`) and registers it to the global registry of registries,
+// so other packages can discover it by `MapOf` without import cycles, a name can be registered once for each K and V pair,
+// if exists then return `ErrAlreadyExists` error(use `errors.Is` to assert)
+func RegisterMap[K comparable, V any](name string, opts ...Option[K, V]) (*Map[K, V], error) {
+	m := NewMap(append([]Option[K, V]{WithName[K, V](name)}, opts...)...)
+	err := registries.Register(context.Background(), newRegistryKey[K, V](name), m)
+	if err != nil {
+		return nil, fmt.Errorf("registry %s of %s: %w", name, registryType[K, V](), ErrAlreadyExists)
+	}
+	return m, nil
+}
+
+// MapOf returns the Map[K, V] named name registered by `RegisterMap`,
+// if not found then return `ErrNotFound` error(use `errors.Is` to assert)
+func MapOf[K comparable, V any](name string) (*Map[K, V], error) {
+	m, err := registries.Get(context.Background(), newRegistryKey[K, V](name))
+	if err != nil {
+		return nil, fmt.Errorf("registry %s of %s: %w", name, registryType[K, V](), ErrNotFound)
+	}
+	return m.(*Map[K, V]), nil
+}
+
+// Registry returns the Map registered by `RegisterMap` described by info as any, used for introspection,
+// if not found then return `ErrNotFound` error(use `errors.Is` to assert)
+func Registry(info RegistryInfo) (any, error) {
+	m, err := registries.Get(context.Background(), info)
+	if err != nil {
+		return nil, fmt.Errorf("registry %s of %s: %w", info.Name, registryTypeString(info), ErrNotFound)
+	}
+	return m, nil
+}
+
+// Registries returns all Maps registered by `RegisterMap` sorted by name, used for introspection
+func Registries() []RegistryInfo {
+	infos := registries.Keys(context.Background())
+	sort.Slice(infos, func(i, j int) bool {
+		if infos[i].Name != infos[j].Name {
+			return infos[i].Name < infos[j].Name
+		}
+		return registryTypeString(infos[i]) < registryTypeString(infos[j])
+	})
+	return infos
+}
+
+// For returns the process-wide Map[string, V] of the value type V, which is created lazily on the first call,
+// so no package-level Map need to be declared for every type
+func For[V any]() *Map[string, V] {
+	typ := reflect.TypeOf(new(V)).Elem()
+	m, _ := typedRegistries.GetOrCompute(context.Background(), typ, func(ctx context.Context) (any, error) {
+		return NewMap(WithName[string, V](typ.String())), nil
+	})
+	return m.(*Map[string, V])
+}
+
+func newRegistryKey[K comparable, V any](name string) RegistryInfo {
+	return RegistryInfo{
+		Name:      name,
+		KeyType:   reflect.TypeOf(new(K)).Elem(),
+		ValueType: reflect.TypeOf(new(V)).Elem(),
+	}
+}
+
+func registryType[K comparable, V any]() string {
+	return registryTypeString(newRegistryKey[K, V](""))
+}
+
+func registryTypeString(info RegistryInfo) string {
+	return "Map[" + info.KeyType.String() + ", " + info.ValueType.String() + "]"
+}
+
+var (
+	// registries is the global registry of registries, values are *Map[K, V]
+	registries = NewMap[RegistryInfo, any]()
+
+	// typedRegistries holds the Maps of `For` keyed by the value type, values are *Map[string, V]
+	typedRegistries = NewMap[reflect.Type, any]()
+)
@@ -0,0 +1,29 @@
+package inithook
+
+// Seal seals the map, then all modifications(e.g. Register, Set, Delete, Tx) return `ErrSealed` error
+// (use `errors.Is` to assert), Swap panics and CompareAndSwap returns false, usually called once the init finished
+// to freeze the registrations, NOTE: providers are still resolved and memoized, and expired instances still evicted
+func (m *Map[K, V]) Seal() {
+	m.lock.Lock()
+	m.sealed = true
+	m.lock.Unlock()
+}
+
+// Sealed tells if the map is sealed
+func (m *Map[K, V]) Sealed() bool {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	return m.sealed
+}
+
+// errSealed returns the `ErrSealed` error of op modifying keys
+func (m *Map[K, V]) errSealed(op string, keys ...K) error {
+	switch len(keys) {
+	case 0:
+		return m.newError(op, nil, ErrSealed, nil)
+	case 1:
+		return m.newError(op, keys[0], ErrSealed, nil)
+	default:
+		return m.newError(op, keys, ErrSealed, nil)
+	}
+}
@@ -0,0 +1,146 @@
+package inithook
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+)
+
+// Set is a concurrency-safe set of keys for the registries which only need membership, e.g. enabled features, seen plugins,
+// the zero Set is empty and ready to use
+type Set[K comparable] struct {
+	keys map[K]struct{}
+	lock sync.RWMutex
+}
+
+// NewSet creates a new set of keys
+func NewSet[K comparable](keys ...K) *Set[K] {
+	s := &Set[K]{keys: make(map[K]struct{}, len(keys))}
+	for _, key := range keys {
+		s.keys[key] = struct{}{}
+	}
+	return s
+}
+
+// Add adds keys, returns the number of keys not present before
+func (s *Set[K]) Add(ctx context.Context, keys ...K) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.keys == nil {
+		s.keys = make(map[K]struct{}, len(keys))
+	}
+	n := len(s.keys)
+	for _, key := range keys {
+		s.keys[key] = struct{}{}
+	}
+	return len(s.keys) - n
+}
+
+// Remove removes keys, returns the number of keys present before
+func (s *Set[K]) Remove(ctx context.Context, keys ...K) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := len(s.keys)
+	for _, key := range keys {
+		delete(s.keys, key)
+	}
+	return n - len(s.keys)
+}
+
+// Has tells if the set has key
+func (s *Set[K]) Has(ctx context.Context, key K) bool {
+	s.lock.RLock()
+	_, ok := s.keys[key]
+	s.lock.RUnlock()
+	return ok
+}
+
+// Len returns the number of keys
+func (s *Set[K]) Len(ctx context.Context) int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.keys)
+}
+
+// Clear removes all keys
+func (s *Set[K]) Clear(ctx context.Context) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.keys = make(map[K]struct{})
+}
+
+// Keys return all keys in a new slice owned by the caller, NOTE: the order is not specified
+func (s *Set[K]) Keys(ctx context.Context) []K {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	keys := make([]K, 0, len(s.keys))
+	for key := range s.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// Range calls f sequentially for each key present in the set. If f returns false or ctx is done, range stops the iteration.
+// NOTE: f is called with the read lock held, so it must not mutate the set which deadlocks
+func (s *Set[K]) Range(ctx context.Context, fn func(key K) bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for key := range s.keys {
+		if ctx.Err() != nil || !fn(key) {
+			return
+		}
+	}
+}
+
+// Union returns a new set holding the keys in s or other
+func (s *Set[K]) Union(ctx context.Context, other *Set[K]) *Set[K] {
+	union := NewSet(s.Keys(ctx)...)
+	union.Add(ctx, other.Keys(ctx)...)
+	return union
+}
+
+// Intersect returns a new set holding the keys in both s and other
+func (s *Set[K]) Intersect(ctx context.Context, other *Set[K]) *Set[K] {
+	return s.filter(ctx, other, true)
+}
+
+// Difference returns a new set holding the keys in s but not in other
+func (s *Set[K]) Difference(ctx context.Context, other *Set[K]) *Set[K] {
+	return s.filter(ctx, other, false)
+}
+
+// filter returns a new set holding the keys of s whose presence in other is in, other is copied first
+// so the locks of both sets are never held at the same time
+func (s *Set[K]) filter(ctx context.Context, other *Set[K], in bool) *Set[K] {
+	others := NewSet(other.Keys(ctx)...)
+	filtered := NewSet[K]()
+	s.Range(ctx, func(key K) bool {
+		if _, ok := others.keys[key]; ok == in {
+			filtered.keys[key] = struct{}{}
+		}
+		return true
+	})
+	return filtered
+}
+
+// MarshalJSON implements `json.Marshaler`, encodes the set as an array of keys
+func (s *Set[K]) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Keys(context.Background()))
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`, adds the keys of the json array
+func (s *Set[K]) UnmarshalJSON(data []byte) error {
+	var keys []K
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return err
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.keys == nil {
+		s.keys = make(map[K]struct{}, len(keys))
+	}
+	for _, key := range keys {
+		s.keys[key] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package inithook_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	ctx := context.Background()
+	s := inithook.NewSet("a", "b")
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			s.Add(ctx, strconv.Itoa(i))
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			s.Has(ctx, strconv.Itoa(i))
+		}(i)
+	}
+	wg.Wait()
+	assert.Equalf(t, 52, s.Len(ctx), "len")
+	assert.Equalf(t, 1, s.Add(ctx, "a", "c"), "added new only")
+	assert.Equalf(t, 2, s.Remove(ctx, "c", "a", "missing"), "removed present only")
+	assert.Falsef(t, s.Has(ctx, "a"), "removed")
+	assert.Truef(t, s.Has(ctx, "b"), "kept")
+	count := 0
+	s.Range(ctx, func(key string) bool {
+		count++
+		return count < 3
+	})
+	assert.Equalf(t, 3, count, "range stops")
+	s.Clear(ctx)
+	assert.Emptyf(t, s.Keys(ctx), "cleared")
+
+	a, b := inithook.NewSet(1, 2, 3), inithook.NewSet(2, 3, 4)
+	assert.ElementsMatchf(t, []int{1, 2, 3, 4}, a.Union(ctx, b).Keys(ctx), "union")
+	assert.ElementsMatchf(t, []int{2, 3}, a.Intersect(ctx, b).Keys(ctx), "intersect")
+	assert.ElementsMatchf(t, []int{1}, a.Difference(ctx, b).Keys(ctx), "difference")
+	assert.ElementsMatchf(t, []int{1, 2, 3}, a.Intersect(ctx, a).Keys(ctx), "intersect self")
+
+	data, err := json.Marshal(a)
+	assert.Nilf(t, err, "marshal")
+	var loaded inithook.Set[int]
+	assert.Nilf(t, json.Unmarshal(data, &loaded), "unmarshal")
+	assert.ElementsMatchf(t, []int{1, 2, 3}, loaded.Keys(ctx), "roundtrip")
+	var zero inithook.Set[string]
+	assert.Equalf(t, 1, zero.Add(ctx, "a"), "zero set ready to use")
+}
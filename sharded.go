@@ -0,0 +1,321 @@
+package inithook
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+	"unsafe"
+)
+
+// ShardedMap is a instances map of specified Type which spreads keys over several shards,
+// each shard is a Map with its own lock, so writes of different keys rarely contends with each other.
+// It has the same API as Map, except that operations across shards(e.g. Clear, Range, Keys) are not atomic.
+type ShardedMap[K comparable, V any] struct {
+	shards []*Map[K, V]
+	hash   func(key K) uint64
+}
+
+// NewShardedMap creates a new sharded map with shards shards, if shards <= 0 then use 32 shards
+func NewShardedMap[K comparable, V any](shards int) *ShardedMap[K, V] {
+	if shards <= 0 {
+		shards = 32
+	}
+	m := &ShardedMap[K, V]{
+		shards: make([]*Map[K, V], shards),
+		hash:   newHasher[K](maphash.MakeSeed()),
+	}
+	for i := range m.shards {
+		m.shards[i] = NewMap[K, V]()
+	}
+	return m
+}
+
+// shard returns the shard which key belongs to
+func (m *ShardedMap[K, V]) shard(key K) *Map[K, V] {
+	if len(m.shards) == 1 {
+		return m.shards[0]
+	}
+	return m.shards[m.hash(key)%uint64(len(m.shards))]
+}
+
+// newHasher returns a hash func of K, string and integer keys are hashed without allocation
+func newHasher[K comparable](seed maphash.Seed) func(key K) uint64 {
+	switch reflect.TypeOf(new(K)).Elem().Kind() {
+	case reflect.String:
+		return func(key K) uint64 {
+			var h maphash.Hash
+			h.SetSeed(seed)
+			h.WriteString(*(*string)(unsafe.Pointer(&key)))
+			return h.Sum64()
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return func(key K) uint64 {
+			var x uint64
+			b := unsafe.Slice((*byte)(unsafe.Pointer(&key)), unsafe.Sizeof(key))
+			for i := len(b) - 1; i >= 0; i-- {
+				x = x<<8 | uint64(b[i])
+			}
+			return mix(x)
+		}
+	default:
+		return func(key K) uint64 {
+			var h maphash.Hash
+			h.SetSeed(seed)
+			hashValue(&h, reflect.ValueOf(&key).Elem())
+			return h.Sum64()
+		}
+	}
+}
+
+// hashValue writes v into h by its kind, so that the equal values(per `==`) are hashed equally,
+// e.g. 0.0 and -0.0, and the interfaces are hashed by their dynamic type and value
+func hashValue(h *maphash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		hashUint64(h, uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		hashUint64(h, v.Uint())
+	case reflect.Float32, reflect.Float64:
+		hashFloat(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		hashFloat(h, real(c))
+		hashFloat(h, imag(c))
+	case reflect.String:
+		hashUint64(h, uint64(v.Len())) // separates the adjacent strings of arrays and structs
+		h.WriteString(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		hashUint64(h, uint64(v.Pointer()))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			hashValue(h, v.Field(i))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			h.WriteByte(0)
+			return
+		}
+		v = v.Elem()
+		h.WriteString(v.Type().String())
+		hashValue(h, v)
+	}
+}
+
+func hashFloat(h *maphash.Hash, f float64) {
+	if f == 0 { // -0.0 == 0.0
+		f = 0
+	}
+	hashUint64(h, math.Float64bits(f))
+}
+
+func hashUint64(h *maphash.Hash, x uint64) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], x)
+	h.Write(b[:])
+}
+
+// mix is the finalizer of splitmix64, used to spread integer keys over shards
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// MustRegister register a V's instance with key, if failed(e.g. already exists) then panic
+func (m *ShardedMap[K, V]) MustRegister(ctx context.Context, key K, value V, opts ...RegisterOption) {
+	m.shard(key).MustRegister(ctx, key, value, opts...)
+}
+
+// Register register a V's instance with key, if exists then return `ErrAlreadyExists` error(use `errors.Is` to assert)
+func (m *ShardedMap[K, V]) Register(ctx context.Context, key K, value V, opts ...RegisterOption) error {
+	return m.shard(key).Register(ctx, key, value, opts...)
+}
+
+// Describe returns the metadata attached when key registered, see `Map.Describe`
+func (m *ShardedMap[K, V]) Describe(ctx context.Context, key K) (Metadata, error) {
+	return m.shard(key).Describe(ctx, key)
+}
+
+// MustSet set a V's instance with key, if exists then override, if failed then panic
+func (m *ShardedMap[K, V]) MustSet(ctx context.Context, key K, value V) {
+	m.shard(key).MustSet(ctx, key, value)
+}
+
+// Set set a V's instance with key, if exists then override
+func (m *ShardedMap[K, V]) Set(ctx context.Context, key K, value V) error {
+	return m.shard(key).Set(ctx, key, value)
+}
+
+// GetOrSet returns the existing V's instance of key if present, otherwise set value with key and returns it
+func (m *ShardedMap[K, V]) GetOrSet(ctx context.Context, key K, value V) (V, error) {
+	return m.shard(key).GetOrSet(ctx, key, value)
+}
+
+// GetOrCompute returns the existing V's instance of key if present, otherwise construct it by fn and set it with key
+func (m *ShardedMap[K, V]) GetOrCompute(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	return m.shard(key).GetOrCompute(ctx, key, fn)
+}
+
+// Update update the V's instance of key by fn under the write lock of its shard
+func (m *ShardedMap[K, V]) Update(ctx context.Context, key K, fn func(old V) (V, error)) error {
+	return m.shard(key).Update(ctx, key, fn)
+}
+
+// CompareAndSwap swaps the old and new V's instance of key if the instance stored in the map is equal to old
+func (m *ShardedMap[K, V]) CompareAndSwap(ctx context.Context, key K, old, new V) bool {
+	return m.shard(key).CompareAndSwap(ctx, key, old, new)
+}
+
+// MustDelete delete a V's instance specified by key, if failed then panic
+func (m *ShardedMap[K, V]) MustDelete(ctx context.Context, key K) {
+	m.shard(key).MustDelete(ctx, key)
+}
+
+// Delete delete a V's instance specified by key
+func (m *ShardedMap[K, V]) Delete(ctx context.Context, key K) error {
+	return m.shard(key).Delete(ctx, key)
+}
+
+// MustClear clear all V's instances, if failed then panic
+func (m *ShardedMap[K, V]) MustClear(ctx context.Context) {
+	err := m.Clear(ctx)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// Clear clear all V's instances shard by shard
+func (m *ShardedMap[K, V]) Clear(ctx context.Context) error {
+	for _, shard := range m.shards {
+		if err := shard.Clear(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get get a V's instance by key, if not found return `NotFound` error(use `errors.Is` to assert)
+func (m *ShardedMap[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return m.shard(key).Get(ctx, key)
+}
+
+// GetDefault get a V's instance by key, if not found, then try to returns a default one,
+// defaulted tells if the instance is the default one rather than a registered one
+func (m *ShardedMap[K, V]) GetDefault(ctx context.Context, key K) (value V, defaulted bool, err error) {
+	return m.shard(key).GetDefault(ctx, key)
+}
+
+// Default returns V's default value if it implement the `DefaultLoaderK`, `DefaultLoader` or `Default`, otherwise return `Zero[V]()`
+func (m *ShardedMap[K, V]) Default(ctx context.Context, key K) (V, error) {
+	return m.shard(key).Default(ctx, key)
+}
+
+// Has tells if map has key
+func (m *ShardedMap[K, V]) Has(ctx context.Context, key K) bool {
+	return m.shard(key).Has(ctx, key)
+}
+
+// Len returns the number of V's instances of all shards
+func (m *ShardedMap[K, V]) Len(ctx context.Context) int {
+	var n int
+	for _, shard := range m.shards {
+		n += shard.Len(ctx)
+	}
+	return n
+}
+
+// IsEmpty tells if map has no V's instance
+func (m *ShardedMap[K, V]) IsEmpty(ctx context.Context) bool {
+	for _, shard := range m.shards {
+		if !shard.IsEmpty(ctx) {
+			return false
+		}
+	}
+	return true
+}
+
+// Range calls f sequentially for each key and value present in the map shard by shard. If f returns false or ctx is done, range stops the iteration.
+func (m *ShardedMap[K, V]) Range(ctx context.Context, fn func(key, value any) bool) {
+	shouldContinue := true
+	for _, shard := range m.shards {
+		shard.Range(ctx, func(key, value any) bool {
+			shouldContinue = fn(key, value)
+			return shouldContinue
+		})
+		if !shouldContinue || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// RangeTyped calls f sequentially for each key and value present in the map shard by shard with typed arguments. If f returns false or ctx is done, range stops the iteration.
+func (m *ShardedMap[K, V]) RangeTyped(ctx context.Context, fn func(key K, value V) bool) {
+	shouldContinue := true
+	for _, shard := range m.shards {
+		shard.RangeTyped(ctx, func(key K, value V) bool {
+			shouldContinue = fn(key, value)
+			return shouldContinue
+		})
+		if !shouldContinue || ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// Keys return all keys
+func (m *ShardedMap[K, V]) Keys(ctx context.Context) []K {
+	var keys []K
+	for _, shard := range m.shards {
+		keys = append(keys, shard.Keys(ctx)...)
+	}
+	return keys
+}
+
+// Values return all values
+func (m *ShardedMap[K, V]) Values(ctx context.Context) []V {
+	var values []V
+	for _, shard := range m.shards {
+		values = append(values, shard.Values(ctx)...)
+	}
+	return values
+}
+
+// Map return map with all items
+func (m *ShardedMap[K, V]) Map(ctx context.Context) map[K]V {
+	kvs := make(map[K]V)
+	for _, shard := range m.shards {
+		for k, v := range shard.Map(ctx) {
+			kvs[k] = v
+		}
+	}
+	return kvs
+}
+
+// Watch subscribes fn to all mutations of all shards, see `Map.Watch`
+func (m *ShardedMap[K, V]) Watch(ctx context.Context, fn func(ev Event[K, V])) (cancel func()) {
+	cancels := make([]func(), len(m.shards))
+	for i, shard := range m.shards {
+		cancels[i] = shard.Watch(ctx, fn)
+	}
+	return func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}
+}
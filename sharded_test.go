@@ -0,0 +1,123 @@
+package inithook_test
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedMap(t *testing.T) {
+	m := inithook.NewShardedMap[string, int](8)
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.MustRegister(ctx, strconv.Itoa(i), i)
+		}(i)
+	}
+	wg.Wait()
+	assert.Lenf(t, m.Keys(ctx), 100, "keys")
+	assert.Lenf(t, m.Map(ctx), 100, "map")
+	for i := 0; i < 100; i++ {
+		v, err := m.Get(ctx, strconv.Itoa(i))
+		assert.Nilf(t, err, "get %d", i)
+		assert.Equalf(t, i, v, "value %d", i)
+	}
+	err := m.Register(ctx, "1", 1)
+	assert.ErrorIsf(t, err, inithook.ErrAlreadyExists, "register twice")
+	m.MustDelete(ctx, "1")
+	assert.Falsef(t, m.Has(ctx, "1"), "deleted")
+	m.MustClear(ctx)
+	assert.Emptyf(t, m.Keys(ctx), "cleared")
+	assert.Truef(t, m.IsEmpty(ctx), "empty")
+
+	im := inithook.NewShardedMap[int, string](0)
+	im.MustSet(ctx, 1, "one")
+	v, err := im.Get(ctx, 1)
+	assert.Nilf(t, err, "get int key")
+	assert.Equalf(t, "one", v, "int key")
+
+	type route struct {
+		path   string
+		weight float64
+	}
+	rm := inithook.NewShardedMap[route, int](64)
+	for i := 0; i < 100; i++ {
+		rm.MustSet(ctx, route{path: strconv.Itoa(i), weight: math.Copysign(0, -1)}, i)
+	}
+	for i := 0; i < 100; i++ {
+		v, err := rm.Get(ctx, route{path: strconv.Itoa(i), weight: 0})
+		assert.Nilf(t, err, "get struct key %d with 0.0", i)
+		assert.Equalf(t, i, v, "struct key %d", i)
+	}
+
+	am := inithook.NewShardedMap[any, int](64)
+	for i := 0; i < 100; i++ {
+		am.MustSet(ctx, i, i)
+		am.MustSet(ctx, strconv.Itoa(i), -i)
+	}
+	for i := 0; i < 100; i++ {
+		v, err := am.Get(ctx, i)
+		assert.Nilf(t, err, "get interface key %d", i)
+		assert.Equalf(t, i, v, "interface key %d", i)
+		v, err = am.Get(ctx, strconv.Itoa(i))
+		assert.Nilf(t, err, "get interface key %q", strconv.Itoa(i))
+		assert.Equalf(t, -i, v, "interface key %q", strconv.Itoa(i))
+	}
+}
+
+var benchKeys = func() []string {
+	keys := make([]string, 1024)
+	for i := range keys {
+		keys[i] = "handler-" + strconv.Itoa(i)
+	}
+	return keys
+}()
+
+type benchMap interface {
+	Set(ctx context.Context, key string, value int) error
+	Get(ctx context.Context, key string) (int, error)
+}
+
+func benchmarkReadWrite(b *testing.B, m benchMap, writePercent int) {
+	ctx := context.Background()
+	for i, key := range benchKeys {
+		m.Set(ctx, key, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var i int
+		for pb.Next() {
+			key := benchKeys[i%len(benchKeys)]
+			if i%100 < writePercent {
+				m.Set(ctx, key, i)
+			} else {
+				m.Get(ctx, key)
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkMapWriteHeavy(b *testing.B) {
+	benchmarkReadWrite(b, inithook.NewMap[string, int](), 50)
+}
+
+func BenchmarkShardedMapWriteHeavy(b *testing.B) {
+	benchmarkReadWrite(b, inithook.NewShardedMap[string, int](32), 50)
+}
+
+func BenchmarkMapReadHeavy(b *testing.B) {
+	benchmarkReadWrite(b, inithook.NewMap[string, int](), 5)
+}
+
+func BenchmarkShardedMapReadHeavy(b *testing.B) {
+	benchmarkReadWrite(b, inithook.NewShardedMap[string, int](32), 5)
+}
@@ -0,0 +1,128 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// call is an in-flight or completed fn invocation of a key
+type call[V any] struct {
+	done   chan struct{}
+	value  V
+	err    error
+	shared bool // the result is given to duplicates, guarded by callsLock
+}
+
+// DoOption used to configure `Map.Do`
+type DoOption func(o *doOptions)
+
+type doOptions struct {
+	store bool
+}
+
+// StoreResult sets the result of a successful `Map.Do` with key, so the following Get of key returns it
+func StoreResult() DoOption {
+	return func(o *doOptions) {
+		o.store = true
+	}
+}
+
+// Do executes fn for key like `golang.org/x/sync/singleflight`, making sure only one execution is in-flight for a given key
+// at a time, if a duplicate comes in, the duplicate caller waits for the original to complete and receives the same results,
+// shared tells if v was given to multiple callers. Unlike `GetOrCompute`, fn is executed even if key exists, and its result
+// is not stored unless `StoreResult` is used. The in-flight executions are tracked apart from the providers and `GetOrCompute`
+// of the same key, if ctx is done before fn starts or while waiting then return `ctx.Err()`
+func (m *Map[K, V]) Do(ctx context.Context, key K, fn func(ctx context.Context) (V, error), opts ...DoOption) (v V, shared bool, err error) {
+	o := &doOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	key = m.key(key)
+	v, shared, err = m.do(ctx, &m.doCalls, key, func(ctx context.Context) (V, error) {
+		v, err := fn(ctx)
+		if err != nil || !o.store {
+			return v, err
+		}
+		return v, m.Set(ctx, key, v)
+	})
+	return m.copy(v), shared, err
+}
+
+// flight executes fn for key, making sure only one execution is in-flight for a given key at a time, see `do`
+func (m *Map[K, V]) flight(ctx context.Context, key K, fn func(ctx context.Context) (V, error)) (V, error) {
+	v, _, err := m.do(ctx, &m.calls, key, fn)
+	return v, err
+}
+
+// do executes fn for key, making sure only one execution is in-flight for a given key of calls at a time,
+// if a duplicate comes in, the duplicate caller waits for the original to complete and receives the same results,
+// if fn panics, the duplicates receive an error with the panic value and stack and the original caller panics again,
+// if ctx is done before fn starts or while waiting then return `ctx.Err()`
+func (m *Map[K, V]) do(ctx context.Context, calls *map[K]*call[V], key K, fn func(ctx context.Context) (V, error)) (V, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return *new(V), false, err
+	}
+	m.callsLock.Lock()
+	if *calls == nil {
+		*calls = make(map[K]*call[V])
+	}
+	if c, ok := (*calls)[key]; ok {
+		c.shared = true
+		m.callsLock.Unlock()
+		select {
+		case <-c.done:
+			if isContextErr(c.err) && ctx.Err() == nil { // the original caller is canceled but not this one
+				return m.do(ctx, calls, key, fn)
+			}
+			return c.value, true, c.err
+		case <-ctx.Done():
+			return *new(V), false, ctx.Err()
+		}
+	}
+	c := &call[V]{done: make(chan struct{})}
+	(*calls)[key] = c
+	m.callsLock.Unlock()
+
+	func() {
+		defer close(c.done)
+		defer func() {
+			m.callsLock.Lock()
+			delete(*calls, key)
+			m.callsLock.Unlock()
+		}()
+		defer func() {
+			if r := recover(); r != nil {
+				c.err = &flightPanic{value: r, stack: debug.Stack()}
+			}
+		}()
+		c.value, c.err = fn(ctx)
+	}()
+	if p, ok := c.err.(*flightPanic); ok { // the waiters receive the error, while the original caller panics again
+		panic(p)
+	}
+	return c.value, c.shared, c.err
+}
+
+// flightPanic is the error received by the callers waiting for an execution(e.g. of a provider) which panicked,
+// the original caller panics again with it, like `golang.org/x/sync/singleflight`
+type flightPanic struct {
+	value any    // the value passed to panic
+	stack []byte // the stack of the panicking goroutine
+}
+
+func (p *flightPanic) Error() string {
+	return fmt.Sprintf("inithook: panic: %v\n\n%s", p.value, p.stack)
+}
+
+// Unwrap returns the panic value if it's an error
+func (p *flightPanic) Unwrap() error {
+	err, _ := p.value.(error)
+	return err
+}
+
+// isContextErr tells if err is caused by a canceled or timed out context
+func isContextErr(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,69 @@
+package inithook
+
+import "context"
+
+// Cloner is implemented by values which can deep copy themselves
+type Cloner[V any] interface {
+	Clone() V
+}
+
+// Snapshot returns a copy of all items, values implementing `Cloner` are deep copied by `Clone`,
+// the result can be passed to `Restore` to roll back to this state
+func (m *Map[K, V]) Snapshot(ctx context.Context) map[K]V {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	snapshot := make(map[K]V, m.store.Len())
+	m.each(func(k K, v V) bool {
+		snapshot[k] = clone(v)
+		return true
+	})
+	return snapshot
+}
+
+// Restore replaces all items with the snapshot under a single write lock, so readers see either the old or the new state
+func (m *Map[K, V]) Restore(ctx context.Context, snapshot map[K]V) error {
+	snapshot = m.keys(snapshot)
+	if err := m.validateMany(ctx, "Restore", snapshot); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("Restore")
+	}
+	var events []Event[K, V]
+	m.each(func(k K, v V) bool {
+		if _, ok := snapshot[k]; !ok {
+			events = append(events, Event[K, V]{Type: EventDelete, Key: k, OldValue: v, Loaded: true})
+		}
+		return true
+	})
+	for _, ev := range events {
+		m.remove(ev.Key)
+	}
+	for k, v := range snapshot {
+		v = clone(v)
+		old, loaded := m.load(k)
+		m.put(k, v)
+		events = append(events, Event[K, V]{Type: EventSet, Key: k, OldValue: old, NewValue: v, Loaded: loaded})
+	}
+	m.lock.Unlock()
+	m.notify(events...)
+	return m.release(ctx, events...)
+}
+
+// copy returns the copy of v by the func of `WithCopyOnRead` if used, otherwise v as is
+func (m *Map[K, V]) copy(v V) V {
+	if m.copyOnRead == nil {
+		return v
+	}
+	return m.copyOnRead(v)
+}
+
+// clone deep copies v if it implements `Cloner`, otherwise returns v as is
+func clone[V any](v V) V {
+	if c, ok := any(v).(Cloner[V]); ok {
+		return c.Clone()
+	}
+	return v
+}
@@ -0,0 +1,66 @@
+package inithook
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// Stats is a snapshot of the operation counters of a Map
+type Stats struct {
+	// Gets is the number of `Get` and `GetDefault` calls, i.e. Hits + Misses
+	Gets uint64
+	// Hits is the number of gets which found the key
+	Hits uint64
+	// Misses is the number of gets which not found the key
+	Misses uint64
+	// Defaults is the number of `GetDefault` calls which returned the default, counted in Misses
+	Defaults uint64
+	// Registers is the number of instances registered
+	Registers uint64
+	// Sets is the number of instances set, including the ones replaced
+	Sets uint64
+	// Deletes is the number of instances deleted, cleared or evicted
+	Deletes uint64
+	// Conflicts is the number of registrations rejected as `ErrAlreadyExists`
+	Conflicts uint64
+}
+
+// Stats returns the operation counters of the map, e.g. to spot registries never read or keys frequently missed
+func (m *Map[K, V]) Stats(ctx context.Context) Stats {
+	hits, misses := m.counters.hits.Load(), m.counters.misses.Load()
+	return Stats{
+		Gets:      hits + misses,
+		Hits:      hits,
+		Misses:    misses,
+		Defaults:  m.counters.defaults.Load(),
+		Registers: m.counters.registers.Load(),
+		Sets:      m.counters.sets.Load(),
+		Deletes:   m.counters.deletes.Load(),
+		Conflicts: m.counters.conflicts.Load(),
+	}
+}
+
+// counters records the operations of a Map
+type counters struct {
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	defaults  atomic.Uint64
+	registers atomic.Uint64
+	sets      atomic.Uint64
+	deletes   atomic.Uint64
+	conflicts atomic.Uint64
+}
+
+// record counts the mutations of events
+func (m *Map[K, V]) record(events []Event[K, V]) {
+	for _, ev := range events {
+		switch ev.Type {
+		case EventRegister:
+			m.counters.registers.Add(1)
+		case EventSet:
+			m.counters.sets.Add(1)
+		case EventDelete, EventClear:
+			m.counters.deletes.Add(1)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package inithook
+
+// Store is the backing storage of Map, Map calls Store/Delete/Clear under its write lock, and Load/Len/Range under its read lock,
+// so the read methods must be safe for concurrent readers(e.g. a store updating recency in Load needs its own lock like `NewBoundedMap`),
+// while the write methods are never called concurrently with any other method
+type Store[K comparable, V any] interface {
+	// Load returns the value stored with key, ok tells if it's present
+	Load(key K) (value V, ok bool)
+
+	// Store stores value with key, if exists then override
+	Store(key K, value V)
+
+	// Delete deletes the value stored with key
+	Delete(key K)
+
+	// Clear deletes all values
+	Clear()
+
+	// Len returns the number of values stored
+	Len() int
+
+	// Range calls fn sequentially for each key and value, if fn returns false, range stops the iteration
+	Range(fn func(key K, value V) bool)
+}
+
+// NewMapStore creates a new Store backed by builtin map, which is the default Store of Map
+func NewMapStore[K comparable, V any]() Store[K, V] {
+	return mapStore[K, V]{}
+}
+
+type mapStore[K comparable, V any] map[K]V
+
+func (s mapStore[K, V]) Load(key K) (V, bool) {
+	v, ok := s[key]
+	return v, ok
+}
+
+func (s mapStore[K, V]) Store(key K, value V) {
+	s[key] = value
+}
+
+func (s mapStore[K, V]) Delete(key K) {
+	delete(s, key)
+}
+
+func (s mapStore[K, V]) Clear() {
+	for k := range s {
+		delete(s, k)
+	}
+}
+
+func (s mapStore[K, V]) Len() int {
+	return len(s)
+}
+
+func (s mapStore[K, V]) Range(fn func(key K, value V) bool) {
+	for k, v := range s {
+		if !fn(k, v) {
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package inithook
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+)
+
+// Tracer starts spans around slow operations(i.e. provider construction, default loading, attr setters and hooks execution),
+// see `WithTracer` and `SetTracer`
+type Tracer interface {
+	// Start starts a span named name, the returned end func must be called with the error of the operation
+	Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, func(err error))
+}
+
+// Attribute is a span attribute
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// span names and attribute keys used by inithook
+const (
+	SpanProvide    = "inithook.provide"
+	SpanDefault    = "inithook.default"
+	SpanAttrSetter = "inithook.attr_setter"
+	SpanHook       = "inithook.hook"
+
+	AttributeRegistry = "inithook.registry"
+	AttributeKey      = "inithook.key"
+	AttributeAttr     = "inithook.attr"
+	AttributeSetter   = "inithook.setter"
+	AttributePhase    = "inithook.phase"
+	AttributeHook     = "inithook.hook_name"
+)
+
+// SetTracer sets the global tracer, which is used by attr setters and Maps without `WithTracer`, nil to disable tracing
+func SetTracer(t Tracer) {
+	globalTracer.Store(&tracerHolder{t})
+}
+
+type tracerHolder struct {
+	tracer Tracer
+}
+
+var globalTracer atomic.Pointer[tracerHolder]
+
+// startSpan starts a span by t, or the global tracer if t is nil
+func startSpan(ctx context.Context, t Tracer, name string, attrs ...Attribute) (context.Context, func(err error)) {
+	if t == nil {
+		if h := globalTracer.Load(); h != nil {
+			t = h.tracer
+		}
+	}
+	if t == nil {
+		return ctx, func(error) {}
+	}
+	return t.Start(ctx, name, attrs...)
+}
+
+// trace starts a span of the operation on key
+func (m *Map[K, V]) trace(ctx context.Context, name string, key K) (context.Context, func(err error)) {
+	if m.tracer == nil && globalTracer.Load() == nil {
+		return ctx, func(error) {}
+	}
+	return startSpan(ctx, m.tracer, name, Attribute{AttributeRegistry, m.name}, Attribute{AttributeKey, fmt.Sprint(key)})
+}
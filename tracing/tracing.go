@@ -0,0 +1,42 @@
+// Package tracing implements inithook.Tracer by OpenTelemetry
+package tracing
+
+import (
+	"context"
+
+	"github.com/ccmonky/inithook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope name of the tracer
+const ScopeName = "github.com/ccmonky/inithook"
+
+// NewTracer creates an inithook.Tracer which starts spans by tp,
+// use `inithook.SetTracer` or `inithook.WithTracer` to install it
+func NewTracer(tp trace.TracerProvider) inithook.Tracer {
+	return &tracer{
+		tracer: tp.Tracer(ScopeName),
+	}
+}
+
+type tracer struct {
+	tracer trace.Tracer
+}
+
+// Start implements inithook.Tracer
+func (t *tracer) Start(ctx context.Context, name string, attrs ...inithook.Attribute) (context.Context, func(err error)) {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		kvs = append(kvs, attribute.String(attr.Key, attr.Value))
+	}
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(kvs...))
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
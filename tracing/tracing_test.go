@@ -0,0 +1,38 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/ccmonky/inithook/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	ctx := context.Background()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	m := inithook.NewMap(inithook.WithName[string, int]("ints"), inithook.WithTracer[string, int](tracing.NewTracer(tp)))
+	errBoom := errors.New("boom")
+	m.MustRegisterProvider(ctx, "ok", func(ctx context.Context) (int, error) { return 1, nil })
+	m.MustRegisterProvider(ctx, "fail", func(ctx context.Context) (int, error) { return 0, errBoom })
+	m.Get(ctx, "ok")
+	m.Get(ctx, "fail")
+
+	spans := exporter.GetSpans()
+	assert.Lenf(t, spans, 2, "spans")
+	assert.Equalf(t, inithook.SpanProvide, spans[0].Name, "span name")
+	assert.Equalf(t, []attribute.KeyValue{
+		attribute.String(inithook.AttributeRegistry, "ints"),
+		attribute.String(inithook.AttributeKey, "ok"),
+	}, spans[0].Attributes, "span attributes")
+	assert.Equalf(t, codes.Unset, spans[0].Status.Code, "ok status")
+	assert.Equalf(t, codes.Error, spans[1].Status.Code, "fail status")
+	assert.Equalf(t, "boom", spans[1].Status.Description, "fail status")
+}
@@ -0,0 +1,121 @@
+package inithook
+
+import (
+	"context"
+	"time"
+)
+
+// MustSetWithTTL set a V's instance with key which expires after ttl, if failed then panic
+func (m *Map[K, V]) MustSetWithTTL(ctx context.Context, key K, value V, ttl time.Duration) {
+	err := m.SetWithTTL(ctx, key, value, ttl)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// SetWithTTL set a V's instance with key which expires after ttl, if exists then override,
+// the replaced one is closed if `WithAutoClose` is used, expired instances are invisible to all reads,
+// and are evicted lazily on Get or by `EvictExpired`
+func (m *Map[K, V]) SetWithTTL(ctx context.Context, key K, value V, ttl time.Duration) error {
+	key = m.key(key)
+	if err := m.validate(ctx, "SetWithTTL", key, value); err != nil {
+		return err
+	}
+	m.lock.Lock()
+	if m.sealed {
+		m.lock.Unlock()
+		return m.errSealed("SetWithTTL", key)
+	}
+	old, loaded := m.load(key)
+	m.put(key, value)
+	if m.expires == nil {
+		m.expires = make(map[K]time.Time)
+	}
+	m.expires[key] = time.Now().Add(ttl)
+	m.lock.Unlock()
+	ev := Event[K, V]{Type: EventSet, Key: key, OldValue: old, NewValue: value, Loaded: loaded}
+	m.notify(ev)
+	return m.release(ctx, ev)
+}
+
+// TTL returns the remaining time to live of key, ok is false if key not found or has no expiration
+func (m *Map[K, V]) TTL(ctx context.Context, key K) (ttl time.Duration, ok bool) {
+	key = m.key(key)
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	if _, found := m.load(key); !found {
+		return 0, false
+	}
+	deadline, ok := m.expires[key]
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
+
+// EvictExpired evicts all expired instances and returns the number evicted
+func (m *Map[K, V]) EvictExpired(ctx context.Context) int {
+	now := time.Now()
+	m.lock.Lock()
+	var events []Event[K, V]
+	for key, deadline := range m.expires {
+		if now.Before(deadline) {
+			continue
+		}
+		if v, ok := m.store.Load(key); ok {
+			events = append(events, Event[K, V]{Type: EventDelete, Key: key, OldValue: v, Loaded: true})
+		}
+		m.remove(key)
+	}
+	m.lock.Unlock()
+	m.evicted(events...)
+	return len(events)
+}
+
+// StartJanitor starts a background goroutine which calls `EvictExpired` every interval until ctx done
+func (m *Map[K, V]) StartJanitor(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.EvictExpired(ctx)
+			}
+		}
+	}()
+}
+
+// expired tells if key has expired at now, must be called with lock held
+func (m *Map[K, V]) expired(key K, now time.Time) bool {
+	if len(m.expires) == 0 {
+		return false
+	}
+	deadline, ok := m.expires[key]
+	return ok && !now.Before(deadline)
+}
+
+// evict evicts key if it has expired
+func (m *Map[K, V]) evict(key K) {
+	m.lock.Lock()
+	if !m.expired(key, time.Now()) {
+		m.lock.Unlock()
+		return
+	}
+	v, _ := m.store.Load(key)
+	m.remove(key)
+	m.lock.Unlock()
+	m.evicted(Event[K, V]{Type: EventDelete, Key: key, OldValue: v, Loaded: true})
+}
+
+// evicted invokes the eviction callback and notifies watchers for the evicted instances
+func (m *Map[K, V]) evicted(events ...Event[K, V]) {
+	if m.onEvict != nil {
+		for _, ev := range events {
+			m.onEvict(ev.Key, ev.OldValue)
+		}
+	}
+	m.notify(events...)
+}
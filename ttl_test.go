@@ -0,0 +1,63 @@
+package inithook_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapSetWithTTL(t *testing.T) {
+	var lock sync.Mutex
+	evicted := map[string]int{}
+	m := inithook.NewMap(inithook.WithOnEvict(func(key string, value int) {
+		lock.Lock()
+		evicted[key] = value
+		lock.Unlock()
+	}))
+	ctx := context.Background()
+	m.MustSetWithTTL(ctx, "short", 1, 20*time.Millisecond)
+	m.MustSetWithTTL(ctx, "long", 2, time.Hour)
+	m.MustSet(ctx, "forever", 3)
+	ttl, ok := m.TTL(ctx, "long")
+	assert.Truef(t, ok, "long has ttl")
+	assert.Truef(t, ttl > 59*time.Minute, "long ttl")
+	_, ok = m.TTL(ctx, "forever")
+	assert.Falsef(t, ok, "forever has no ttl")
+	assert.Truef(t, m.Has(ctx, "short"), "short not expired yet")
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Falsef(t, m.Has(ctx, "short"), "short expired")
+	assert.ElementsMatchf(t, []string{"long", "forever"}, m.Keys(ctx), "keys")
+	assert.Equalf(t, 2, m.Len(ctx), "len without expired")
+	_, err := m.Get(ctx, "short")
+	assert.ErrorIsf(t, err, inithook.ErrNotFound, "get expired")
+	lock.Lock()
+	assert.Equalf(t, map[string]int{"short": 1}, evicted, "lazily evicted")
+	lock.Unlock()
+
+	m.MustSetWithTTL(ctx, "long", 2, -time.Second)
+	m.MustSet(ctx, "forever", 3)
+	assert.Equalf(t, 1, m.EvictExpired(ctx), "evict expired")
+	assert.Equalf(t, []string{"forever"}, m.Keys(ctx), "keys after evict")
+}
+
+func TestMapJanitor(t *testing.T) {
+	evicted := make(chan string, 1)
+	m := inithook.NewMap(inithook.WithOnEvict(func(key string, value int) {
+		evicted <- key
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	m.StartJanitor(ctx, 5*time.Millisecond)
+	m.MustSetWithTTL(ctx, "key", 1, 10*time.Millisecond)
+	select {
+	case key := <-evicted:
+		assert.Equalf(t, "key", key, "evicted")
+	case <-time.After(time.Second):
+		t.Fatal("janitor should evict expired key")
+	}
+}
@@ -0,0 +1,124 @@
+package inithook
+
+import "context"
+
+// Txn stages Register/Set/Delete operations of a transaction, reads see the staged operations, see `Map.Tx`
+type Txn[K comparable, V any] interface {
+	Get(ctx context.Context, key K) (V, error)
+	Has(ctx context.Context, key K) bool
+	Register(ctx context.Context, key K, value V) error
+	Set(ctx context.Context, key K, value V) error
+	Delete(ctx context.Context, key K) error
+}
+
+// Tx runs fn with a transaction holding the write lock of the map, operations staged in fn are committed atomically
+// if fn returns nil, otherwise(including fn panics) all of them are discarded, so readers never see a partial state.
+// NOTE: fn must only access the map through tx, calling the map's methods in fn will deadlock.
+func (m *Map[K, V]) Tx(ctx context.Context, fn func(tx Txn[K, V]) error) error {
+	events, err := m.tx(fn)
+	if err != nil {
+		return err
+	}
+	m.notify(events...)
+	return m.release(ctx, events...)
+}
+
+// tx runs fn and commits the staged operations under the write lock, which is released even if fn panics
+func (m *Map[K, V]) tx(fn func(tx Txn[K, V]) error) ([]Event[K, V], error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.sealed {
+		return nil, m.errSealed("Tx")
+	}
+	tx := &txn[K, V]{m: m, staged: map[K]txnEntry[V]{}}
+	if err := fn(tx); err != nil {
+		return nil, err
+	}
+	events := make([]Event[K, V], 0, len(tx.ops))
+	for _, op := range tx.ops {
+		old, loaded := m.load(op.Key)
+		op.OldValue, op.Loaded = old, loaded
+		if op.Type == EventDelete {
+			m.remove(op.Key)
+			delete(m.providers, op.Key)
+			if !loaded {
+				continue
+			}
+		} else {
+			m.put(op.Key, op.NewValue)
+		}
+		events = append(events, op)
+	}
+	return events, nil
+}
+
+type txnEntry[V any] struct {
+	value   V
+	deleted bool
+}
+
+type txn[K comparable, V any] struct {
+	m      *Map[K, V]
+	staged map[K]txnEntry[V]
+	ops    []Event[K, V]
+}
+
+func (tx *txn[K, V]) load(key K) (V, bool) {
+	if e, ok := tx.staged[key]; ok {
+		return e.value, !e.deleted
+	}
+	return tx.m.load(key)
+}
+
+// exists tells if key has a staged or stored instance, or a provider which is not deleted by the transaction
+func (tx *txn[K, V]) exists(key K) bool {
+	if e, ok := tx.staged[key]; ok {
+		return !e.deleted
+	}
+	return tx.m.exists(key)
+}
+
+func (tx *txn[K, V]) Get(ctx context.Context, key K) (V, error) {
+	key = tx.m.key(key)
+	if v, ok := tx.load(key); ok {
+		return v, nil
+	}
+	value := *new(V)
+	return value, newRegistryError[V]("Tx", key, ErrNotFound, nil)
+}
+
+func (tx *txn[K, V]) Has(ctx context.Context, key K) bool {
+	key = tx.m.key(key)
+	_, ok := tx.load(key)
+	return ok
+}
+
+func (tx *txn[K, V]) Register(ctx context.Context, key K, value V) error {
+	key = tx.m.key(key)
+	if err := tx.m.validate(ctx, "Tx", key, value); err != nil {
+		return err
+	}
+	if tx.exists(key) {
+		return tx.m.errAlreadyExists("Tx", key)
+	}
+	tx.staged[key] = txnEntry[V]{value: value}
+	tx.ops = append(tx.ops, Event[K, V]{Type: EventRegister, Key: key, NewValue: value})
+	return nil
+}
+
+func (tx *txn[K, V]) Set(ctx context.Context, key K, value V) error {
+	key = tx.m.key(key)
+	if err := tx.m.validate(ctx, "Tx", key, value); err != nil {
+		return err
+	}
+	tx.staged[key] = txnEntry[V]{value: value}
+	tx.ops = append(tx.ops, Event[K, V]{Type: EventSet, Key: key, NewValue: value})
+	return nil
+}
+
+func (tx *txn[K, V]) Delete(ctx context.Context, key K) error {
+	key = tx.m.key(key)
+	tx.staged[key] = txnEntry[V]{deleted: true}
+	tx.ops = append(tx.ops, Event[K, V]{Type: EventDelete, Key: key})
+	return nil
+}
@@ -0,0 +1,86 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+)
+
+var errNilValue = errors.New("nil value")
+
+// validateKey validates key by all key validators for op
+func (m *Map[K, V]) validateKey(ctx context.Context, op string, key K) error {
+	for _, validator := range m.keyValidators {
+		if err := validator(key); err != nil {
+			err = m.newError(op, key, ErrInvalidKey, err)
+			m.logError(ctx, "inithook: invalid key", key, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// validate validates key by all key validators and value of key by all validators for op
+func (m *Map[K, V]) validate(ctx context.Context, op string, key K, value V) error {
+	if err := m.validateKey(ctx, op, key); err != nil {
+		return err
+	}
+	for _, validator := range m.validators {
+		if err := validator(ctx, key, value); err != nil {
+			err = m.newError(op, key, ErrInvalidValue, err)
+			m.logError(ctx, "inithook: invalid value", key, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// validateMany validates all values
+func (m *Map[K, V]) validateMany(ctx context.Context, op string, values map[K]V) error {
+	if len(m.validators) == 0 && len(m.keyValidators) == 0 {
+		return nil
+	}
+	for key, value := range values {
+		if err := m.validate(ctx, op, key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NonEmptyKey is a key validator(see `WithKeyValidator`) rejecting the empty key
+func NonEmptyKey(key string) error {
+	if key == "" {
+		return errEmptyKey
+	}
+	return nil
+}
+
+var errEmptyKey = errors.New("empty key")
+
+// KeyPattern returns a key validator(see `WithKeyValidator`) accepting the keys fully matching the regular expression pattern,
+// e.g. `[a-z0-9_]+` for stable machine-readable identifiers, it panics if pattern is invalid
+func KeyPattern(pattern string) func(key string) error {
+	re := regexp.MustCompile(`^(?:` + pattern + `)$`)
+	return func(key string) error {
+		if !re.MatchString(key) {
+			return fmt.Errorf("not match %s", pattern)
+		}
+		return nil
+	}
+}
+
+// isNil tells if v is nil or a typed-nil pointer, map, func, chan or interface, nil slices are considered usable
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer:
+		return rv.IsNil()
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package inithook_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMapWithValidator(t *testing.T) {
+	ctx := context.Background()
+	name := regexp.MustCompile(`^[a-z]+$`)
+	m := inithook.NewMap(
+		inithook.WithValidator(func(ctx context.Context, key string, value int) error {
+			if value < 0 {
+				return errors.New("should be non-negative")
+			}
+			return nil
+		}),
+		inithook.WithValidator(func(ctx context.Context, key string, value int) error {
+			if !name.MatchString(key) {
+				return errors.New("name should match " + name.String())
+			}
+			return nil
+		}),
+	)
+	assert.Nilf(t, m.Register(ctx, "one", 1), "valid")
+	err := m.Register(ctx, "two", -2)
+	assert.ErrorIsf(t, err, inithook.ErrInvalidValue, "invalid value")
+	assert.Containsf(t, err.Error(), "should be non-negative", "reason")
+	assert.ErrorIsf(t, m.Set(ctx, "Two", 2), inithook.ErrInvalidValue, "invalid name")
+	assert.ErrorIsf(t, m.SetMany(ctx, map[string]int{"two": 2, "three": -3}), inithook.ErrInvalidValue, "invalid batch")
+	assert.Falsef(t, m.Has(ctx, "two"), "nothing set in invalid batch")
+	err = m.Update(ctx, "one", func(old int) (int, error) { return -1, nil })
+	assert.ErrorIsf(t, err, inithook.ErrInvalidValue, "invalid update")
+	assert.Falsef(t, m.CompareAndSwap(ctx, "one", 1, -1), "invalid swap")
+	v, _ := m.Get(ctx, "one")
+	assert.Equalf(t, 1, v, "unchanged")
+	err = m.Tx(ctx, func(tx inithook.Txn[string, int]) error {
+		return tx.Set(ctx, "four", -4)
+	})
+	assert.ErrorIsf(t, err, inithook.ErrInvalidValue, "invalid tx")
+}
+
+type nilHandler struct{}
+
+func (*nilHandler) ServeHTTP() {}
+
+func TestMapWithRejectNil(t *testing.T) {
+	ctx := context.Background()
+	type handler interface{ ServeHTTP() }
+	m := inithook.NewMap(inithook.WithRejectNil[string, handler]())
+	var typedNil *nilHandler
+	assert.ErrorIsf(t, m.Register(ctx, "typed-nil", typedNil), inithook.ErrInvalidValue, "typed nil interface")
+	assert.ErrorIsf(t, m.Register(ctx, "nil", nil), inithook.ErrInvalidValue, "nil interface")
+	assert.Nilf(t, m.Register(ctx, "handler", &nilHandler{}), "non-nil")
+
+	funcs := inithook.NewMap(inithook.WithRejectNil[string, func()]())
+	assert.ErrorIsf(t, funcs.Set(ctx, "nil-func", nil), inithook.ErrInvalidValue, "nil func")
+	slices := inithook.NewMap(inithook.WithRejectNil[string, []int]())
+	assert.Nilf(t, slices.Set(ctx, "nil-slice", nil), "nil slice is usable")
+}
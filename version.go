@@ -0,0 +1,354 @@
+package inithook
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SemVer is a semantic version(see https://semver.org), the build metadata is ignored
+type SemVer struct {
+	Major, Minor, Patch uint64
+	Prerelease          string
+}
+
+// ParseSemVer parses a semantic version like `1.4.0`, `v1.4.0-rc.1`, the missing minor or patch is 0
+func ParseSemVer(s string) (SemVer, error) {
+	v, n, err := parseSemVer(s)
+	if err != nil {
+		return SemVer{}, err
+	}
+	if n < 0 {
+		return SemVer{}, fmt.Errorf("invalid version %q: wildcard", s)
+	}
+	return v, nil
+}
+
+// MustParseSemVer parses a semantic version, if failed then panic
+func MustParseSemVer(s string) SemVer {
+	return Must(ParseSemVer(s))
+}
+
+// parseSemVer parses a version which may be partial(`1.2`) or contain a wildcard(`1.2.x`, `*`),
+// returns the number of numeric parts given, or -(n+1) if the n+1 part is a wildcard
+func parseSemVer(s string) (SemVer, int, error) {
+	var v SemVer
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexByte(raw, '+'); i >= 0 {
+		raw = raw[:i]
+	}
+	if i := strings.IndexByte(raw, '-'); i >= 0 {
+		raw, v.Prerelease = raw[:i], raw[i+1:]
+		if v.Prerelease == "" {
+			return v, 0, fmt.Errorf("invalid version %q: empty prerelease", s)
+		}
+	}
+	parts := strings.Split(raw, ".")
+	if len(parts) > 3 {
+		return v, 0, fmt.Errorf("invalid version %q: too many parts", s)
+	}
+	nums := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if part == "x" || part == "X" || part == "*" {
+			if i != len(parts)-1 || v.Prerelease != "" {
+				return v, 0, fmt.Errorf("invalid version %q: misplaced wildcard", s)
+			}
+			return v, -(i + 1), nil
+		}
+		n, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return v, 0, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		*nums[i] = n
+	}
+	return v, len(parts), nil
+}
+
+// String returns the version like `1.4.0-rc.1`
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	return s
+}
+
+// MarshalText implements `encoding.TextMarshaler`
+func (v SemVer) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText implements `encoding.TextUnmarshaler`
+func (v *SemVer) UnmarshalText(text []byte) error {
+	parsed, err := ParseSemVer(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+// Compare returns -1, 0 or 1 if v is less than, equal to or greater than o by the semver precedence
+func (v SemVer) Compare(o SemVer) int {
+	for _, c := range [][2]uint64{{v.Major, o.Major}, {v.Minor, o.Minor}, {v.Patch, o.Patch}} {
+		if c[0] != c[1] {
+			if c[0] < c[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+func comparePrerelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		an, aerr := strconv.ParseUint(as[i], 10, 64)
+		bn, berr := strconv.ParseUint(bs[i], 10, 64)
+		switch {
+		case aerr == nil && berr == nil:
+			if an < bn {
+				return -1
+			}
+			return 1
+		case aerr == nil: // numeric identifiers have lower precedence
+			return -1
+		case berr == nil:
+			return 1
+		case as[i] < bs[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+	switch {
+	case len(as) < len(bs):
+		return -1
+	case len(as) > len(bs):
+		return 1
+	}
+	return 0
+}
+
+// Constraint is a parsed semver constraint, see `ParseConstraint`
+type Constraint struct {
+	raw        string
+	groups     [][]func(SemVer) bool
+	prerelease bool
+}
+
+// ParseConstraint parses a semver constraint, which is comparators separated by `||`(or) and comma or space(and),
+// a comparator is an operator(`=`, `!=`, `>`, `>=`, `<`, `<=`, `~`, `^`) followed by a version, e.g. `>=1.2, <2` or `>= 1.2, < 2`,
+// the missing parts of the version are 0 except for `=`(`1.2` matches `1.2.x`), `~` and `^`, `*` or empty matches any,
+// the prerelease versions are matched only if the constraint contains a prerelease
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: s}
+	for _, group := range strings.Split(s, "||") {
+		var comparators []func(SemVer) bool
+		fields := strings.FieldsFunc(group, func(r rune) bool { return r == ',' || r == ' ' })
+		for i := 0; i < len(fields); i++ {
+			field := fields[i]
+			if isOperator(field) && i+1 < len(fields) { // `>= 1.2` is `>=1.2`
+				i++
+				field += fields[i]
+			}
+			fn, prerelease, err := parseComparator(field)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("invalid constraint %q: %w", s, err)
+			}
+			comparators = append(comparators, fn)
+			c.prerelease = c.prerelease || prerelease
+		}
+		c.groups = append(c.groups, comparators)
+	}
+	return c, nil
+}
+
+// MustParseConstraint parses a semver constraint, if failed then panic
+func MustParseConstraint(s string) Constraint {
+	return Must(ParseConstraint(s))
+}
+
+// String returns the raw constraint
+func (c Constraint) String() string {
+	return c.raw
+}
+
+// Check tells if v satisfies the constraint
+func (c Constraint) Check(v SemVer) bool {
+	if v.Prerelease != "" && !c.prerelease {
+		return false
+	}
+	for _, group := range c.groups {
+		matched := true
+		for _, fn := range group {
+			if !fn(v) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+var operators = []string{"!=", ">=", "<=", "=", ">", "<", "~", "^"} // longest first
+
+// isOperator tells if s is a bare operator without version
+func isOperator(s string) bool {
+	for _, o := range operators {
+		if s == o {
+			return true
+		}
+	}
+	return false
+}
+
+func parseComparator(s string) (fn func(SemVer) bool, prerelease bool, err error) {
+	op := ""
+	for _, o := range operators {
+		if strings.HasPrefix(s, o) {
+			op = o
+			break
+		}
+	}
+	v, n, err := parseSemVer(s[len(op):])
+	if err != nil {
+		return nil, false, err
+	}
+	prerelease = v.Prerelease != ""
+	if n == -1 { // `*`
+		if op != "" && op != "=" {
+			return nil, false, fmt.Errorf("wildcard of %s", op)
+		}
+		return func(SemVer) bool { return true }, false, nil
+	}
+	if n < 0 {
+		n = -n - 1
+	}
+	// upper returns the exclusive upper bound by bumping the part i(0 for major)
+	upper := func(i int) SemVer {
+		switch i {
+		case 0:
+			return SemVer{Major: v.Major + 1}
+		case 1:
+			return SemVer{Major: v.Major, Minor: v.Minor + 1}
+		default:
+			return SemVer{Major: v.Major, Minor: v.Minor, Patch: v.Patch + 1}
+		}
+	}
+	between := func(hi SemVer) func(SemVer) bool {
+		lo := v
+		return func(x SemVer) bool { return x.Compare(lo) >= 0 && x.Compare(hi) < 0 }
+	}
+	switch op {
+	case "", "=", "!=":
+		var eq func(SemVer) bool
+		if n == 3 {
+			eq = func(x SemVer) bool { return x.Compare(v) == 0 }
+		} else {
+			eq = between(upper(n - 1))
+		}
+		if op == "!=" {
+			return func(x SemVer) bool { return !eq(x) }, prerelease, nil
+		}
+		return eq, prerelease, nil
+	case ">":
+		return func(x SemVer) bool { return x.Compare(v) > 0 }, prerelease, nil
+	case ">=":
+		return func(x SemVer) bool { return x.Compare(v) >= 0 }, prerelease, nil
+	case "<":
+		return func(x SemVer) bool { return x.Compare(v) < 0 }, prerelease, nil
+	case "<=":
+		return func(x SemVer) bool { return x.Compare(v) <= 0 }, prerelease, nil
+	case "~": // `~1.2.3` is `>=1.2.3, <1.3.0`, `~1` is `>=1.0.0, <2.0.0`
+		if n == 1 {
+			return between(upper(0)), prerelease, nil
+		}
+		return between(upper(1)), prerelease, nil
+	default: // `^1.2.3` is `>=1.2.3, <2.0.0`, `^0.2.3` is `>=0.2.3, <0.3.0`, `^0.0.3` is `>=0.0.3, <0.0.4`
+		switch {
+		case v.Major > 0 || n == 1:
+			return between(upper(0)), prerelease, nil
+		case v.Minor > 0 || n == 2:
+			return between(upper(1)), prerelease, nil
+		default:
+			return between(upper(2)), prerelease, nil
+		}
+	}
+}
+
+// VersionKey is the key of `VersionedMap`, i.e. (name, version)
+type VersionKey = Key2[string, SemVer]
+
+// VersionedMap is a map which stores multiple versions of instances under one logical name side by side,
+// e.g. the protocol codecs, and resolves them by semver constraints
+type VersionedMap[V any] struct {
+	*Map[VersionKey, V]
+}
+
+// NewVersionedMap creates a new VersionedMap
+func NewVersionedMap[V any](opts ...Option[VersionKey, V]) *VersionedMap[V] {
+	return &VersionedMap[V]{Map: NewMap(opts...)}
+}
+
+// MustRegisterVersion register a V's instance with name and version, if failed(e.g. already exists) then panic
+func (vm *VersionedMap[V]) MustRegisterVersion(ctx context.Context, name, version string, value V) {
+	if err := vm.RegisterVersion(ctx, name, version, value); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterVersion register a V's instance with name and version, if version is invalid then return `ErrInvalidKey` error,
+// if exists then return `ErrAlreadyExists` error(use `errors.Is` to assert)
+func (vm *VersionedMap[V]) RegisterVersion(ctx context.Context, name, version string, value V) error {
+	v, err := ParseSemVer(version)
+	if err != nil {
+		return vm.newError("RegisterVersion", name, ErrInvalidKey, err)
+	}
+	return vm.Register(ctx, NewKey2(name, v), value)
+}
+
+// GetVersion get the V's instance of name with the highest version satisfying constraint(see `ParseConstraint`),
+// if constraint is invalid then return `ErrInvalidKey` error, if no version matches return `ErrNotFound` error
+func (vm *VersionedMap[V]) GetVersion(ctx context.Context, name, constraint string) (V, error) {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		return Zero[V](), vm.newError("GetVersion", name, ErrInvalidKey, err)
+	}
+	versions := vm.Versions(ctx, name)
+	for i := len(versions) - 1; i >= 0; i-- {
+		if c.Check(versions[i]) {
+			return vm.Get(ctx, NewKey2(name, versions[i]))
+		}
+	}
+	return Zero[V](), vm.newError("GetVersion", name, ErrNotFound, fmt.Errorf("no version matches %q", constraint))
+}
+
+// Versions return the versions of name in ascending order
+func (vm *VersionedMap[V]) Versions(ctx context.Context, name string) []SemVer {
+	var versions []SemVer
+	for _, key := range vm.Keys(ctx) {
+		if key.First == name {
+			versions = append(versions, key.Second)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Compare(versions[j]) < 0 })
+	return versions
+}
@@ -0,0 +1,119 @@
+package inithook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ccmonky/inithook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSemVer(t *testing.T) {
+	v, err := inithook.ParseSemVer("v1.4.0-rc.1+build.5")
+	assert.Nilf(t, err, "parse")
+	assert.Equalf(t, inithook.SemVer{Major: 1, Minor: 4, Prerelease: "rc.1"}, v, "parse")
+	assert.Equalf(t, "1.4.0-rc.1", v.String(), "string")
+	assert.Equalf(t, "1.2.0", inithook.MustParseSemVer("1.2").String(), "partial")
+	for _, s := range []string{"", "1.x", "1.2.3.4", "a.b", "1.2-"} {
+		_, err := inithook.ParseSemVer(s)
+		assert.NotNilf(t, err, "invalid %q", s)
+	}
+	for _, c := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.2.3", "1.2.3", 0},
+		{"1.2.3", "1.10.0", -1},
+		{"2.0.0", "1.9.9", 1},
+		{"1.0.0-alpha", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-rc.1", "1.0.0-beta.11", 1},
+	} {
+		assert.Equalf(t, c.want, inithook.MustParseSemVer(c.a).Compare(inithook.MustParseSemVer(c.b)), "%s <=> %s", c.a, c.b)
+	}
+}
+
+func TestParseConstraint(t *testing.T) {
+	for _, c := range []struct {
+		constraint string
+		matches    []string
+		excludes   []string
+	}{
+		{"", []string{"0.0.1", "9.9.9"}, []string{"1.0.0-rc.1"}},
+		{"*", []string{"1.2.3"}, nil},
+		{"1.2", []string{"1.2.0", "1.2.9"}, []string{"1.3.0", "1.1.9"}},
+		{"=1.2.3", []string{"1.2.3"}, []string{"1.2.4"}},
+		{"!=1.2.3", []string{"1.2.4"}, []string{"1.2.3"}},
+		{">=1.2", []string{"1.2.0", "2.0.0"}, []string{"1.1.9"}},
+		{">=1.2, <2", []string{"1.9.9"}, []string{"2.0.0", "1.1.0"}},
+		{">= 1.2, < 2", []string{"1.9.9"}, []string{"2.0.0", "1.1.0"}},
+		{"> 1.2.3 <= 1.4 || ^ 3", []string{"1.2.4", "3.1.0"}, []string{"1.2.3", "1.4.1", "4.0.0"}},
+		{">1.2.3 <=1.4", []string{"1.2.4", "1.4.0"}, []string{"1.2.3", "1.4.1"}},
+		{"~1.2.3", []string{"1.2.3", "1.2.9"}, []string{"1.3.0", "1.2.2"}},
+		{"~1", []string{"1.9.0"}, []string{"2.0.0"}},
+		{"^1.2.3", []string{"1.9.0"}, []string{"2.0.0", "1.2.2"}},
+		{"^0.2.3", []string{"0.2.9"}, []string{"0.3.0"}},
+		{"^0.0.3", []string{"0.0.3"}, []string{"0.0.4"}},
+		{"1.x", []string{"1.5.0"}, []string{"2.0.0"}},
+		{"<1 || >=3", []string{"0.9.0", "3.1.0"}, []string{"2.0.0"}},
+		{">=1.0.0-rc.1", []string{"1.0.0-rc.2", "1.0.0"}, []string{"1.0.0-beta.1"}},
+	} {
+		constraint, err := inithook.ParseConstraint(c.constraint)
+		assert.Nilf(t, err, "parse %q", c.constraint)
+		for _, v := range c.matches {
+			assert.Truef(t, constraint.Check(inithook.MustParseSemVer(v)), "%q should match %s", c.constraint, v)
+		}
+		for _, v := range c.excludes {
+			assert.Falsef(t, constraint.Check(inithook.MustParseSemVer(v)), "%q should not match %s", c.constraint, v)
+		}
+	}
+	for _, s := range []string{">=a", ">*", "1.x.2", "=>1.2", ">= 1.2, <"} {
+		_, err := inithook.ParseConstraint(s)
+		assert.NotNilf(t, err, "invalid %q", s)
+	}
+}
+
+func TestVersionedMap(t *testing.T) {
+	ctx := context.Background()
+	codecs := inithook.NewVersionedMap[string]()
+	for _, version := range []string{"1.0.0", "1.4.0", "1.2.1", "2.0.0", "2.1.0-rc.1"} {
+		codecs.MustRegisterVersion(ctx, "codec", version, "codec@"+version)
+	}
+	codecs.MustRegisterVersion(ctx, "other", "3.0.0", "other")
+	err := codecs.RegisterVersion(ctx, "codec", "v1.4", "duplicated")
+	assert.Truef(t, errors.Is(err, inithook.ErrAlreadyExists), "duplicated: %v", err)
+	err = codecs.RegisterVersion(ctx, "codec", "latest", "invalid")
+	assert.Truef(t, errors.Is(err, inithook.ErrInvalidKey), "invalid version: %v", err)
+
+	for constraint, want := range map[string]string{
+		">=1.2":       "codec@2.0.0",
+		">=1.2, <2":   "codec@1.4.0",
+		"~1.2":        "codec@1.2.1",
+		"1.0":         "codec@1.0.0",
+		"^2.1.0-rc.1": "codec@2.1.0-rc.1",
+		"":            "codec@2.0.0",
+		"<1 || 1.2.x": "codec@1.2.1",
+	} {
+		v, err := codecs.GetVersion(ctx, "codec", constraint)
+		assert.Nilf(t, err, "get %q", constraint)
+		assert.Equalf(t, want, v, "get %q", constraint)
+	}
+	_, err = codecs.GetVersion(ctx, "codec", ">=3")
+	assert.Truef(t, errors.Is(err, inithook.ErrNotFound), "no match: %v", err)
+	_, err = codecs.GetVersion(ctx, "codec", ">=x.y")
+	assert.Truef(t, errors.Is(err, inithook.ErrInvalidKey), "invalid constraint: %v", err)
+	assert.Equalf(t, []inithook.SemVer{inithook.MustParseSemVer("3.0.0")}, codecs.Versions(ctx, "other"), "versions")
+	assert.Lenf(t, codecs.Versions(ctx, "codec"), 5, "versions")
+
+	data, err := json.Marshal(codecs)
+	assert.Nilf(t, err, "marshal")
+	loaded := inithook.NewVersionedMap[string]()
+	assert.Nilf(t, json.Unmarshal(data, loaded), "unmarshal")
+	v, err := loaded.GetVersion(ctx, "codec", "~1.4")
+	assert.Nilf(t, err, "get loaded")
+	assert.Equalf(t, "codec@1.4.0", v, "get loaded")
+}
@@ -0,0 +1,54 @@
+package inithook
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Warm resolves all singleton providers not yet resolved(see `RegisterProvider`) and the defaults of keys(see `GetDefault`)
+// eagerly, at most concurrency at a time(unbounded if concurrency <= 0), so the construction cost is paid at deploy time
+// instead of on the first request, the defaults are memoized only if `WithDefaultCache` is used,
+// returns the aggregated errors of failed keys, and stops resolving the rest when ctx is done
+func (m *Map[K, V]) Warm(ctx context.Context, concurrency int, keys ...K) error {
+	m.lock.RLock()
+	var pending []K
+	for key, p := range m.providers {
+		if _, ok := m.load(key); !ok && p.scope == ScopeSingleton {
+			pending = append(pending, key)
+		}
+	}
+	m.lock.RUnlock()
+	pending = append(pending, keys...)
+	if concurrency <= 0 || concurrency > len(pending) {
+		concurrency = len(pending)
+	}
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+		errs []error
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, key := range pending {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return errors.Join(append(errs, ctx.Err())...)
+		}
+		wg.Add(1)
+		go func(key K) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if _, _, err := m.GetDefault(ctx, key); err != nil {
+				lock.Lock()
+				errs = append(errs, err)
+				lock.Unlock()
+			}
+		}(key)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}